@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"toysService/internal/catalog"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+	"toysService/internal/validator"
+)
+
+const exportUsage = `usage: api export [flags]
+
+Writes the toys matching the filters to a CSV, JSON lines or XLSX file in id
+order. Columns default to the ones "api import" reads, plus id.
+
+columns: %s
+
+`
+
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	driver := fs.String("db-driver", "postgres", "Storage backend (postgres|sqlite)")
+	dsn := fs.String("db-dsn", defaultDSN(), "PostgresSQL DSN, or the database file with -db-driver=sqlite (default "+defaultSQLitePath+")")
+	outPath := fs.String("out", "-", "Output file (\"-\" for stdout)")
+	format := fs.String("format", "", "File format (csv|jsonl|xlsx), guessed from -out by default, csv for stdout")
+	columnList := fs.String("columns", "", "Comma separated columns to include")
+	title := fs.String("q", "", "Full-text search query")
+	categories := fs.String("categories", "", "Comma separated categories every toy must be in")
+	skills := fs.String("skills", "", "Comma separated skills every toy must teach")
+	manufacturers := fs.String("manufacturers", "", "Comma separated manufacturer ids")
+	ageMonths := fs.Int("age-months", -1, "Only toys suitable for a child of this age in months")
+	from := fs.Int64("from", 0, "Minimum toy value")
+	to := fs.Int64("to", math.MaxInt64, "Maximum toy value")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), exportUsage, strings.Join(catalog.ExportColumns, ", "))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	*dsn = driverDSN(*driver, *dsn, fs)
+
+	logger := jsonlog.New(os.Stderr, jsonlog.LevelInfo)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if *format == "" {
+		*format = catalog.FormatCSV
+		if *outPath != "-" {
+			var ok bool
+			if *format, ok = catalog.FormatOf(*outPath); !ok {
+				fmt.Fprintln(fs.Output(), "cannot tell the format of", *outPath, "- pass -format")
+				return 2
+			}
+		}
+	}
+	columns, err := catalog.ParseColumns(*columnList)
+	if err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		return 2
+	}
+
+	query := data.ToyQuery{
+		Title:      strings.TrimSpace(*title),
+		Categories: splitFlag(*categories),
+		Skills:     splitFlag(*skills),
+		From:       *from,
+		To:         *to,
+	}
+	v := validator.New()
+	if items := splitFlag(*manufacturers); len(items) > 0 {
+		ids, ok := data.ParseIDs(items)
+		v.Check(ok, "manufacturers", "must be a list of manufacturer ids")
+		query.Manufacturers = ids
+	}
+	if *ageMonths >= 0 {
+		v.Check(*ageMonths <= data.MaxAgeMonths, "age-months", "must not be more than 216")
+		age := int32(*ageMonths)
+		query.AgeMonths = &age
+	}
+	v.Check(validator.PermittedValue(*format, catalog.FormatCSV, catalog.FormatJSONL, catalog.FormatXLSX), "format", "must be csv, jsonl or xlsx")
+	if data.ValidateToyQuery(v, query, data.Filters{}); !v.Valid() {
+		fmt.Fprintln(fs.Output(), validationMessage(v))
+		return 2
+	}
+
+	db, err := openCatalog(*driver, *dsn, logger)
+	if err != nil {
+		logger.PrintError(err, nil)
+		return 1
+	}
+	defer db.Close()
+
+	file := os.Stdout
+	if *outPath != "-" {
+		file, err = os.Create(*outPath)
+		if err != nil {
+			logger.PrintError(err, nil)
+			return 1
+		}
+		defer file.Close()
+	}
+	buf := bufio.NewWriter(file)
+
+	out, err := catalog.NewWriter(buf, *format, columns)
+	if err != nil {
+		logger.PrintError(err, nil)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rows := 0
+	err = db.ExportToys(ctx, query, func(toy *data.Toy) error {
+		rows++
+		return out.Write(toy)
+	})
+	if err == nil {
+		err = out.Close()
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		logger.PrintError(err, map[string]string{
+			"command": "export",
+			"rows":    strconv.Itoa(rows),
+		})
+		return 1
+	}
+
+	logger.PrintInfo("export finished", map[string]string{
+		"out":  *outPath,
+		"rows": strconv.Itoa(rows),
+	})
+	return 0
+}
+
+// splitFlag splits a comma separated flag value, dropping empty items.
+func splitFlag(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"toysService/internal/catalog"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+	"toysService/internal/validator"
+	"toysService/storage/postgres"
+)
+
+const importUsage = `usage: api import [flags] <file>
+
+Creates or updates toys from a CSV or JSON lines file, matching existing toys
+by the "sku" column. CSV files need a header row naming the columns
+sku, title, desc, value, recommendedAge, manufacturer, categories, skills and
+images; list cells separate their values with "|". Rows that leave images
+empty keep the images a toy already has. The optional minAgeMonths and
+maxAgeMonths columns set the age range directly; without them it is parsed
+from recommendedAge.
+
+Every batch is written in one transaction. Rows that fail validation or
+cannot be written are skipped and listed in the error report.
+
+`
+
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	driver := fs.String("db-driver", "postgres", "Storage backend (postgres|sqlite)")
+	dsn := fs.String("db-dsn", defaultDSN(), "PostgresSQL DSN, or the database file with -db-driver=sqlite (default "+defaultSQLitePath+")")
+	format := fs.String("format", "", "File format (csv|jsonl), guessed from the file extension by default")
+	batchSize := fs.Int("batch", 100, "Rows written per transaction")
+	dryRun := fs.Bool("dry-run", false, "Print what would be created or changed without writing anything")
+	reportPath := fs.String("report", "", "Write the per-row error report as CSV to this file (default stderr)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), importUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	*dsn = driverDSN(*driver, *dsn, fs)
+
+	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
+	if fs.NArg() != 1 || *batchSize < 1 {
+		fs.Usage()
+		return 2
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		var ok bool
+		if *format, ok = catalog.FormatOf(path); !ok {
+			fmt.Fprintln(fs.Output(), "cannot tell the format of", path, "- pass -format")
+			return 2
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		logger.PrintError(err, nil)
+		return 1
+	}
+	defer file.Close()
+
+	reader, err := catalog.NewReader(file, *format)
+	if err != nil {
+		logger.PrintError(err, map[string]string{
+			"file": path,
+		})
+		return 1
+	}
+
+	reportOut := os.Stderr
+	if *reportPath != "" {
+		reportOut, err = os.Create(*reportPath)
+		if err != nil {
+			logger.PrintError(err, nil)
+			return 1
+		}
+		defer reportOut.Close()
+	}
+	report := newImportReport(reportOut)
+	defer report.Flush()
+
+	db, err := openCatalog(*driver, *dsn, logger)
+	if err != nil {
+		logger.PrintError(err, nil)
+		return 1
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	vocabulary, err := db.Vocabulary(ctx)
+	if err != nil {
+		logger.PrintError(err, nil)
+		return 1
+	}
+
+	counts := map[string]int{}
+	seen := map[string]int{}
+	var batch []data.ImportRow
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := db.ImportToys(ctx, batch, *dryRun)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			counts[result.Action]++
+			if result.Action == data.ImportActionError {
+				report.Add(result.Line, result.SKU, result.Error)
+			}
+			if *dryRun {
+				printImportPlan(result)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		toy, line, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *catalog.RowError
+		if errors.As(err, &rowErr) {
+			counts[data.ImportActionError]++
+			report.Add(rowErr.Line, "", rowErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			logger.PrintError(err, map[string]string{
+				"file": path,
+				"line": strconv.Itoa(line),
+			})
+			return 1
+		}
+
+		v := validator.New()
+		postgres.ValidateSKU(v, toy.SKU)
+		postgres.NormalizeToy(v, &toy, vocabulary)
+		if first, ok := seen[toy.SKU]; ok && toy.SKU != "" {
+			v.AddError("sku", "duplicate sku, first seen on line "+strconv.Itoa(first))
+		}
+		if !v.Valid() {
+			counts[data.ImportActionError]++
+			report.Add(line, toy.SKU, validationMessage(v))
+			continue
+		}
+		seen[toy.SKU] = line
+
+		batch = append(batch, data.ImportRow{Line: line, Toy: toy})
+		if len(batch) >= *batchSize {
+			if err = flush(); err != nil {
+				logger.PrintError(err, map[string]string{
+					"command": "import",
+					"line":    strconv.Itoa(line),
+				})
+				return 1
+			}
+		}
+	}
+	if err = flush(); err != nil {
+		logger.PrintError(err, map[string]string{
+			"command": "import",
+		})
+		return 1
+	}
+
+	logger.PrintInfo("import finished", map[string]string{
+		"file":      path,
+		"dryRun":    strconv.FormatBool(*dryRun),
+		"created":   strconv.Itoa(counts[data.ImportActionCreate]),
+		"changed":   strconv.Itoa(counts[data.ImportActionChange]),
+		"unchanged": strconv.Itoa(counts[data.ImportActionUnchanged]),
+		"failed":    strconv.Itoa(counts[data.ImportActionError]),
+	})
+	if counts[data.ImportActionError] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// importReport lists the rows an import skipped as CSV.
+type importReport struct {
+	w       *csv.Writer
+	started bool
+}
+
+func newImportReport(out io.Writer) *importReport {
+	return &importReport{w: csv.NewWriter(out)}
+}
+
+func (r *importReport) Add(line int, sku, message string) {
+	if !r.started {
+		r.w.Write([]string{"line", "sku", "error"})
+		r.started = true
+	}
+	r.w.Write([]string{strconv.Itoa(line), sku, message})
+}
+
+func (r *importReport) Flush() {
+	r.w.Flush()
+}
+
+// validationMessage joins a validator's errors in a stable order.
+func validationMessage(v *validator.Validator) string {
+	fields := make([]string, 0, len(v.Errors))
+	for field := range v.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var b strings.Builder
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s: %s", field, v.Errors[field])
+	}
+	return b.String()
+}
+
+// printImportPlan prints one dry-run result with the fields it would set.
+func printImportPlan(result data.ImportResult) {
+	switch result.Action {
+	case data.ImportActionError:
+		fmt.Printf("%-9s line %d %s: %s\n", result.Action, result.Line, result.SKU, result.Error)
+		return
+	case data.ImportActionCreate:
+		fmt.Printf("%-9s line %d %s\n", result.Action, result.Line, result.SKU)
+	default:
+		fmt.Printf("%-9s line %d %s (toy %d)\n", result.Action, result.Line, result.SKU, result.ToyID)
+	}
+	for _, change := range result.Changes {
+		if result.Action == data.ImportActionCreate {
+			fmt.Printf("    %s: %s\n", change.Field, planValue(change.New))
+			continue
+		}
+		fmt.Printf("    %s: %s -> %s\n", change.Field, planValue(change.Old), planValue(change.New))
+	}
+}
+
+func planValue(value any) string {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(js)
+}
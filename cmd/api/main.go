@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
@@ -9,27 +10,46 @@ import (
 	grpctoys "github.com/spacecowboytobykty123/toysProto/gen/go/toys"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 	"toysService/internal/app/grpcapp"
+	"toysService/internal/auth"
+	"toysService/internal/blobstore"
 	subsgrpc "toysService/internal/clients/subscriptions/grpc"
+	"toysService/internal/data"
+	toygrpc "toysService/internal/grpc/toys"
+	httptoys "toysService/internal/http/toys"
 	"toysService/internal/jsonlog"
+	"toysService/internal/outbox"
 	"toysService/internal/services/toys"
 	_ "toysService/internal/services/toys"
+	"toysService/internal/validator"
+	"toysService/migrations"
+	sqlitemigrations "toysService/migrations/sqlite"
+	"toysService/storage/memory"
 	"toysService/storage/postgres"
+	"toysService/storage/sqlite"
 )
 
 const version = "1.0.0"
 
 type StorageDetails struct {
+	Driver       string
 	DSN          string
 	MaxOpenConns int
 	MaxIdleConns int
 	MaxIdleTime  string
+	AutoMigrate  bool
+	// ReplicaDSNs is a comma separated list of postgres read replicas.
+	ReplicaDSNs   string
+	MaxReplicaLag time.Duration
 }
 
 type Client struct {
@@ -48,6 +68,21 @@ type GRPCConfig struct {
 	Timeout time.Duration
 }
 
+type AuthConfig struct {
+	PolicyOverrides string
+}
+
+type OutboxConfig struct {
+	Out       string
+	Interval  time.Duration
+	BatchSize int
+}
+
+type MediaConfig struct {
+	Dir string
+	URL string
+}
+
 type Config struct {
 	env       string
 	DB        StorageDetails
@@ -55,32 +90,55 @@ type Config struct {
 	TokenTTL  time.Duration
 	Clients   ClientsConfig
 	AppSecret string
+	Auth      AuthConfig
+	Outbox    OutboxConfig
+	Media     MediaConfig
 }
 
 type Application struct {
-	GRPCSrv *grpcapp.App
+	GRPCSrv  *grpcapp.App
+	Toys     *toys.Toys
+	Policies auth.Policies
+	Relay    *outbox.Relay
+	MediaURL string
+	// Storage is closed on shutdown; the memory backend has nothing to close.
+	Storage io.Closer
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(runExport(os.Args[2:]))
+	}
+
 	var cfg Config
 
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	pass := os.Getenv("DB_PASSWORD")
-	name := os.Getenv("DB_NAME")
 
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&client_encoding=UTF8", user, pass, host, port, name)
-
-	flag.StringVar(&cfg.DB.DSN, "db-dsn", dsn, "PostgresSQL DSN")
+	flag.StringVar(&cfg.DB.Driver, "db-driver", "postgres", "Storage backend (postgres|sqlite|memory); sqlite keeps the catalogue in a single file, memory keeps everything in process memory, for frontend development and demos")
+	flag.StringVar(&cfg.DB.DSN, "db-dsn", defaultDSN(), "PostgresSQL DSN, or the database file with -db-driver=sqlite (default "+defaultSQLitePath+")")
 	flag.IntVar(&cfg.DB.MaxOpenConns, "db-max-open-conns", 25, "PostgresSQL max open connections")
 	flag.IntVar(&cfg.DB.MaxIdleConns, "db-max-Idle-conns", 25, "PostgresSQL max Idle connections")
 	flag.StringVar(&cfg.DB.MaxIdleTime, "db-max-Idle-time", "15m", "PostgresSQl max Idle time")
+	flag.BoolVar(&cfg.DB.AutoMigrate, "db-auto-migrate", false, "Apply pending migrations on startup")
+	flag.StringVar(&cfg.DB.ReplicaDSNs, "db-replica-dsns", "", "Comma separated PostgreSQL read replica DSNs; toy listing and lookups read from them")
+	flag.DurationVar(&cfg.DB.MaxReplicaLag, "db-max-replica-lag", 5*time.Second, "Read from the primary while a replica trails it by more than this (0 disables the check)")
 
 	flag.IntVar(&cfg.GRPC.Port, "grpc-port", 9000, "grpc-port")
 	flag.DurationVar(&cfg.TokenTTL, "token-ttl", time.Hour, "GRPC's work duration")
 	flag.IntVar(&cfg.Clients.Subs.Address, "sub-client-addr", 3000, "sub-port")
+	flag.StringVar(&cfg.AppSecret, "app-secret", os.Getenv("APP_SECRET"), "JWT signing secret, required (default $APP_SECRET)")
+	flag.StringVar(&cfg.Auth.PolicyOverrides, "auth-policy", "", "Per-method auth overrides (method=public|optional|required, comma separated)")
+	flag.StringVar(&cfg.Outbox.Out, "events-out", "", "Publish toy events as JSON lines to this file (\"-\" for stdout, empty disables the relay)")
+	flag.DurationVar(&cfg.Outbox.Interval, "events-interval", 2*time.Second, "Outbox relay polling interval")
+	flag.IntVar(&cfg.Outbox.BatchSize, "events-batch", 100, "Outbox relay batch size")
+	flag.StringVar(&cfg.Media.Dir, "media-dir", "media", "Directory uploaded images are stored in")
+	flag.StringVar(&cfg.Media.URL, "media-url", "/media/", "Base URL of uploaded images; the gateway serves them under its path")
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 	subsClient, err := subsgrpc.New(context.Background(), logger, cfg.Clients.Subs.Address, cfg.Clients.Subs.Timeout, cfg.Clients.Subs.RetriesCount)
 
@@ -92,6 +150,13 @@ func main() {
 	}
 
 	flag.Parse()
+	cfg.DB.DSN = driverDSN(cfg.DB.Driver, cfg.DB.DSN, flag.CommandLine)
+	// Anyone who knows the secret can sign themselves an admin token, so
+	// there is no default to fall back on.
+	if cfg.AppSecret == "" {
+		logger.PrintError(errors.New("no JWT signing secret, set -app-secret or APP_SECRET"), nil)
+		os.Exit(1)
+	}
 
 	app := New(logger, cfg.GRPC.Port, cfg, cfg.TokenTTL, subsClient)
 
@@ -99,7 +164,12 @@ func main() {
 		"port": strconv.Itoa(cfg.GRPC.Port),
 	})
 	go app.GRPCSrv.MustRun()
-	go runHTTP(cfg.GRPC.Port, logger)
+	go runHTTP(cfg.GRPC.Port, logger, app, []byte(cfg.AppSecret))
+
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	if app.Relay != nil {
+		go app.Relay.Run(relayCtx)
+	}
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
@@ -109,28 +179,254 @@ func main() {
 		"signal": sign.String(),
 	})
 
+	stopRelay()
 	app.GRPCSrv.Stop()
+	if app.Storage != nil {
+		if err := app.Storage.Close(); err != nil {
+			logger.PrintError(err, nil)
+		}
+	}
 
 }
 
+func defaultDSN() string {
+	host := os.Getenv("DB_HOST")
+	port := os.Getenv("DB_PORT")
+	user := os.Getenv("DB_USER")
+	pass := os.Getenv("DB_PASSWORD")
+	name := os.Getenv("DB_NAME")
+
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&client_encoding=UTF8", user, pass, host, port, name)
+}
+
+// defaultSQLitePath is the database file of the sqlite driver when -db-dsn is
+// not given.
+const defaultSQLitePath = "toys.db"
+
+// driverDSN replaces the postgres default of -db-dsn when another driver is
+// chosen and the flag was left out.
+func driverDSN(driver, dsn string, fs *flag.FlagSet) string {
+	if driver != "sqlite" {
+		return dsn
+	}
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == "db-dsn"
+	})
+	if set {
+		return dsn
+	}
+	return defaultSQLitePath
+}
+
+// eventSource is what the outbox relay reads pending toy events from.
+type eventSource interface {
+	DeliverToyEvents(ctx context.Context, limit int, publish func(context.Context, *data.ToyEvent) error, backoff func(attempts int32) time.Duration) (int, error)
+}
+
 func New(log *jsonlog.Logger, grpcPort int, cfg Config, tokenTTL time.Duration, subsClient *subsgrpc.Client) *Application {
-	dbCfg := postgres.StorageDetails(cfg.DB)
+	policies := auth.DefaultPolicies()
+	if err := policies.Override(cfg.Auth.PolicyOverrides); err != nil {
+		log.PrintFatal(err, nil)
+	}
+
+	// Image URLs are built from the media URL, so it has to pass the same
+	// check as the images of a toy.
+	if !validator.New().ImageUrlsCheck([]string{cfg.Media.URL}) {
+		log.PrintFatal(fmt.Errorf("media url %q must start with https:// or /", cfg.Media.URL), nil)
+	}
+	images, err := blobstore.NewLocalStore(cfg.Media.Dir, cfg.Media.URL)
+	if err != nil {
+		log.PrintFatal(err, nil)
+	}
+
+	var toyservice *toys.Toys
+	var events eventSource
+	var storage io.Closer
+	switch cfg.DB.Driver {
+	case "postgres":
+		db := openPostgres(log, cfg)
+		toyservice, events, storage = toys.New(log, db, tokenTTL, subsClient, images), db, db
+	case "sqlite":
+		db := openSQLite(log, cfg)
+		toyservice, events, storage = toys.New(log, db, tokenTTL, subsClient, images), db, db
+	case "memory":
+		// Nothing is persisted: the catalogue starts empty and is gone when
+		// the server stops.
+		store := memory.New(log)
+		toyservice, events = toys.New(log, store, tokenTTL, subsClient, images), store
+	default:
+		log.PrintFatal(fmt.Errorf("unknown db driver %q", cfg.DB.Driver), nil)
+	}
+	grpcApp := grpcapp.New(log, grpcPort, toyservice, policies, []byte(cfg.AppSecret))
+
+	var relay *outbox.Relay
+	if cfg.Outbox.Out != "" {
+		out := os.Stdout
+		if cfg.Outbox.Out != "-" {
+			out, err = os.OpenFile(cfg.Outbox.Out, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				log.PrintFatal(err, nil)
+			}
+		}
+		relay = outbox.NewRelay(log, events, outbox.NewWriterPublisher(out), cfg.Outbox.Interval, cfg.Outbox.BatchSize)
+	}
+
+	return &Application{GRPCSrv: grpcApp, Toys: toyservice, Policies: policies, Relay: relay, MediaURL: cfg.Media.URL, Storage: storage}
+}
+
+// openPostgres connects to the database and, if asked to, applies pending
+// migrations.
+func openPostgres(log *jsonlog.Logger, cfg Config) *postgres.Storage {
+	dbCfg := postgres.StorageDetails{
+		DSN:          cfg.DB.DSN,
+		MaxOpenConns: cfg.DB.MaxOpenConns,
+		MaxIdleConns: cfg.DB.MaxIdleConns,
+		MaxIdleTime:  cfg.DB.MaxIdleTime,
+
+		ReplicaDSNs:   splitList(cfg.DB.ReplicaDSNs),
+		MaxReplicaLag: cfg.DB.MaxReplicaLag,
+	}
 	db, err := postgres.OpenDB(dbCfg, log)
 	if err != nil {
 		log.PrintFatal(err, nil)
 	}
 
+	if cfg.DB.AutoMigrate {
+		migrator, err := db.Migrator(migrations.FS)
+		if err != nil {
+			log.PrintFatal(err, nil)
+		}
+		if err := migrator.Up(context.Background()); err != nil {
+			log.PrintFatal(err, map[string]string{
+				"message": "auto-migrate failed",
+			})
+		}
+	}
+
 	//defer db.Close()
 
-	toyservice := toys.New(log, db, tokenTTL, subsClient)
-	grpcApp := grpcapp.New(log, grpcPort, toyservice)
+	return db
+}
+
+// splitList splits a comma separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// openSQLite opens or creates the database file and, if asked to, applies
+// pending migrations.
+func openSQLite(log *jsonlog.Logger, cfg Config) *sqlite.Storage {
+	dbCfg := sqlite.StorageDetails{
+		DSN:          cfg.DB.DSN,
+		MaxOpenConns: cfg.DB.MaxOpenConns,
+		MaxIdleConns: cfg.DB.MaxIdleConns,
+		MaxIdleTime:  cfg.DB.MaxIdleTime,
+	}
+	db, err := sqlite.OpenDB(dbCfg, log)
+	if err != nil {
+		log.PrintFatal(err, nil)
+	}
+
+	if cfg.DB.AutoMigrate {
+		migrator, err := db.Migrator(sqlitemigrations.FS)
+		if err != nil {
+			log.PrintFatal(err, nil)
+		}
+		if err := migrator.Up(context.Background()); err != nil {
+			log.PrintFatal(err, map[string]string{
+				"message": "auto-migrate failed",
+			})
+		}
+	}
+
+	return db
+}
+
+// catalogStore is what the import and export commands work on.
+type catalogStore interface {
+	Vocabulary(ctx context.Context) (data.Vocabulary, error)
+	ImportToys(ctx context.Context, rows []data.ImportRow, dryRun bool) ([]data.ImportResult, error)
+	ExportToys(ctx context.Context, q data.ToyQuery, fn func(toy *data.Toy) error) error
+	Close() error
+}
+
+// openCatalog opens the storage of a command-line tool with a small pool.
+func openCatalog(driver, dsn string, logger *jsonlog.Logger) (catalogStore, error) {
+	switch driver {
+	case "postgres":
+		db, err := postgres.OpenDB(postgres.StorageDetails{DSN: dsn, MaxOpenConns: 2, MaxIdleConns: 2, MaxIdleTime: "1m"}, logger)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	case "sqlite":
+		db, err := sqlite.OpenDB(sqlite.StorageDetails{DSN: dsn, MaxOpenConns: 2, MaxIdleConns: 2, MaxIdleTime: "1m"}, logger)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unknown db driver %q", driver)
+	}
+}
+
+// passthroughHeaders are exchanged with REST clients under their own names
+// instead of the gateway's Grpc-Metadata- prefix.
+var passthroughHeaders = map[string]bool{
+	toygrpc.VersionHeader:     true,
+	toygrpc.NextCursorHeader:  true,
+	toygrpc.PrevCursorHeader:  true,
+	toygrpc.FuzzyHeader:       true,
+	toygrpc.ReadPrimaryHeader: true,
+}
+
+func incomingHeaderMatcher(key string) (string, bool) {
+	if passthroughHeaders[strings.ToLower(key)] {
+		return key, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
 
-	return &Application{GRPCSrv: grpcApp}
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if passthroughHeaders[strings.ToLower(key)] {
+		return key, true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// queryMetadata forwards REST query parameters the proto request messages have
+// no field for to the gRPC handlers as metadata.
+var queryMetadata = map[string]string{
+	"age_months":    toygrpc.AgeMonthsHeader,
+	"manufacturers": toygrpc.ManufacturersHeader,
+	"cursor":        toygrpc.CursorHeader,
+}
+
+func queryAnnotator(_ context.Context, r *http.Request) metadata.MD {
+	md := metadata.MD{}
+	query := r.URL.Query()
+	for param, key := range queryMetadata {
+		if value := query.Get(param); value != "" {
+			md.Set(key, value)
+		}
+	}
+	return md
 }
 
-func runHTTP(grpcPort int, logger *jsonlog.Logger) {
+func runHTTP(grpcPort int, logger *jsonlog.Logger, app *Application, secret []byte) {
 	ctx := context.Background()
-	mux := runtime.NewServeMux()
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithMetadata(queryAnnotator),
+	)
 	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	}
@@ -143,6 +439,13 @@ func runHTTP(grpcPort int, logger *jsonlog.Logger) {
 		})
 	}
 
+	if err := httptoys.Register(mux, app.Toys, logger, app.Policies, secret, app.MediaURL); err != nil {
+		logger.PrintFatal(err, map[string]string{
+			"message": "failed to register REST routes",
+			"method":  "main.runHTTP",
+		})
+	}
+
 	fs := http.FileServer(http.Dir("C:\\Users\\Еркебулан\\GolandProjects\\toysProto\\gen\\swagger"))
 	http.Handle("/swagger/", http.StripPrefix("/swagger/", fs))
 	http.Handle("/", mux)
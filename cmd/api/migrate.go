@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"toysService/internal/jsonlog"
+	"toysService/migrations"
+	sqlitemigrations "toysService/migrations/sqlite"
+	"toysService/storage/postgres"
+	"toysService/storage/sqlite"
+)
+
+const migrateUsage = `usage: api migrate [flags] <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    roll back the last n migrations (default 1)
+  status      list migrations and whether they are applied
+  goto <v>    migrate up or down to version v (0 rolls everything back)
+  force <v>   record version v as applied without running any migration, to
+              adopt a database migrated by hand or clear a dirty version
+`
+
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	driver := fs.String("db-driver", "postgres", "Storage backend (postgres|sqlite)")
+	dsn := fs.String("db-dsn", defaultDSN(), "PostgresSQL DSN, or the database file with -db-driver=sqlite (default "+defaultSQLitePath+")")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	*dsn = driverDSN(*driver, *dsn, fs)
+
+	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	migrator, db, err := openMigrator(*driver, *dsn, logger)
+	if err != nil {
+		logger.PrintError(err, nil)
+		return 1
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch fs.Arg(0) {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			steps, err = strconv.Atoi(fs.Arg(1))
+			if err != nil || steps < 1 {
+				fs.Usage()
+				return 2
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "goto":
+		if fs.NArg() < 2 {
+			fs.Usage()
+			return 2
+		}
+		version, perr := strconv.ParseInt(fs.Arg(1), 10, 64)
+		if perr != nil || version < 0 {
+			fs.Usage()
+			return 2
+		}
+		err = migrator.Goto(ctx, version)
+	case "force":
+		if fs.NArg() < 2 {
+			fs.Usage()
+			return 2
+		}
+		version, perr := strconv.ParseInt(fs.Arg(1), 10, 64)
+		if perr != nil || version < 0 {
+			fs.Usage()
+			return 2
+		}
+		err = migrator.Force(ctx, version)
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	if err != nil {
+		logger.PrintError(err, map[string]string{
+			"command": "migrate " + fs.Arg(0),
+		})
+		return 1
+	}
+	return 0
+}
+
+// schemaMigrator is what the migrate command drives; every SQL backend has
+// one for its own migrations.
+type schemaMigrator interface {
+	Up(ctx context.Context) error
+	Down(ctx context.Context, steps int) error
+	Goto(ctx context.Context, version int64) error
+	Force(ctx context.Context, version int64) error
+}
+
+// openMigrator opens the database of the migrate command and its migrator;
+// the caller closes the database.
+func openMigrator(driver, dsn string, logger *jsonlog.Logger) (schemaMigrator, io.Closer, error) {
+	switch driver {
+	case "postgres":
+		db, err := postgres.OpenDB(postgres.StorageDetails{DSN: dsn, MaxOpenConns: 2, MaxIdleConns: 2, MaxIdleTime: "1m"}, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := db.Migrator(migrations.FS)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return m, db, nil
+	case "sqlite":
+		db, err := sqlite.OpenDB(sqlite.StorageDetails{DSN: dsn, MaxOpenConns: 2, MaxIdleConns: 2, MaxIdleTime: "1m"}, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := db.Migrator(sqlitemigrations.FS)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return m, db, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown db driver %q", driver)
+	}
+}
+
+func printMigrationStatus(ctx context.Context, migrator schemaMigrator) error {
+	type status struct {
+		version int64
+		name    string
+		applied bool
+	}
+	var statuses []status
+	switch m := migrator.(type) {
+	case *postgres.Migrator:
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range list {
+			statuses = append(statuses, status{st.Version, st.Name, st.Applied})
+		}
+	case *sqlite.Migrator:
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range list {
+			statuses = append(statuses, status{st.Version, st.Name, st.Applied})
+		}
+	}
+
+	for _, st := range statuses {
+		mark := " "
+		if st.applied {
+			mark = "x"
+		}
+		fmt.Printf("[%s] %06d %s\n", mark, st.version, st.name)
+	}
+	return nil
+}
@@ -3,14 +3,12 @@ package grpcapp
 import (
 	"context"
 	"fmt"
-	"github.com/golang-jwt/jwt/v5"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"net"
-	"strings"
-	"toysService/internal/contextkeys"
+	"toysService/internal/auth"
 	toygrpc "toysService/internal/grpc/toys"
 	"toysService/internal/jsonlog"
 )
@@ -21,60 +19,35 @@ type App struct {
 	Port       int
 }
 
-func UnaryJWTInterceptor(secret []byte) grpc.UnaryServerInterceptor {
+func UnaryJWTInterceptor(secret []byte, policies auth.Policies) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler) (interface{}, error) {
 
-		md, ok := metadata.FromIncomingContext(ctx)
+		policy, ok := policies[info.FullMethod]
 		if !ok {
-			return nil, status.Error(codes.Unauthenticated, "missing metadata")
+			return nil, status.Error(codes.PermissionDenied, "insufficient permissions for this method")
 		}
 
-		authHeader := md["authorization"]
-		if len(authHeader) == 0 || !strings.HasPrefix(authHeader[0], "Bearer ") {
-			return nil, status.Error(codes.Unauthenticated, "missing or invalid authorization header")
+		authHeader := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md["authorization"]) > 0 {
+			authHeader = md["authorization"][0]
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader[0], "Bearer ")
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return secret, nil
-		})
-
-		if err != nil || !token.Valid {
-			return nil, status.Error(codes.Unauthenticated, "invalid token")
-		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return nil, status.Error(codes.Internal, "cannot parse claims")
-		}
-
-		userIDFloat, ok := claims["user_id"].(float64)
-		if !ok {
-			return nil, status.Error(codes.Internal, "user ID not found or invalid type in token")
-		}
-
-		// Convert to int64
-		userID := int64(userIDFloat)
+		ctx, err := auth.Authorize(ctx, secret, policy, authHeader)
 		if err != nil {
-			return nil, status.Error(codes.Internal, "invalid user ID format")
+			return nil, err
 		}
-
-		ctx = context.WithValue(ctx, contextkeys.UserIDKey, userID)
 		return handler(ctx, req)
 
 	}
 }
 
-func New(log *jsonlog.Logger, port int, toyService toygrpc.Toys) *App {
+func New(log *jsonlog.Logger, port int, toyService toygrpc.Toys, policies auth.Policies, secret []byte) *App {
 	gRPCServer := grpc.NewServer(
-		grpc.UnaryInterceptor(UnaryJWTInterceptor([]byte("test-secret"))),
+		grpc.UnaryInterceptor(UnaryJWTInterceptor(secret, policies)),
 	)
 
 	toygrpc.Register(gRPCServer, toyService, log)
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"toysService/internal/contextkeys"
+	"toysService/internal/data"
+)
+
+// Authorize applies a method policy to the caller's authorization header and
+// returns a context carrying the user ID and permissions of an authenticated
+// caller. Errors are gRPC status errors.
+func Authorize(ctx context.Context, secret []byte, policy MethodPolicy, authHeader string) (context.Context, error) {
+	if policy.Auth == AuthPublic {
+		return ctx, nil
+	}
+
+	if authHeader == "" {
+		if policy.Auth == AuthOptional {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid authorization header")
+	}
+
+	userID, permissions, err := authenticate(secret, authHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Optional methods are open to anonymous callers, so signing in must not
+	// take them away: their permission only applies to required auth.
+	if policy.Auth == AuthRequired && policy.Permission != "" && !permissions.Include(policy.Permission) {
+		return nil, status.Error(codes.PermissionDenied, "insufficient permissions for this method")
+	}
+
+	ctx = context.WithValue(ctx, contextkeys.UserIDKey, userID)
+	ctx = context.WithValue(ctx, contextkeys.PermissionsKey, permissions)
+	return ctx, nil
+}
+
+func authenticate(secret []byte, authHeader string) (int64, data.Permissions, error) {
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return 0, nil, status.Error(codes.Unauthenticated, "missing or invalid authorization header")
+	}
+
+	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return secret, nil
+	})
+
+	if err != nil || !token.Valid {
+		return 0, nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, nil, status.Error(codes.Internal, "cannot parse claims")
+	}
+
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, nil, status.Error(codes.Internal, "user ID not found or invalid type in token")
+	}
+
+	return int64(userIDFloat), permissionsFromClaims(claims), nil
+}
+
+// permissionsFromClaims grants the permissions of every role listed in the
+// "roles" claim plus any explicit codes from the "permissions" claim. Tokens
+// that carry neither are treated as ordinary subscribers.
+func permissionsFromClaims(claims jwt.MapClaims) data.Permissions {
+	roles := stringsClaim(claims, "roles")
+	if role, ok := claims["role"].(string); ok {
+		roles = append(roles, role)
+	}
+	explicit := stringsClaim(claims, "permissions")
+
+	if len(roles) == 0 && len(explicit) == 0 {
+		roles = []string{data.RoleSubscriber}
+	}
+
+	permissions := data.PermissionsForRoles(roles)
+	for _, code := range explicit {
+		if !permissions.Include(code) {
+			permissions = append(permissions, code)
+		}
+	}
+	return permissions
+}
+
+func stringsClaim(claims jwt.MapClaims, key string) []string {
+	raw, ok := claims[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	values := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
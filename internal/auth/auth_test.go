@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"slices"
+	"testing"
+	"toysService/internal/contextkeys"
+	"toysService/internal/data"
+)
+
+var testSecret = []byte("s3cret")
+
+func bearer(t *testing.T, secret []byte, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+func TestPermissionsFromClaims(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		claims jwt.MapClaims
+		want   data.Permissions
+	}{
+		{"no roles", jwt.MapClaims{}, data.Permissions{data.PermissionToysRead, data.PermissionReservationsWrite}},
+		{"admin role", jwt.MapClaims{"role": data.RoleAdmin}, data.Permissions{data.PermissionToysRead, data.PermissionToysWrite, data.PermissionReservationsWrite}},
+		{"roles list", jwt.MapClaims{"roles": []any{data.RoleSubscriber, data.RoleAdmin}}, data.Permissions{data.PermissionToysRead, data.PermissionReservationsWrite, data.PermissionToysWrite}},
+		{"unknown role", jwt.MapClaims{"role": "janitor"}, data.Permissions{}},
+		{"explicit only", jwt.MapClaims{"permissions": []any{data.PermissionToysWrite, 7}}, data.Permissions{data.PermissionToysWrite}},
+		{"role and explicit", jwt.MapClaims{"role": data.RoleSubscriber, "permissions": []any{data.PermissionToysRead, data.PermissionToysWrite}}, data.Permissions{data.PermissionToysRead, data.PermissionReservationsWrite, data.PermissionToysWrite}},
+	} {
+		if got := permissionsFromClaims(tc.claims); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	subscriber := bearer(t, testSecret, jwt.MapClaims{"user_id": 7, "role": data.RoleSubscriber})
+	admin := bearer(t, testSecret, jwt.MapClaims{"user_id": 1, "role": data.RoleAdmin})
+	forged := bearer(t, []byte("other"), jwt.MapClaims{"user_id": 1, "role": data.RoleAdmin})
+	noUser := bearer(t, testSecret, jwt.MapClaims{"role": data.RoleAdmin})
+
+	write := MethodPolicy{Auth: AuthRequired, Permission: data.PermissionToysWrite}
+	for _, tc := range []struct {
+		name   string
+		policy MethodPolicy
+		header string
+		code   codes.Code
+		userID int64
+	}{
+		{"permission granted", write, admin, codes.OK, 1},
+		{"permission missing", write, subscriber, codes.PermissionDenied, 0},
+		{"no permission needed", MethodPolicy{Auth: AuthRequired}, subscriber, codes.OK, 7},
+		{"no header", write, "", codes.Unauthenticated, 0},
+		{"not a bearer token", write, "Basic dXNlcjpwYXNz", codes.Unauthenticated, 0},
+		{"wrong signature", write, forged, codes.Unauthenticated, 0},
+		{"no user id", write, noUser, codes.Internal, 0},
+		{"optional, anonymous", MethodPolicy{Auth: AuthOptional, Permission: data.PermissionToysRead}, "", codes.OK, 0},
+		{"optional, signed in without the permission", MethodPolicy{Auth: AuthOptional, Permission: data.PermissionToysWrite}, subscriber, codes.OK, 7},
+		{"optional, bad token", MethodPolicy{Auth: AuthOptional}, forged, codes.Unauthenticated, 0},
+		{"public ignores the header", MethodPolicy{Auth: AuthPublic, Permission: data.PermissionToysWrite}, forged, codes.OK, 0},
+	} {
+		ctx, err := Authorize(context.Background(), testSecret, tc.policy, tc.header)
+		if code := status.Code(err); code != tc.code {
+			t.Errorf("%s: got %v, want %v", tc.name, code, tc.code)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		userID, _ := ctx.Value(contextkeys.UserIDKey).(int64)
+		if userID != tc.userID {
+			t.Errorf("%s: user ID %d, want %d", tc.name, userID, tc.userID)
+		}
+		if tc.userID != 0 {
+			if _, ok := ctx.Value(contextkeys.PermissionsKey).(data.Permissions); !ok {
+				t.Errorf("%s: no permissions in the context", tc.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
+	"strings"
+	"toysService/internal/data"
+)
+
+type AuthMode int
+
+const (
+	// AuthRequired rejects calls without a valid bearer token.
+	AuthRequired AuthMode = iota
+	// AuthOptional lets anonymous calls through but still authenticates a
+	// token when one is sent, so UserIDKey is filled for logged-in users. The
+	// method's permission is not checked, as anonymous callers have none.
+	AuthOptional
+	// AuthPublic never looks at the authorization header.
+	AuthPublic
+)
+
+func (m AuthMode) String() string {
+	switch m {
+	case AuthRequired:
+		return "required"
+	case AuthOptional:
+		return "optional"
+	case AuthPublic:
+		return "public"
+	default:
+		return ""
+	}
+}
+
+func ParseAuthMode(s string) (AuthMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "required":
+		return AuthRequired, nil
+	case "optional":
+		return AuthOptional, nil
+	case "public":
+		return AuthPublic, nil
+	default:
+		return AuthRequired, fmt.Errorf("unknown auth mode %q", s)
+	}
+}
+
+type MethodPolicy struct {
+	Auth       AuthMode
+	Permission string
+}
+
+// Policies is keyed by full gRPC method name, e.g. "/toys.Toys/ListToy", or
+// by "METHOD /path" for REST-only routes served by the gateway.
+// Methods missing from the table are denied.
+type Policies map[string]MethodPolicy
+
+func DefaultPolicies() Policies {
+	return Policies{
+		toys.Toys_CreateToy_FullMethodName:       {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		toys.Toys_DeleteToy_FullMethodName:       {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		toys.Toys_ChangeToy_FullMethodName:       {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		toys.Toys_GetToy_FullMethodName:          {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		toys.Toys_ListToy_FullMethodName:         {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		toys.Toys_GetToysByIds_FullMethodName:    {Auth: AuthRequired, Permission: data.PermissionToysRead},
+		toys.Toys_ListRecommended_FullMethodName: {Auth: AuthRequired, Permission: data.PermissionToysRead},
+
+		"POST /v1/toys/{toy_id}/reservations":        {Auth: AuthRequired, Permission: data.PermissionReservationsWrite},
+		"GET /v1/reservations":                       {Auth: AuthRequired, Permission: data.PermissionReservationsWrite},
+		"DELETE /v1/reservations/{reservation_id}":   {Auth: AuthRequired, Permission: data.PermissionReservationsWrite},
+		"POST /v1/toys/{toy_id}/units":               {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"GET /v1/toys/{toy_id}/units":                {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"PATCH /v1/units/{unit_id}":                  {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"POST /v1/units/{unit_id}/retire":            {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"POST /v1/toys/{toy_id}/restore":             {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"GET /v1/toys/deleted":                       {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"GET /v1/toys/{toy_id}/history":              {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"GET /v1/toys/search":                        {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		"GET /v1/toys/suggest":                       {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		"GET /v1/toys/export":                        {Auth: AuthRequired, Permission: data.PermissionToysRead},
+		"POST /v1/toys/batch":                        {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"PATCH /v1/toys/batch":                       {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"GET /v1/categories":                         {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		"GET /v1/categories/{slug}":                  {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		"POST /v1/categories":                        {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"PATCH /v1/categories/{slug}":                {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"DELETE /v1/categories/{slug}":               {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"GET /v1/skills":                             {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		"POST /v1/skills":                            {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"PATCH /v1/skills/{skill_id}":                {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"DELETE /v1/skills/{skill_id}":               {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"GET /v1/manufacturers":                      {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		"GET /v1/manufacturers/{manufacturer_id}":    {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		"POST /v1/manufacturers":                     {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"PATCH /v1/manufacturers/{manufacturer_id}":  {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"DELETE /v1/manufacturers/{manufacturer_id}": {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"POST /v1/images":                            {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"DELETE /v1/images/{image_id}":               {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"GET /v1/toys/{toy_id}/images":               {Auth: AuthOptional, Permission: data.PermissionToysRead},
+		"PUT /v1/toys/{toy_id}/images":               {Auth: AuthRequired, Permission: data.PermissionToysWrite},
+		"GET /media/{key}":                           {Auth: AuthPublic},
+	}
+}
+
+// Override changes the auth mode of the listed methods. The spec is a comma
+// separated list of method=mode pairs, e.g.
+// "/toys.Toys/GetToy=public,/toys.Toys/ListToy=required".
+func (p Policies) Override(spec string) error {
+	if strings.TrimSpace(spec) == "" {
+		return nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		method, rawMode, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return fmt.Errorf("invalid auth policy %q", pair)
+		}
+		policy, ok := p[method]
+		if !ok {
+			return fmt.Errorf("unknown method %q in auth policy", method)
+		}
+		mode, err := ParseAuthMode(rawMode)
+		if err != nil {
+			return err
+		}
+		policy.Auth = mode
+		p[method] = policy
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
+	"testing"
+)
+
+func TestParseAuthMode(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want AuthMode
+		ok   bool
+	}{
+		{"required", AuthRequired, true},
+		{" Optional ", AuthOptional, true},
+		{"PUBLIC", AuthPublic, true},
+		{"anonymous", AuthRequired, false},
+		{"", AuthRequired, false},
+	} {
+		mode, err := ParseAuthMode(tc.text)
+		if mode != tc.want || (err == nil) != tc.ok {
+			t.Errorf("ParseAuthMode(%q) = %v, %v, want %v, ok %t", tc.text, mode, err, tc.want, tc.ok)
+		}
+		if tc.ok && mode.String() != tc.want.String() {
+			t.Errorf("%v does not round-trip through String", mode)
+		}
+	}
+}
+
+func TestOverride(t *testing.T) {
+	p := DefaultPolicies()
+	err := p.Override(" " + toys.Toys_GetToy_FullMethodName + "=public, " + toys.Toys_ListToy_FullMethodName + "=required,GET /v1/toys/search=public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for method, want := range map[string]AuthMode{
+		toys.Toys_GetToy_FullMethodName:    AuthPublic,
+		toys.Toys_ListToy_FullMethodName:   AuthRequired,
+		"GET /v1/toys/search":              AuthPublic,
+		toys.Toys_CreateToy_FullMethodName: AuthRequired,
+	} {
+		if got := p[method].Auth; got != want {
+			t.Errorf("%s: got %v, want %v", method, got, want)
+		}
+	}
+	if p[toys.Toys_GetToy_FullMethodName].Permission == "" {
+		t.Error("Override dropped the permission of the method")
+	}
+
+	if err = p.Override(""); err != nil {
+		t.Errorf("empty spec: %v", err)
+	}
+	for _, spec := range []string{
+		toys.Toys_GetToy_FullMethodName,
+		"/toys.Toys/Unknown=public",
+		toys.Toys_GetToy_FullMethodName + "=anonymous",
+	} {
+		if err = DefaultPolicies().Override(spec); err == nil {
+			t.Errorf("Override(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// keyRX limits keys to flat file names, so a key can never point outside the
+// store whatever the backend.
+var keyRX = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*(\.[a-z0-9]+)?$`)
+
+// Store keeps uploaded files such as toy images. URL returns where clients
+// download a blob from; it does not check that the blob exists.
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+func ValidKey(key string) bool {
+	return keyRX.MatchString(key)
+}
@@ -0,0 +1,244 @@
+// Package catalog reads and writes toy catalogs as CSV or JSON lines files,
+// the formats the import and export commands exchange with other systems.
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"toysService/internal/data"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+// ListSeparator joins list values such as categories in a CSV cell.
+const ListSeparator = "|"
+
+// importColumns are the CSV columns an import understands. They are named
+// after the JSON fields of data.Toy, which is also what JSON lines use.
+var importColumns = map[string]bool{
+	"sku":            true,
+	"title":          true,
+	"desc":           true,
+	"value":          true,
+	"recommendedAge": true,
+	"minAgeMonths":   true,
+	"maxAgeMonths":   true,
+	"manufacturer":   true,
+	"categories":     true,
+	"skills":         true,
+	"images":         true,
+}
+
+// FormatOf guesses a file's format from its extension. Only exports can be
+// written as XLSX.
+func FormatOf(path string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, true
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, true
+	case ".xlsx":
+		return FormatXLSX, true
+	default:
+		return "", false
+	}
+}
+
+// RowError is a row that could not be parsed. Reading can go on after it.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads toys from an import file one row at a time. Read returns a
+// *RowError for a malformed row and io.EOF at the end of the file.
+type Reader struct {
+	format string
+	csv    *csv.Reader
+	header []string
+	lines  *bufio.Scanner
+	line   int
+}
+
+func NewReader(r io.Reader, format string) (*Reader, error) {
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		header, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV header: %w", err)
+		}
+		for i, column := range header {
+			column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+			if !importColumns[column] {
+				return nil, fmt.Errorf("unknown CSV column %q", column)
+			}
+			header[i] = column
+		}
+		return &Reader{format: format, csv: cr, header: header}, nil
+	case FormatJSONL:
+		lines := bufio.NewScanner(r)
+		lines.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &Reader{format: format, lines: lines}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// Read returns the next toy and the line it starts on. Images stay nil when
+// the row leaves them out, which imports take as "keep the current images".
+func (r *Reader) Read() (data.Toy, int, error) {
+	if r.format == FormatCSV {
+		return r.readCSV()
+	}
+	return r.readJSONL()
+}
+
+func (r *Reader) readCSV() (data.Toy, int, error) {
+	record, err := r.csv.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return data.Toy{}, parseErr.StartLine, &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return data.Toy{}, 0, err
+	}
+	line, _ := r.csv.FieldPos(0)
+	if len(record) != len(r.header) {
+		return data.Toy{}, line, &RowError{Line: line, Err: fmt.Errorf("expected %d fields, got %d", len(r.header), len(record))}
+	}
+
+	var toy data.Toy
+	for i, column := range r.header {
+		value := unescapeCell(strings.TrimSpace(record[i]))
+		switch column {
+		case "sku":
+			toy.SKU = value
+		case "title":
+			toy.Title = value
+		case "desc":
+			toy.Desc = value
+		case "value":
+			if value == "" {
+				continue
+			}
+			toy.Value, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return data.Toy{}, line, &RowError{Line: line, Err: errors.New("value must be a whole number")}
+			}
+		case "recommendedAge":
+			toy.RecAge = value
+		case "minAgeMonths", "maxAgeMonths":
+			if value == "" {
+				continue
+			}
+			months, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return data.Toy{}, line, &RowError{Line: line, Err: fmt.Errorf("%s must be a whole number", column)}
+			}
+			if column == "minAgeMonths" {
+				toy.MinAgeMonths = int32(months)
+			} else {
+				toy.MaxAgeMonths = int32(months)
+			}
+		case "manufacturer":
+			toy.Manufacturer = value
+		case "categories":
+			toy.Categories = splitList(value)
+		case "skills":
+			toy.Skills = splitList(value)
+		case "images":
+			toy.Images = splitList(value)
+		}
+	}
+	return toy, line, nil
+}
+
+func (r *Reader) readJSONL() (data.Toy, int, error) {
+	for r.lines.Scan() {
+		r.line++
+		text := bytes.TrimSpace(r.lines.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var input struct {
+			SKU          string   `json:"sku"`
+			Title        string   `json:"title"`
+			Desc         string   `json:"desc"`
+			Value        int64    `json:"value"`
+			RecAge       string   `json:"recommendedAge"`
+			MinAgeMonths int32    `json:"minAgeMonths"`
+			MaxAgeMonths int32    `json:"maxAgeMonths"`
+			Manufacturer string   `json:"manufacturer"`
+			Categories   []string `json:"categories"`
+			Skills       []string `json:"skills"`
+			Images       []string `json:"images"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&input); err != nil {
+			return data.Toy{}, r.line, &RowError{Line: r.line, Err: err}
+		}
+		return data.Toy{
+			SKU:          strings.TrimSpace(input.SKU),
+			Title:        input.Title,
+			Desc:         input.Desc,
+			Value:        input.Value,
+			RecAge:       input.RecAge,
+			MinAgeMonths: input.MinAgeMonths,
+			MaxAgeMonths: input.MaxAgeMonths,
+			Manufacturer: input.Manufacturer,
+			Categories:   input.Categories,
+			Skills:       input.Skills,
+			Images:       input.Images,
+		}, r.line, nil
+	}
+	if err := r.lines.Err(); err != nil {
+		return data.Toy{}, r.line, err
+	}
+	return data.Toy{}, r.line, io.EOF
+}
+
+// unescapeCell takes off the apostrophe an export puts in front of text a
+// spreadsheet would take for a formula.
+func unescapeCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes+"'", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// splitList splits a CSV list cell. An empty cell is a nil list.
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	items := strings.Split(value, ListSeparator)
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
@@ -0,0 +1,307 @@
+package catalog
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"toysService/internal/data"
+)
+
+// ExportColumns lists every column an export can include, in the order they
+// appear when selected.
+var ExportColumns = []string{
+	"id", "sku", "title", "desc", "value", "recommendedAge", "minAgeMonths", "maxAgeMonths",
+	"manufacturer", "manufacturerId", "categories", "skills", "images", "availableCount", "createdAt",
+}
+
+// DefaultColumns are exported when the caller picks none. Apart from id they
+// are what an import reads, so an export can be edited and imported again.
+var DefaultColumns = []string{
+	"id", "sku", "title", "desc", "value", "recommendedAge", "manufacturer", "categories", "skills", "images",
+}
+
+// ParseColumns reads a comma separated column selection. Unknown and repeated
+// columns are errors; an empty selection means DefaultColumns.
+func ParseColumns(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultColumns, nil
+	}
+	known := map[string]bool{}
+	for _, column := range ExportColumns {
+		known[column] = true
+	}
+
+	var columns []string
+	seen := map[string]bool{}
+	for _, column := range strings.Split(s, ",") {
+		column = strings.TrimSpace(column)
+		switch {
+		case !known[column]:
+			return nil, fmt.Errorf("unknown column %q", column)
+		case seen[column]:
+			return nil, fmt.Errorf("column %q is listed twice", column)
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// ContentType is the media type exports of a format are served with.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// columnValue is the value of one export column: a string, a number or a
+// list of strings.
+func columnValue(toy *data.Toy, column string) any {
+	switch column {
+	case "id":
+		return toy.ID
+	case "sku":
+		return toy.SKU
+	case "title":
+		return toy.Title
+	case "desc":
+		return toy.Desc
+	case "value":
+		return toy.Value
+	case "recommendedAge":
+		return toy.RecAge
+	case "minAgeMonths":
+		return int64(toy.MinAgeMonths)
+	case "maxAgeMonths":
+		return int64(toy.MaxAgeMonths)
+	case "manufacturer":
+		return toy.Manufacturer
+	case "manufacturerId":
+		return toy.ManufacturerID
+	case "categories":
+		return toy.Categories
+	case "skills":
+		return toy.Skills
+	case "images":
+		return toy.Images
+	case "availableCount":
+		return int64(toy.AvailableCount)
+	case "createdAt":
+		return createdAt(toy.CreatedAt)
+	default:
+		return ""
+	}
+}
+
+// createdAt writes a creation time in RFC 3339, in UTC, whichever way the
+// backend stored it.
+func createdAt(s string) string {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return s
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formulaPrefixes start a cell that spreadsheets would run as a formula.
+const formulaPrefixes = "=+-@\t\r"
+
+// cellText formats a column value for the flat formats. Text that a
+// spreadsheet would take for a formula gets a leading apostrophe, and so
+// does text that starts with one, so that imports can take it off again.
+func cellText(value any) string {
+	var text string
+	switch value := value.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case []string:
+		text = strings.Join(value, ListSeparator)
+	default:
+		text = fmt.Sprint(value)
+	}
+	if text != "" && strings.ContainsRune(formulaPrefixes+"'", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// Writer writes toys one at a time. Close finishes the file but does not
+// close the underlying writer.
+type Writer interface {
+	Write(toy *data.Toy) error
+	Close() error
+}
+
+// NewWriter starts an export file. Formats that have a header write it
+// straight away.
+func NewWriter(w io.Writer, format string, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := &csvWriter{w: csv.NewWriter(w), columns: columns}
+		return cw, cw.w.Write(columns)
+	case FormatJSONL:
+		return &jsonlWriter{w: w, columns: columns}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	record  []string
+}
+
+func (c *csvWriter) Write(toy *data.Toy) error {
+	c.record = c.record[:0]
+	for _, column := range c.columns {
+		c.record = append(c.record, cellText(columnValue(toy, column)))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlWriter struct {
+	w       io.Writer
+	columns []string
+	buf     []byte
+}
+
+// Write writes the selected columns as one JSON object, keeping the column
+// order.
+func (j *jsonlWriter) Write(toy *data.Toy) error {
+	j.buf = append(j.buf[:0], '{')
+	for i, column := range j.columns {
+		if i > 0 {
+			j.buf = append(j.buf, ',')
+		}
+		j.buf = strconv.AppendQuote(j.buf, column)
+		j.buf = append(j.buf, ':')
+
+		value := columnValue(toy, column)
+		if list, ok := value.([]string); ok && list == nil {
+			value = []string{}
+		}
+		js, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		j.buf = append(j.buf, js...)
+	}
+	j.buf = append(j.buf, '}', '\n')
+	_, err := j.w.Write(j.buf)
+	return err
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
+
+// xlsxParts are the fixed parts of a single-sheet workbook. Cells use inline
+// strings, so no shared string table has to be built up in memory.
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Toys" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter streams the sheet straight into the zip archive; the sheet is
+// the last part, so rows never have to be held back.
+type xlsxWriter struct {
+	zip     *zip.Writer
+	sheet   io.Writer
+	columns []string
+	row     strings.Builder
+}
+
+func newXLSXWriter(w io.Writer, columns []string) (*xlsxWriter, error) {
+	x := &xlsxWriter{zip: zip.NewWriter(w), columns: columns}
+	for _, part := range xlsxParts {
+		f, err := x.zip.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x.sheet = sheet
+	_, err = io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	return x, x.writeRow(header)
+}
+
+func (x *xlsxWriter) Write(toy *data.Toy) error {
+	values := make([]any, len(x.columns))
+	for i, column := range x.columns {
+		values[i] = columnValue(toy, column)
+	}
+	return x.writeRow(values)
+}
+
+func (x *xlsxWriter) writeRow(values []any) error {
+	x.row.Reset()
+	x.row.WriteString("<row>")
+	for _, value := range values {
+		if n, ok := value.(int64); ok {
+			fmt.Fprintf(&x.row, `<c t="n"><v>%d</v></c>`, n)
+			continue
+		}
+		x.row.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(&x.row, []byte(cellText(value)))
+		x.row.WriteString(`</t></is></c>`)
+	}
+	x.row.WriteString("</row>")
+	_, err := io.WriteString(x.sheet, x.row.String())
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
@@ -0,0 +1,196 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"toysService/internal/data"
+)
+
+func TestParseColumns(t *testing.T) {
+	for _, tc := range []struct {
+		spec string
+		want []string
+		ok   bool
+	}{
+		{"", DefaultColumns, true},
+		{"  ", DefaultColumns, true},
+		{"title, value,createdAt", []string{"title", "value", "createdAt"}, true},
+		{"title,price", nil, false},
+		{"title,,value", nil, false},
+		{"sku,title,sku", nil, false},
+	} {
+		columns, err := ParseColumns(tc.spec)
+		if !slices.Equal(columns, tc.want) || (err == nil) != tc.ok {
+			t.Errorf("ParseColumns(%q) = %q, %v, want %q, ok %t", tc.spec, columns, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCellText(t *testing.T) {
+	for _, tc := range []struct {
+		value any
+		want  string
+	}{
+		{int64(-5), "-5"},
+		{"Ball", "Ball"},
+		{"", ""},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+7 700 000", "'+7 700 000"},
+		{"-10%", "'-10%"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tindented", "'\tindented"},
+		{"'quoted'", "''quoted'"},
+		{"a=b", "a=b"},
+		{[]string{"=cmd", "b"}, "'=cmd|b"},
+		{[]string(nil), ""},
+	} {
+		if got := cellText(tc.value); got != tc.want {
+			t.Errorf("cellText(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestUnescapeCell(t *testing.T) {
+	for _, text := range []string{"Ball", "", "=1+2", "-10%", "'quoted'", "''", "'", "it's"} {
+		if got := unescapeCell(cellText(text)); got != text {
+			t.Errorf("unescapeCell(cellText(%q)) = %q", text, got)
+		}
+	}
+	// Apostrophes that no export would have added stay.
+	for _, text := range []string{"'Ball", "'", "'1"} {
+		if got := unescapeCell(text); got != text {
+			t.Errorf("unescapeCell(%q) = %q, want it unchanged", text, got)
+		}
+	}
+}
+
+func TestCreatedAt(t *testing.T) {
+	for _, tc := range []struct {
+		stored string
+		want   string
+	}{
+		{"2024-03-01T15:04:05+06:00", "2024-03-01T09:04:05Z"},
+		{"2024-03-01T09:04:05.123456Z", "2024-03-01T09:04:05Z"},
+		{"2024-03-01T09:04:05Z", "2024-03-01T09:04:05Z"},
+		{"2024-03-01 09:04:05", "2024-03-01 09:04:05"},
+		{"", ""},
+	} {
+		if got := createdAt(tc.stored); got != tc.want {
+			t.Errorf("createdAt(%q) = %q, want %q", tc.stored, got, tc.want)
+		}
+	}
+}
+
+var exportedToys = []data.Toy{
+	{
+		ID: 1, SKU: "A-1", Title: "=Ball", Desc: "red, \"bouncy\"\nball", Value: 2500, RecAge: "3+",
+		Manufacturer: "Acme", Categories: []string{"games", "outdoor"}, Skills: []string{"motor"},
+		Images: []string{"https://example.com/ball.jpg"}, CreatedAt: "2024-03-01T15:04:05+06:00",
+	},
+	{ID: 2, SKU: "B-2", Title: "'Quoted'", Value: 4000, Manufacturer: "Zeta"},
+}
+
+func writeAll(t *testing.T, format string, columns []string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range exportedToys {
+		if err = w.Write(&exportedToys[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestCSVWriter(t *testing.T) {
+	got := writeAll(t, FormatCSV, []string{"id", "title", "value", "categories", "createdAt"})
+	want := "id,title,value,categories,createdAt\n" +
+		"1,'=Ball,2500,games|outdoor,2024-03-01T09:04:05Z\n" +
+		"2,''Quoted',4000,,\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestCSVRoundTrip checks that an export in the import columns reads back as
+// the toys it was written from, escaped cells included.
+func TestCSVRoundTrip(t *testing.T) {
+	columns := slices.DeleteFunc(slices.Clone(DefaultColumns), func(column string) bool { return column == "id" })
+	r, err := NewReader(strings.NewReader(writeAll(t, FormatCSV, columns)), FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range exportedToys {
+		toy, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if toy.SKU != want.SKU || toy.Title != want.Title || toy.Desc != want.Desc || toy.Value != want.Value ||
+			toy.RecAge != want.RecAge || toy.Manufacturer != want.Manufacturer ||
+			!slices.Equal(toy.Categories, want.Categories) || !slices.Equal(toy.Skills, want.Skills) || !slices.Equal(toy.Images, want.Images) {
+			t.Errorf("got %+v, want %+v", toy, want)
+		}
+	}
+	if _, _, err = r.Read(); err != io.EOF {
+		t.Errorf("after the last toy: got %v, want io.EOF", err)
+	}
+}
+
+func TestJSONLWriter(t *testing.T) {
+	got := writeAll(t, FormatJSONL, []string{"title", "id", "skills", "createdAt"})
+	want := `{"title":"=Ball","id":1,"skills":["motor"],"createdAt":"2024-03-01T09:04:05Z"}` + "\n" +
+		`{"title":"'Quoted'","id":2,"skills":[],"createdAt":""}` + "\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	out := writeAll(t, FormatXLSX, []string{"id", "title"})
+	archive, err := zip.NewReader(strings.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sheet string
+	for _, f := range archive.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet = string(b)
+	}
+
+	for _, cell := range []string{
+		`<c t="inlineStr"><is><t xml:space="preserve">title</t></is></c>`,
+		`<c t="n"><v>1</v></c>`,
+		`<c t="inlineStr"><is><t xml:space="preserve">&#39;=Ball</t></is></c>`,
+		`<c t="inlineStr"><is><t xml:space="preserve">&#39;&#39;Quoted&#39;</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("sheet is missing %s:\n%s", cell, sheet)
+		}
+	}
+	if !strings.HasSuffix(sheet, "</sheetData></worksheet>") {
+		t.Errorf("sheet is not closed:\n%s", sheet)
+	}
+}
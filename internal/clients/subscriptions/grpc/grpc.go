@@ -10,6 +10,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"strconv"
 	"time"
 	"toysService/internal/jsonlog"
 )
@@ -52,7 +53,7 @@ func New(ctx context.Context, log *jsonlog.Logger, addr int, timeout time.Durati
 func InterceptorLogger(logger *jsonlog.Logger) grpclog.Logger {
 	return grpclog.LoggerFunc(func(ctx context.Context, lvl grpclog.Level, msg string, fields ...any) {
 		logger.PrintInfo(msg, map[string]string{
-			"lvl": string(lvl),
+			"lvl": strconv.Itoa(int(lvl)),
 		})
 	},
 	)
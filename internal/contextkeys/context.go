@@ -3,3 +3,9 @@ package contextkeys
 type ContentKey string
 
 const UserIDKey = ContentKey("user_id")
+
+const PermissionsKey = ContentKey("permissions")
+
+// ReadPrimaryKey marks a request whose reads must see its own writes, so the
+// storage serves them from the primary database instead of a replica.
+const ReadPrimaryKey = ContentKey("read_primary")
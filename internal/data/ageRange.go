@@ -0,0 +1,108 @@
+package data
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxAgeMonths is the oldest age, in months, a toy can be recommended for.
+const MaxAgeMonths = 18 * 12
+
+var ageNumberRX = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*([a-zа-яё]*)`)
+
+var ageUpperBoundWords = []string{"до", "up to", "under", "<"}
+
+// AgeRange returns the range in months a toy is stored with: MinAgeMonths
+// and MaxAgeMonths when the caller set them, otherwise the range parsed from
+// RecAge. A toy whose text age changed has to have its old range cleared
+// first. ok is false if the given range is inverted or the text does not
+// parse.
+func (t Toy) AgeRange() (minMonths int32, maxMonths int32, ok bool) {
+	if t.MinAgeMonths != 0 || t.MaxAgeMonths != 0 {
+		ok = t.MinAgeMonths >= 0 && (t.MaxAgeMonths == 0 || t.MaxAgeMonths >= t.MinAgeMonths)
+		return t.MinAgeMonths, t.MaxAgeMonths, ok
+	}
+	return ParseAgeRange(t.RecAge)
+}
+
+// ParseAgeRange turns a free-form recommended age such as "3+", "3-5 лет",
+// "от 3 лет", "6-18 мес", "до 2 лет" or "1.5 years" into a range in months.
+// A zero max means the range has no upper bound. Numbers without a unit take
+// the unit of the next number ("3-5 лет") and default to years.
+func ParseAgeRange(s string) (minMonths int32, maxMonths int32, ok bool) {
+	text := strings.ToLower(strings.TrimSpace(s))
+	text = strings.NewReplacer("–", "-", "—", "-").Replace(text)
+
+	matches := ageNumberRX.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 || len(matches) > 2 {
+		return 0, 0, false
+	}
+
+	units := make([]int, len(matches))
+	for i := len(matches) - 1; i >= 0; i-- {
+		units[i] = ageUnitMonths(matches[i][2])
+		if units[i] == 0 {
+			units[i] = 12
+			if i+1 < len(matches) {
+				units[i] = units[i+1]
+			}
+		}
+	}
+
+	values := make([]int32, len(matches))
+	for i, m := range matches {
+		n, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", "."), 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		values[i] = int32(math.Round(n * float64(units[i])))
+	}
+
+	switch {
+	case len(values) == 2:
+		minMonths, maxMonths = values[0], values[1]
+		if maxMonths < minMonths {
+			return 0, 0, false
+		}
+	case hasUpperBoundWord(text):
+		minMonths, maxMonths = 0, values[0]
+		if maxMonths == 0 {
+			return 0, 0, false
+		}
+	default:
+		minMonths = values[0]
+	}
+
+	return minMonths, maxMonths, true
+}
+
+// ageUnitMonths returns how many months one unit of the given word is, or 0
+// when the word is not a recognised unit.
+func ageUnitMonths(word string) int {
+	switch {
+	case word == "":
+		return 0
+	case strings.HasPrefix(word, "мес"), strings.HasPrefix(word, "mo"), word == "m", word == "mos":
+		return 1
+	case strings.HasPrefix(word, "г"), strings.HasPrefix(word, "л"), strings.HasPrefix(word, "y"):
+		return 12
+	default:
+		return 0
+	}
+}
+
+func hasUpperBoundWord(text string) bool {
+	for _, word := range ageUpperBoundWords {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// SuitsAge reports whether a child aged ageMonths falls into the toy's range.
+func (t Toy) SuitsAge(ageMonths int32) bool {
+	return t.MinAgeMonths <= ageMonths && (t.MaxAgeMonths == 0 || t.MaxAgeMonths >= ageMonths)
+}
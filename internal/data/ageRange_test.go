@@ -0,0 +1,55 @@
+package data
+
+import "testing"
+
+func TestParseAgeRange(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		min, max int32
+		ok       bool
+	}{
+		{"3+", 36, 0, true},
+		{"3-5 лет", 36, 60, true},
+		{"3–5", 36, 60, true},
+		{"от 3 лет", 36, 0, true},
+		{"6-18 мес", 6, 18, true},
+		{"6 мес - 2 года", 6, 24, true},
+		{"до 2 лет", 0, 24, true},
+		{"up to 18 months", 0, 18, true},
+		{"1.5 years", 18, 0, true},
+		{"1,5 года", 18, 0, true},
+		{"  12 Months - 3 Years ", 12, 36, true},
+		{"5-3 years", 0, 0, false},
+		{"до 0 лет", 0, 0, false},
+		{"1, 2, 3", 0, 0, false},
+		{"для всех", 0, 0, false},
+		{"", 0, 0, false},
+	} {
+		minMonths, maxMonths, ok := ParseAgeRange(tc.text)
+		if minMonths != tc.min || maxMonths != tc.max || ok != tc.ok {
+			t.Errorf("ParseAgeRange(%q) = %d, %d, %t, want %d, %d, %t", tc.text, minMonths, maxMonths, ok, tc.min, tc.max, tc.ok)
+		}
+	}
+}
+
+func TestToyAgeRange(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		toy      Toy
+		min, max int32
+		ok       bool
+	}{
+		{"parsed from text", Toy{RecAge: "3-5 лет"}, 36, 60, true},
+		{"given range wins over text", Toy{RecAge: "3-5 лет", MinAgeMonths: 6, MaxAgeMonths: 12}, 6, 12, true},
+		{"open-ended", Toy{MinAgeMonths: 24}, 24, 0, true},
+		{"upper bound only", Toy{MaxAgeMonths: 12}, 0, 12, true},
+		{"inverted", Toy{MinAgeMonths: 24, MaxAgeMonths: 12}, 24, 12, false},
+		{"negative", Toy{MinAgeMonths: -1}, -1, 0, false},
+		{"unparsable text", Toy{RecAge: "для всех"}, 0, 0, false},
+	} {
+		minMonths, maxMonths, ok := tc.toy.AgeRange()
+		if minMonths != tc.min || maxMonths != tc.max || ok != tc.ok {
+			t.Errorf("%s: got %d, %d, %t, want %d, %d, %t", tc.name, minMonths, maxMonths, ok, tc.min, tc.max, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package data
+
+// MaxBatchSize caps how many toys one batch create or update may carry.
+const MaxBatchSize = 500
+
+// Batch modes. An all-or-nothing batch runs in one transaction that is
+// rolled back if any item fails; a best-effort batch writes every item that
+// can be written.
+const (
+	BatchAllOrNothing = "all_or_nothing"
+	BatchBestEffort   = "best_effort"
+)
+
+// Batch item statuses.
+const (
+	BatchStatusCreated  = "created"
+	BatchStatusUpdated  = "updated"
+	BatchStatusInvalid  = "invalid"
+	BatchStatusNotFound = "not_found"
+	BatchStatusConflict = "conflict"
+	BatchStatusFailed   = "failed"
+	// BatchStatusAborted marks items of an all-or-nothing batch that were not
+	// written because another item failed.
+	BatchStatusAborted = "aborted"
+)
+
+// BatchItem is one toy of a batch; Index is its position in the request.
+type BatchItem struct {
+	Index int
+	Toy   Toy
+}
+
+// BatchResult reports what happened to one batch item. Storage fills in Err
+// for failed items and the service layer turns it into Status and Error.
+// Invalid items list their validation errors per field.
+type BatchResult struct {
+	Index   int               `json:"index"`
+	Status  string            `json:"status"`
+	ID      int64             `json:"id,omitempty"`
+	Version int32             `json:"version,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+	Err     error             `json:"-"`
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+var CategorySlugRX = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Category is a node of the toy taxonomy. Toys reference categories by slug,
+// which never changes once created; Names holds the display name per locale
+// ("ru", "en", ...).
+type Category struct {
+	ID        int64             `json:"id"`
+	Slug      string            `json:"slug"`
+	Parent    string            `json:"parent,omitempty"`
+	Names     map[string]string `json:"names"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// CategoryUpdate is a partial change of a category. A non-nil empty Parent
+// moves the category to the top level.
+type CategoryUpdate struct {
+	Parent *string
+	Names  map[string]string
+}
+
+// Taxonomy is a snapshot of all categories for resolving and expanding
+// category references without going back to the database.
+type Taxonomy struct {
+	bySlug   map[string]*Category
+	byName   map[string]string
+	children map[string][]string
+}
+
+func NewTaxonomy(categories []*Category) *Taxonomy {
+	t := &Taxonomy{
+		bySlug:   make(map[string]*Category, len(categories)),
+		byName:   make(map[string]string),
+		children: make(map[string][]string),
+	}
+	for _, c := range categories {
+		t.bySlug[c.Slug] = c
+		for _, name := range c.Names {
+			t.byName[strings.ToLower(strings.TrimSpace(name))] = c.Slug
+		}
+		if c.Parent != "" {
+			t.children[c.Parent] = append(t.children[c.Parent], c.Slug)
+		}
+	}
+	return t
+}
+
+// Resolve maps a category reference to its slug. It accepts the slug itself
+// or any localized name, ignoring case.
+func (t *Taxonomy) Resolve(ref string) (string, bool) {
+	if _, ok := t.bySlug[ref]; ok {
+		return ref, true
+	}
+	slug, ok := t.byName[strings.ToLower(strings.TrimSpace(ref))]
+	return slug, ok
+}
+
+// Subtree returns the slug and the slugs of all its descendants.
+func (t *Taxonomy) Subtree(slug string) []string {
+	subtree := []string{slug}
+	for i := 0; i < len(subtree); i++ {
+		subtree = append(subtree, t.children[subtree[i]]...)
+	}
+	return subtree
+}
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'ә': "a", 'ғ': "g", 'қ': "k", 'ң': "n", 'ө': "o", 'ұ': "u", 'ү': "u",
+	'һ': "h", 'і': "i",
+}
+
+// Slugify turns a category name into a slug candidate, transliterating
+// Cyrillic letters and collapsing everything else into single dashes.
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		var part string
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			part = string(r)
+		default:
+			part = cyrillicToLatin[r]
+		}
+		if part == "" {
+			if _, ok := cyrillicToLatin[r]; !ok {
+				dash = b.Len() > 0
+			}
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a keyset-paginated listing: the sort it was
+// issued for, the sort key and id of the boundary row, and whether it points
+// at the page after that row or the page before it. Fuzzy cursors continue a
+// listing that fell back to approximate search matches.
+type Cursor struct {
+	Sort     string          `json:"s"`
+	Key      json.RawMessage `json:"k"`
+	ID       int64           `json:"i"`
+	Backward bool            `json:"b,omitempty"`
+	Fuzzy    bool            `json:"f,omitempty"`
+}
+
+// Encode returns the opaque token for the cursor, with key as the sort key of
+// the boundary row.
+func (c Cursor) Encode(key any) (string, error) {
+	var err error
+	if c.Key, err = json.Marshal(key); err != nil {
+		return "", err
+	}
+	js, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(js), nil
+}
+
+// DecodeCursor parses an opaque cursor token. Tokens are not signed: a
+// tampered token can only move the caller to another position of a listing
+// they can already read.
+func DecodeCursor(token string) (Cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err = json.Unmarshal(js, &c); err != nil || c.Sort == "" || len(c.Key) == 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// KeyInto decodes the cursor's sort key into dst.
+func (c Cursor) KeyInto(dst any) error {
+	if err := json.Unmarshal(c.Key, dst); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
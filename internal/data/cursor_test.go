@@ -0,0 +1,52 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{Sort: "-title", ID: 42, Backward: true, Fuzzy: true}
+	token, err := c.Encode("Ёлка")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DecodeCursor(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Sort != c.Sort || got.ID != c.ID || got.Backward != c.Backward || got.Fuzzy != c.Fuzzy {
+		t.Errorf("got %+v, want %+v", got, c)
+	}
+	var key string
+	if err = got.KeyInto(&key); err != nil {
+		t.Fatal(err)
+	}
+	if key != "Ёлка" {
+		t.Errorf("key: got %q, want %q", key, "Ёлка")
+	}
+
+	var number int64
+	if err = got.KeyInto(&number); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("key into int64: got %v, want %v", err, ErrInvalidCursor)
+	}
+}
+
+func TestDecodeCursorRejectsBadTokens(t *testing.T) {
+	encode := func(js string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(js))
+	}
+	for _, token := range []string{
+		"",
+		"not base64!",
+		encode("not json"),
+		encode(`{"k":1,"i":2}`),
+		encode(`{"s":"title","i":2}`),
+	} {
+		if _, err := DecodeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q): got %v, want %v", token, err, ErrInvalidCursor)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package data
+
+import "errors"
+
+var (
+	ErrRecordNotFound        = errors.New("record not found")
+	ErrEditConflict          = errors.New("edit conflict")
+	ErrReservationConflict   = errors.New("no copies available for the requested dates")
+	ErrDuplicateSlug         = errors.New("a category with this slug already exists")
+	ErrCategoryInUse         = errors.New("category still has subcategories or toys")
+	ErrCategoryCycle         = errors.New("a category cannot be nested under itself or its descendants")
+	ErrNoCategoryNames       = errors.New("a category must keep at least one name")
+	ErrDuplicateSkill        = errors.New("another skill already uses this name or synonym")
+	ErrSkillInUse            = errors.New("skill is still used by toys")
+	ErrDuplicateManufacturer = errors.New("a manufacturer with this name already exists")
+	ErrManufacturerInUse     = errors.New("manufacturer still has toys")
+	ErrImageAttached         = errors.New("image is attached to another toy")
+	ErrDeletedSKU            = errors.New("sku belongs to a deleted toy")
+	ErrToyNotDeleted         = errors.New("toy is not deleted")
+	ErrDuplicateSerial       = errors.New("a unit with this serial already exists")
+	ErrUnitRetired           = errors.New("unit is retired")
+	ErrBatchAborted          = errors.New("not written because another item of the batch failed")
+)
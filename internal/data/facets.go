@@ -0,0 +1,60 @@
+package data
+
+const (
+	FacetCategory     = "category"
+	FacetSkill        = "skill"
+	FacetManufacturer = "manufacturer"
+	FacetAge          = "age"
+	FacetValue        = "value"
+)
+
+// FacetLimit caps how many values the category, skill and manufacturer facets
+// list, most common first.
+const FacetLimit = 50
+
+// FacetCount is one entry of a facet. Manufacturers carry the ID to filter by,
+// age bands and value buckets the bounds (months and tenge, inclusive).
+type FacetCount struct {
+	Value string `json:"value"`
+	ID    int64  `json:"id,omitempty"`
+	Count int32  `json:"count"`
+	From  int64  `json:"from,omitempty"`
+	To    int64  `json:"to,omitempty"`
+}
+
+// Facets counts the toys matching a ListToy query per filter value. Each
+// facet ignores its own filter, so picking a second category still shows how
+// many toys every other category would add.
+type Facets struct {
+	Categories    []FacetCount `json:"categories"`
+	Skills        []FacetCount `json:"skills"`
+	Manufacturers []FacetCount `json:"manufacturers"`
+	AgeBands      []FacetCount `json:"ageBands"`
+	ValueBuckets  []FacetCount `json:"valueBuckets"`
+}
+
+// FacetRange is a named, inclusive range of an age band or value bucket.
+type FacetRange struct {
+	Name     string
+	From, To int64
+}
+
+// AgeBands are the age facet's ranges in months. A toy counts towards every
+// band its own age range overlaps.
+var AgeBands = []FacetRange{
+	{"0-1", 0, 11},
+	{"1-3", 12, 35},
+	{"3-6", 36, 71},
+	{"6-9", 72, 107},
+	{"9-12", 108, 143},
+	{"12+", 144, MaxAgeMonths},
+}
+
+// ValueBuckets are the value facet's ranges in tenge.
+var ValueBuckets = []FacetRange{
+	{"2000-4999", 2000, 4999},
+	{"5000-9999", 5000, 9999},
+	{"10000-19999", 10000, 19999},
+	{"20000-49999", 20000, 49999},
+	{"50000+", 50000, 150000},
+}
@@ -11,14 +11,25 @@ type Filters struct {
 	PageSize     int32
 	Sort         string
 	SortSafelist []string
+	// Cursor switches a listing that supports it from page numbers to keyset
+	// pagination. Page is ignored while it is set.
+	Cursor string
 }
 
+// Metadata describes a page of results. Keyset pages leave the page numbers
+// and TotalRecords empty, since counting every match is what makes deep
+// offset pages slow.
 type Metadata struct {
 	CurrentPage  int32
 	PageSize     int32
 	FirstPage    int32
 	LastPage     int32
 	TotalRecords int32
+	NextCursor   string
+	PrevCursor   string
+	// Fuzzy is set when a search matched nothing exactly and the results are
+	// approximate matches instead.
+	Fuzzy bool
 }
 
 func (f Filters) CalculateMetadata(totalRecords int, page, pageSize int32) Metadata {
@@ -50,6 +61,12 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize <= 100, "page_size", "maximum is 100")
 
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort type")
+
+	if f.Cursor != "" {
+		c, err := DecodeCursor(f.Cursor)
+		v.Check(err == nil, "cursor", "invalid cursor")
+		v.Check(err != nil || c.Sort == f.Sort, "cursor", "cursor was issued for a different sort")
+	}
 }
 
 func (f Filters) SortColumn() string {
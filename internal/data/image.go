@@ -0,0 +1,26 @@
+package data
+
+import "time"
+
+// MaxToyImages caps how many images one toy can have.
+const MaxToyImages = 10
+
+// ToyImage is an uploaded image. ToyID is zero until the image is attached to
+// a toy; Position orders a toy's images and the Primary one is what toy
+// summaries show.
+type ToyImage struct {
+	ID           int64     `json:"id"`
+	ToyID        int64     `json:"toyId,omitempty"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailUrl"`
+	ContentType  string    `json:"contentType"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	Size         int64     `json:"size"`
+	Alt          string    `json:"alt"`
+	Position     int32     `json:"position"`
+	Primary      bool      `json:"primary"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
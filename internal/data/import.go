@@ -0,0 +1,27 @@
+package data
+
+// Import actions tell what happened, or in a dry run what would happen, to
+// one imported row.
+const (
+	ImportActionCreate    = "create"
+	ImportActionChange    = "change"
+	ImportActionUnchanged = "unchanged"
+	ImportActionError     = "error"
+)
+
+// ImportRow is a validated toy read from line Line of an import file.
+type ImportRow struct {
+	Line int
+	Toy  Toy
+}
+
+// ImportResult reports the outcome of one import row. Changes lists the
+// fields a change touches; Error is set for rows that were not written.
+type ImportResult struct {
+	Line    int           `json:"line"`
+	SKU     string        `json:"sku"`
+	Action  string        `json:"action"`
+	ToyID   int64         `json:"toyId,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
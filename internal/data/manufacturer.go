@@ -0,0 +1,61 @@
+package data
+
+import (
+	"strings"
+	"time"
+)
+
+// TopToysLimit is how many of a brand's most rented toys its page shows.
+const TopToysLimit = 10
+
+type Manufacturer struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Country     string    `json:"country,omitempty"`
+	LogoURL     string    `json:"logoUrl,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Toys        int32     `json:"toys"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ManufacturerUpdate is a partial change of a manufacturer; nil fields are
+// left as they are.
+type ManufacturerUpdate struct {
+	Name        *string
+	Country     *string
+	LogoURL     *string
+	Description *string
+}
+
+// BrandPage is a manufacturer together with its most rented live toys.
+type BrandPage struct {
+	Manufacturer Manufacturer `json:"manufacturer"`
+	TopToys      []*Toy       `json:"topToys"`
+}
+
+// ManufacturerDirectory is a snapshot of the manufacturers table for
+// resolving the manufacturer names of toys being written.
+type ManufacturerDirectory struct {
+	byKey map[string]*Manufacturer
+}
+
+func NewManufacturerDirectory(manufacturers []*Manufacturer) *ManufacturerDirectory {
+	d := &ManufacturerDirectory{byKey: make(map[string]*Manufacturer, len(manufacturers))}
+	for _, m := range manufacturers {
+		d.byKey[ManufacturerKey(m.Name)] = m
+	}
+	return d
+}
+
+// Resolve finds the manufacturer a name refers to, ignoring case and extra
+// whitespace.
+func (d *ManufacturerDirectory) Resolve(name string) (*Manufacturer, bool) {
+	m, ok := d.byKey[ManufacturerKey(name)]
+	return m, ok
+}
+
+// ManufacturerKey mirrors the manufacturer_key SQL function that manufacturer
+// names are unique by.
+func ManufacturerKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,40 @@
+package data
+
+const (
+	PermissionToysRead          = "toys:read"
+	PermissionToysWrite         = "toys:write"
+	PermissionReservationsWrite = "reservations:write"
+)
+
+const (
+	RoleAdmin      = "admin"
+	RoleSubscriber = "subscriber"
+)
+
+type Permissions []string
+
+var rolePermissions = map[string]Permissions{
+	RoleAdmin:      {PermissionToysRead, PermissionToysWrite, PermissionReservationsWrite},
+	RoleSubscriber: {PermissionToysRead, PermissionReservationsWrite},
+}
+
+func (p Permissions) Include(code string) bool {
+	for i := range p {
+		if code == p[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func PermissionsForRoles(roles []string) Permissions {
+	permissions := Permissions{}
+	for _, role := range roles {
+		for _, code := range rolePermissions[role] {
+			if !permissions.Include(code) {
+				permissions = append(permissions, code)
+			}
+		}
+	}
+	return permissions
+}
@@ -0,0 +1,19 @@
+package data
+
+import "time"
+
+const (
+	ReservationStatusActive    = "active"
+	ReservationStatusCancelled = "cancelled"
+)
+
+type Reservation struct {
+	ID        int64
+	ToyID     int64
+	UnitID    int64
+	UserID    int64
+	StartsAt  time.Time
+	EndsAt    time.Time
+	Status    string
+	CreatedAt time.Time
+}
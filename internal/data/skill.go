@@ -0,0 +1,51 @@
+package data
+
+import (
+	"strings"
+	"time"
+)
+
+// Skill is an entry of the skills dictionary. Toys store the canonical Name;
+// Synonyms are other spellings and translations that resolve to it.
+type Skill struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Synonyms  []string  `json:"synonyms"`
+	Toys      int32     `json:"toys"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SkillUpdate is a partial change of a skill. Renaming a skill renames it on
+// every toy; a non-nil Synonyms replaces the whole list.
+type SkillUpdate struct {
+	Name     *string
+	Synonyms []string
+}
+
+// SkillDictionary is a snapshot of the skills dictionary for normalizing the
+// skills of toys being written.
+type SkillDictionary struct {
+	byTerm map[string]string
+}
+
+func NewSkillDictionary(skills []*Skill) *SkillDictionary {
+	d := &SkillDictionary{byTerm: make(map[string]string)}
+	for _, s := range skills {
+		d.byTerm[skillTerm(s.Name)] = s.Name
+		for _, synonym := range s.Synonyms {
+			d.byTerm[skillTerm(synonym)] = s.Name
+		}
+	}
+	return d
+}
+
+// Resolve maps a skill name or synonym to its canonical name, ignoring case
+// and surrounding spaces.
+func (d *SkillDictionary) Resolve(term string) (string, bool) {
+	name, ok := d.byTerm[skillTerm(term)]
+	return name, ok
+}
+
+func skillTerm(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
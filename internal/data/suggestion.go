@@ -0,0 +1,20 @@
+package data
+
+const (
+	SuggestionTitle        = "title"
+	SuggestionCategory     = "category"
+	SuggestionManufacturer = "manufacturer"
+)
+
+// SuggestionLimit caps how many autocomplete suggestions one request returns.
+const SuggestionLimit = 20
+
+// Suggestion is one autocomplete entry. Category suggestions carry the slug
+// and manufacturer suggestions the ID to filter by.
+type Suggestion struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+	Slug string `json:"slug,omitempty"`
+	ID   int64  `json:"id,omitempty"`
+	Toys int32  `json:"toys"`
+}
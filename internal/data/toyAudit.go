@@ -0,0 +1,61 @@
+package data
+
+import "slices"
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionChange  = "change"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+type ToyAuditEntry struct {
+	ID        int64
+	ToyID     int64
+	UserID    int64
+	Action    string
+	Changes   []FieldChange
+	ChangedAt string
+}
+
+// DiffToys lists the editable fields that differ between two versions of a
+// toy. Diffing against an empty Toy yields every populated field, which is
+// what a creation entry records.
+func DiffToys(before, after Toy) []FieldChange {
+	changes := []FieldChange{}
+	add := func(field string, old, new any) {
+		changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+	}
+
+	if before.Title != after.Title {
+		add("title", before.Title, after.Title)
+	}
+	if before.Desc != after.Desc {
+		add("desc", before.Desc, after.Desc)
+	}
+	if before.Value != after.Value {
+		add("value", before.Value, after.Value)
+	}
+	if !slices.Equal(before.Images, after.Images) {
+		add("images", before.Images, after.Images)
+	}
+	if !slices.Equal(before.Skills, after.Skills) {
+		add("skills", before.Skills, after.Skills)
+	}
+	if !slices.Equal(before.Categories, after.Categories) {
+		add("categories", before.Categories, after.Categories)
+	}
+	if before.RecAge != after.RecAge {
+		add("recommendedAge", before.RecAge, after.RecAge)
+	}
+	if before.Manufacturer != after.Manufacturer {
+		add("manufacturer", before.Manufacturer, after.Manufacturer)
+	}
+	return changes
+}
@@ -0,0 +1,31 @@
+package data
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventToyCreated             = "toy.created"
+	EventToyChanged             = "toy.changed"
+	EventToyDeleted             = "toy.deleted"
+	EventToyRestored            = "toy.restored"
+	EventToyAvailabilityChanged = "toy.availability_changed"
+)
+
+type ToyEvent struct {
+	ID        int64           `json:"id"`
+	ToyID     int64           `json:"toyId"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Attempts  int32           `json:"attempts"`
+}
+
+type ToyEventPayload struct {
+	ToyID          int64         `json:"toyId"`
+	Toy            *Toy          `json:"toy,omitempty"`
+	Changes        []FieldChange `json:"changes,omitempty"`
+	AvailableCount *int32        `json:"availableCount,omitempty"`
+	IsAvailable    *bool         `json:"isAvailable,omitempty"`
+}
@@ -0,0 +1,50 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+	"toysService/internal/validator"
+)
+
+// ToySortSafelist lists the sorts ListToy accepts. "relevance" puts the best
+// full-text matches first and needs a search query.
+var ToySortSafelist = []string{
+	"id", "title", "skills", "categories", "recAge", "age", "value", "from", "to", "relevance",
+	"-id", "-title", "-skills", "-categories", "-recAge", "-age", "-value", "-relevance",
+}
+
+// ToyQuery holds the ListToy filters that narrow down the catalogue. Zero
+// values mean "no filter", except From/To which always bound the value.
+type ToyQuery struct {
+	Title      string
+	Categories []string
+	Skills     []string
+	// Manufacturers keeps toys of any of these manufacturer IDs.
+	Manufacturers []int64
+	From          int64
+	To            int64
+	// AgeMonths keeps only toys suitable for a child of this age.
+	AgeMonths *int32
+}
+
+func ValidateToyQuery(v *validator.Validator, q ToyQuery, f Filters) {
+	v.Check(len(q.Title) <= 500, "title", "search query must not be more than 500 bytes long")
+	v.Check(len(q.Manufacturers) <= 50, "manufacturers", "no more than 50 manufacturers")
+	for _, id := range q.Manufacturers {
+		v.Check(id > 0, "manufacturers", "manufacturer ids must be positive")
+	}
+	v.Check(strings.TrimPrefix(f.Sort, "-") != "relevance" || strings.TrimSpace(q.Title) != "", "sort", "sorting by relevance needs a search query")
+}
+
+// ParseIDs parses a filter list of IDs such as the manufacturers of a
+// ToyQuery. ok is false if any item is not a positive integer.
+func ParseIDs(items []string) (ids []int64, ok bool) {
+	for _, item := range items {
+		id, err := strconv.ParseInt(strings.TrimSpace(item), 10, 64)
+		if err != nil || id < 1 {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
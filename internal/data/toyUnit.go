@@ -0,0 +1,25 @@
+package data
+
+const (
+	UnitStatusAvailable   = "available"
+	UnitStatusRented      = "rented"
+	UnitStatusReserved    = "reserved"
+	UnitStatusMaintenance = "maintenance"
+	UnitStatusRetired     = "retired"
+)
+
+// UnitStatuses lists every status a unit can be in; retired is final.
+var UnitStatuses = []string{UnitStatusAvailable, UnitStatusRented, UnitStatusReserved, UnitStatusMaintenance, UnitStatusRetired}
+
+var UnitConditions = []string{"new", "good", "fair", "poor"}
+
+type ToyUnit struct {
+	ID        int64  `json:"id"`
+	ToyID     int64  `json:"toyId"`
+	Serial    string `json:"serial"`
+	Condition string `json:"condition"`
+	Location  string `json:"location"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"createdAt"`
+	RetiredAt string `json:"retiredAt,omitempty"`
+}
@@ -10,5 +10,4 @@ type UpdatedToy struct {
 	Categories   []string
 	RecAge       *string
 	Manufacturer *string
-	IsAvailable  *bool
 }
@@ -1,15 +1,25 @@
 package data
 
 type Toy struct {
-	ID           int64
-	Title        string
-	Desc         string
-	Value        int64
-	Images       []string
-	Skills       []string
-	Categories   []string
-	RecAge       string
-	Manufacturer string
-	IsAvailable  bool
-	CreatedAt    string
+	ID             int64    `json:"id"`
+	SKU            string   `json:"sku,omitempty"`
+	Title          string   `json:"title"`
+	Desc           string   `json:"desc"`
+	Value          int64    `json:"value"`
+	Images         []string `json:"images"`
+	Skills         []string `json:"skills"`
+	Categories     []string `json:"categories"`
+	RecAge         string   `json:"recommendedAge"`
+	MinAgeMonths   int32    `json:"minAgeMonths"`
+	MaxAgeMonths   int32    `json:"maxAgeMonths,omitempty"`
+	Manufacturer   string   `json:"manufacturer"`
+	ManufacturerID int64    `json:"manufacturerId,omitempty"`
+	IsAvailable    bool     `json:"isAvailable"`
+	AvailableCount int32    `json:"availableCount"`
+	CreatedAt      string   `json:"createdAt,omitempty"`
+	DeletedAt      string   `json:"deletedAt,omitempty"`
+	Version        int32    `json:"version"`
+	// Rank and Snippet are only filled in by full-text searches.
+	Rank    float32 `json:"rank,omitempty"`
+	Snippet string  `json:"snippet,omitempty"`
 }
@@ -0,0 +1,9 @@
+package data
+
+// Vocabulary holds the dictionaries the categories, skills and manufacturer
+// of a toy are checked against and normalized with.
+type Vocabulary struct {
+	Categories    *Taxonomy
+	Skills        *SkillDictionary
+	Manufacturers *ManufacturerDirectory
+}
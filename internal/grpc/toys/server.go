@@ -6,8 +6,11 @@ import (
 	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"strconv"
 	"strings"
+	"toysService/internal/contextkeys"
 	"toysService/internal/data"
 	"toysService/internal/jsonlog"
 	"toysService/internal/validator"
@@ -20,14 +23,46 @@ type serverAPI struct {
 	log  *jsonlog.Logger
 }
 
+// VersionHeader carries a toy's version: GetToy and ChangeToy send the current
+// one back, and ChangeToy accepts the version the caller last read so a
+// concurrent edit is rejected instead of silently overwritten.
+const VersionHeader = "x-toy-version"
+
+// AgeMonthsHeader narrows ListToy down to toys suitable for a child of the
+// given age in months. REST clients pass it as the age_months query parameter.
+const AgeMonthsHeader = "x-child-age-months"
+
+// ManufacturersHeader narrows ListToy down to toys of the given manufacturer
+// IDs (comma separated). REST clients pass it as ?manufacturers=.
+const ManufacturersHeader = "x-manufacturer-ids"
+
+// CursorHeader switches ListToy to keyset pagination starting at the given
+// cursor; NextCursorHeader and PrevCursorHeader carry the cursors of the
+// neighbouring pages back. REST clients pass the cursor as ?cursor=.
+const (
+	CursorHeader     = "x-page-cursor"
+	NextCursorHeader = "x-next-cursor"
+	PrevCursorHeader = "x-prev-cursor"
+)
+
+// FuzzyHeader is set on ListToy responses whose search query matched nothing
+// exactly and that list approximate (typo-tolerant) matches instead.
+const FuzzyHeader = "x-search-fuzzy"
+
+// ReadPrimaryHeader set to true makes GetToy, ListToy and GetToysByIds read
+// from the primary database instead of a replica, so a client sees the
+// writes it has just made.
+const ReadPrimaryHeader = "x-read-primary"
+
 type Toys interface {
 	CreateToy(ctx context.Context, toy data.Toy) (toys.Status, string, data.Toy)
 	DeleteToy(ctx context.Context, toyID int64) (toys.Status, string)
-	ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string)
+	ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string, error)
 	GetToy(ctx context.Context, toyID int64) (data.Toy, toys.Status, string)
-	ListToy(ctx context.Context, to int64, from int64, filters data.Filters, categories []string, skills []string, title string) ([]*data.Toy, toys.Status, string, data.Metadata)
-	ListRecommended(ctx context.Context) ([]*data.Toy, toys.Status, string, data.Metadata)
+	ListToy(ctx context.Context, query data.ToyQuery, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata)
+	ListRecommended(ctx context.Context, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata)
 	GetToysByIds(ctx context.Context, ids []int64) ([]*data.ToySummary, string)
+	Vocabulary(ctx context.Context) (data.Vocabulary, error)
 }
 
 func Register(gRPC *grpc.Server, toy Toys, log *jsonlog.Logger) {
@@ -46,7 +81,7 @@ func (s *serverAPI) GetToysByIds(ctx context.Context, r *toys.GetToysByIdsReques
 		return nil, status.Error(codes.NotFound, "toy ids not provided")
 	}
 
-	toysList, msg := s.toys.GetToysByIds(ctx, toyIds)
+	toysList, msg := s.toys.GetToysByIds(readPrimary(ctx), toyIds)
 
 	if len(toysList) < 1 {
 		return nil, status.Error(codes.NotFound, "failed to fetch toys!")
@@ -71,7 +106,6 @@ func (s *serverAPI) CreateToy(ctx context.Context, r *toys.CreateToyRequest) (*t
 	toyCategories := r.GetCategories()
 	toyRecAge := r.GetRecommendedAge()
 	toyManufacturer := r.GetManufacturer()
-	isToyAvailable := r.GetIsAvailable()
 
 	inputToy := data.Toy{
 		Title:        toyTitle,
@@ -82,10 +116,13 @@ func (s *serverAPI) CreateToy(ctx context.Context, r *toys.CreateToyRequest) (*t
 		Categories:   toyCategories,
 		RecAge:       toyRecAge,
 		Manufacturer: toyManufacturer,
-		IsAvailable:  isToyAvailable,
 	}
 
-	if postgres.ValidateToy(v, &inputToy); !v.Valid() {
+	vocabulary, err := s.toys.Vocabulary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if postgres.NormalizeToy(v, &inputToy, vocabulary); !v.Valid() {
 		return nil, collectErrors(v)
 	}
 
@@ -130,11 +167,22 @@ func (s *serverAPI) ChangeToy(ctx context.Context, r *toys.ChangeToyRequest) (*t
 	v := validator.New()
 	toyProto := r.GetToy()
 
+	// The edit is applied on top of what is read here, which a lagging
+	// replica may not have caught up with yet.
+	ctx = context.WithValue(ctx, contextkeys.ReadPrimaryKey, true)
 	existingToy, opStatus, msg := s.toys.GetToy(ctx, toyProto.Id)
 	if opStatus != toys.Status_STATUS_OK {
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
+	if value, ok := metadataValue(ctx, VersionHeader); ok {
+		version, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || version < 1 {
+			return nil, status.Error(codes.InvalidArgument, "invalid toy version")
+		}
+		existingToy.Version = int32(version)
+	}
+
 	if toyProto.Title != nil {
 		existingToy.Title = *toyProto.Title
 	}
@@ -157,21 +205,26 @@ func (s *serverAPI) ChangeToy(ctx context.Context, r *toys.ChangeToyRequest) (*t
 		existingToy.Manufacturer = *toyProto.Manufacturer
 	}
 	if toyProto.RecommendedAge != nil {
+		// The range in months is parsed again from the new text.
 		existingToy.RecAge = *toyProto.RecommendedAge
-	}
-	if toyProto.IsAvailable != nil {
-		existingToy.IsAvailable = *toyProto.IsAvailable
+		existingToy.MinAgeMonths, existingToy.MaxAgeMonths = 0, 0
 	}
 
-	if postgres.ValidateToy(v, &existingToy); !v.Valid() {
+	vocabulary, err := s.toys.Vocabulary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if postgres.NormalizeToy(v, &existingToy, vocabulary); !v.Valid() {
 		return nil, collectErrors(v)
 	}
 
-	opStatus, msg = s.toys.ChangeToy(ctx, existingToy)
-	if opStatus != toys.Status_STATUS_OK {
-		return nil, status.Error(codes.Internal, "internal error!")
+	opStatus, msg, err = s.toys.ChangeToy(ctx, existingToy)
+	if err != nil {
+		return nil, err
 	}
 
+	grpc.SetHeader(ctx, metadata.Pairs(VersionHeader, strconv.Itoa(int(existingToy.Version+1))))
+
 	return &toys.ChangeToyResponse{
 		Status:   opStatus,
 		ErrorMsg: msg,
@@ -187,7 +240,10 @@ func (s *serverAPI) GetToy(ctx context.Context, r *toys.GetToyRequest) (*toys.Ge
 		return nil, status.Error(codes.InvalidArgument, "invalid ToyId")
 	}
 
-	toy, opStatus, msg := s.toys.GetToy(ctx, toyId)
+	toy, opStatus, msg := s.toys.GetToy(readPrimary(ctx), toyId)
+	if opStatus == toys.Status_STATUS_OK {
+		grpc.SetHeader(ctx, metadata.Pairs(VersionHeader, strconv.Itoa(int(toy.Version))))
+	}
 
 	return &toys.GetToyResponse{
 		Toy:    mapDataToGRPCToy(toy),
@@ -200,18 +256,32 @@ func (s *serverAPI) ListToy(ctx context.Context, r *toys.ListToyRequest) (*toys.
 	//s.log.PrintInfo("server part", map[string]string{
 	//	"method": "server.ListToy",
 	//})
-	from := r.GetFrom()
-	categories := r.GetCategories()
-	skills := r.GetSkills()
-	to := r.GetTo()
-	title := r.GetTitle()
+	query := data.ToyQuery{
+		Title:      r.GetTitle(),
+		Categories: r.GetCategories(),
+		Skills:     r.GetSkills(),
+		From:       r.GetFrom(),
+		To:         r.GetTo(),
+	}
 	v := validator.New()
 
+	if value, ok := metadataValue(ctx, AgeMonthsHeader); ok {
+		age, err := strconv.ParseInt(value, 10, 32)
+		v.Check(err == nil && age >= 0 && age <= data.MaxAgeMonths, "age_months", "must be a number of months between 0 and 216")
+		ageMonths := int32(age)
+		query.AgeMonths = &ageMonths
+	}
+	if value, ok := metadataValue(ctx, ManufacturersHeader); ok {
+		ids, ok := data.ParseIDs(strings.Split(value, ","))
+		v.Check(ok, "manufacturers", "must be a comma separated list of manufacturer ids")
+		query.Manufacturers = ids
+	}
+
 	filters := &data.Filters{
 		Page:         r.GetPage(),
 		PageSize:     r.GetPageSize(),
 		Sort:         r.GetSort(),
-		SortSafelist: []string{"id", "title", "skills", "categories", "recAge", "value", "from", "to", "-id", "-title", "-skills", "-categories", "-recAge", "-value"},
+		SortSafelist: data.ToySortSafelist,
 	}
 	if filters.Page <= 0 {
 		filters.Page = 1
@@ -222,6 +292,8 @@ func (s *serverAPI) ListToy(ctx context.Context, r *toys.ListToyRequest) (*toys.
 	if filters.Sort == "" {
 		filters.Sort = "id"
 	}
+	filters.Cursor, _ = metadataValue(ctx, CursorHeader)
+	data.ValidateToyQuery(v, query, *filters)
 	//if from == 0 {
 	//	return nil, status.Error(codes.InvalidArgument, "Invalid filter(from)")
 	//}
@@ -233,7 +305,8 @@ func (s *serverAPI) ListToy(ctx context.Context, r *toys.ListToyRequest) (*toys.
 		return nil, collectErrors(v)
 	}
 
-	toyList, opStatus, msg, metadata := s.toys.ListToy(ctx, to, from, *filters, categories, skills, title)
+	toyList, opStatus, msg, metadata := s.toys.ListToy(readPrimary(ctx), query, *filters)
+	setPageHeaders(ctx, metadata)
 
 	return &toys.ListToyResponse{
 		Toys:     mapDataListToGrpc(toyList),
@@ -248,7 +321,14 @@ func (s *serverAPI) ListRecommended(ctx context.Context, r *toys.ListRecommended
 	//s.log.PrintInfo("server part", map[string]string{
 	//	"method": "server.ListRec",
 	//})
-	toyList, opStatus, msg, metadata := s.toys.ListRecommended(ctx)
+	filters := data.Filters{
+		Page:         1,
+		PageSize:     20,
+		Sort:         "id",
+		SortSafelist: []string{"id"},
+	}
+
+	toyList, opStatus, msg, metadata := s.toys.ListRecommended(ctx, filters)
 
 	return &toys.ListRecommendedResponse{
 		Toys:     mapDataListToGrpc(toyList),
@@ -258,6 +338,41 @@ func (s *serverAPI) ListRecommended(ctx context.Context, r *toys.ListRecommended
 	}, nil
 }
 
+func setPageHeaders(ctx context.Context, page data.Metadata) {
+	md := metadata.MD{}
+	if page.NextCursor != "" {
+		md.Set(NextCursorHeader, page.NextCursor)
+	}
+	if page.PrevCursor != "" {
+		md.Set(PrevCursorHeader, page.PrevCursor)
+	}
+	if page.Fuzzy {
+		md.Set(FuzzyHeader, "true")
+	}
+	if md.Len() > 0 {
+		grpc.SetHeader(ctx, md)
+	}
+}
+
+// metadataValue returns the first value of an incoming metadata key.
+func metadataValue(ctx context.Context, key string) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(key)) == 0 {
+		return "", false
+	}
+	return md.Get(key)[0], true
+}
+
+// readPrimary marks ctx for reading from the primary database if the caller
+// asked for it with ReadPrimaryHeader.
+func readPrimary(ctx context.Context) context.Context {
+	value, _ := metadataValue(ctx, ReadPrimaryHeader)
+	if primary, _ := strconv.ParseBool(value); primary {
+		return context.WithValue(ctx, contextkeys.ReadPrimaryKey, true)
+	}
+	return ctx
+}
+
 func collectErrors(v *validator.Validator) error {
 	var b strings.Builder
 	for field, msg := range v.Errors {
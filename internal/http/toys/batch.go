@@ -0,0 +1,191 @@
+package toys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"toysService/internal/contextkeys"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+	"toysService/storage/postgres"
+)
+
+// batchToyInput is one toy of a batch. A create takes the toy as given; an
+// update changes only the fields that are present, like a gRPC
+// ChangeToyRequest, and keeps the others.
+type batchToyInput struct {
+	ID           int64    `json:"id"`
+	Version      int32    `json:"version"`
+	Title        *string  `json:"title"`
+	Desc         *string  `json:"desc"`
+	Value        *int64   `json:"value"`
+	Images       []string `json:"images"`
+	Skills       []string `json:"skills"`
+	Categories   []string `json:"categories"`
+	RecAge       *string  `json:"recommendedAge"`
+	MinAgeMonths *int32   `json:"minAgeMonths"`
+	MaxAgeMonths *int32   `json:"maxAgeMonths"`
+	Manufacturer *string  `json:"manufacturer"`
+}
+
+// apply copies the fields present in the input onto toy.
+func (in batchToyInput) apply(toy *data.Toy) {
+	if in.Title != nil {
+		toy.Title = *in.Title
+	}
+	if in.Desc != nil {
+		toy.Desc = *in.Desc
+	}
+	if in.Value != nil {
+		toy.Value = *in.Value
+	}
+	if in.Images != nil {
+		toy.Images = in.Images
+	}
+	if in.Skills != nil {
+		toy.Skills = in.Skills
+	}
+	if in.Categories != nil {
+		toy.Categories = in.Categories
+	}
+	if in.RecAge != nil {
+		// Without a range of its own the new text sets the range in months.
+		toy.RecAge = *in.RecAge
+		toy.MinAgeMonths, toy.MaxAgeMonths = 0, 0
+	}
+	if in.MinAgeMonths != nil {
+		toy.MinAgeMonths = *in.MinAgeMonths
+	}
+	if in.MaxAgeMonths != nil {
+		toy.MaxAgeMonths = *in.MaxAgeMonths
+	}
+	if in.Manufacturer != nil {
+		toy.Manufacturer = *in.Manufacturer
+	}
+}
+
+// createToys creates up to data.MaxBatchSize toys in one request.
+func (h *handler) createToys(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	h.writeBatch(w, r, false)
+}
+
+// updateToys changes up to data.MaxBatchSize toys in one request. Every toy
+// carries its id and the version it was read at; fields it leaves out keep
+// their current values.
+func (h *handler) updateToys(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	h.writeBatch(w, r, true)
+}
+
+// writeBatch reads {"mode": ..., "toys": [...]} and answers with one result
+// per toy in request order. Every toy is validated first; in the default
+// all_or_nothing mode a single invalid or failing toy means nothing is
+// written and the answer is 422, while best_effort writes what it can.
+func (h *handler) writeBatch(w http.ResponseWriter, r *http.Request, update bool) {
+	var input struct {
+		Mode string          `json:"mode"`
+		Toys []batchToyInput `json:"toys"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+	if input.Mode == "" {
+		input.Mode = data.BatchAllOrNothing
+	}
+
+	v := validator.New()
+	v.Check(validator.PermittedValue(input.Mode, data.BatchAllOrNothing, data.BatchBestEffort), "mode", "must be all_or_nothing or best_effort")
+	v.Check(len(input.Toys) >= 1, "toys", "at least 1 toy")
+	v.Check(len(input.Toys) <= data.MaxBatchSize, "toys", fmt.Sprintf("no more than %d toys", data.MaxBatchSize))
+	if !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+	atomic := input.Mode == data.BatchAllOrNothing
+
+	vocabulary, err := h.toys.Vocabulary(r.Context())
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	// Updates are applied on top of what is read here, which a lagging
+	// replica may not have caught up with yet. The toys are read in one go;
+	// a toy that changes before the write fails its version check there.
+	ctx := context.WithValue(r.Context(), contextkeys.ReadPrimaryKey, true)
+	var current map[int64]data.Toy
+	if update {
+		ids := make([]int64, 0, len(input.Toys))
+		for _, in := range input.Toys {
+			if in.ID > 0 {
+				ids = append(ids, in.ID)
+			}
+		}
+		if current, err = h.toys.LoadToys(ctx, ids); err != nil {
+			h.errorResponse(w, err)
+			return
+		}
+	}
+
+	results := make([]data.BatchResult, len(input.Toys))
+	items := make([]data.BatchItem, 0, len(input.Toys))
+	for i, in := range input.Toys {
+		results[i].Index = i
+
+		var toy data.Toy
+		v := validator.New()
+		if update {
+			v.Check(in.ID > 0, "id", "must be a positive toy id")
+			v.Check(in.Version > 0, "version", "the version the toy was read at must be provided")
+			if !v.Valid() {
+				results[i].Status, results[i].Error, results[i].Errors = data.BatchStatusInvalid, "toy failed validation", v.Errors
+				continue
+			}
+
+			existing, ok := current[in.ID]
+			if !ok {
+				results[i].Status, results[i].Error = data.BatchStatusNotFound, "toy not found"
+				continue
+			}
+			toy = existing
+			toy.Version = in.Version
+		}
+		in.apply(&toy)
+
+		if postgres.NormalizeToy(v, &toy, vocabulary); !v.Valid() {
+			results[i].Status, results[i].Error, results[i].Errors = data.BatchStatusInvalid, "toy failed validation", v.Errors
+			continue
+		}
+		items = append(items, data.BatchItem{Index: i, Toy: toy})
+	}
+
+	failed := len(items) < len(input.Toys)
+	switch {
+	case failed && atomic:
+		for _, item := range items {
+			results[item.Index].Status, results[item.Index].Error = data.BatchStatusAborted, data.ErrBatchAborted.Error()
+		}
+	case len(items) > 0:
+		write := h.toys.CreateToys
+		if update {
+			write = h.toys.UpdateToys
+		}
+		written, err := write(ctx, items, atomic)
+		if err != nil {
+			h.errorResponse(w, err)
+			return
+		}
+		for _, result := range written {
+			results[result.Index] = result
+			if result.Err != nil {
+				failed = true
+			}
+		}
+	}
+
+	code := http.StatusOK
+	if failed && atomic {
+		code = http.StatusUnprocessableEntity
+	}
+	h.writeJSON(w, code, envelope{"mode": input.Mode, "results": results})
+}
@@ -0,0 +1,53 @@
+package toys
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"toysService/internal/data"
+)
+
+func TestBatchToyInputApply(t *testing.T) {
+	current := data.Toy{
+		ID: 3, Version: 2, Title: "Ball", Desc: "red", Value: 2500, Images: []string{"a.jpg"},
+		Skills: []string{"motor"}, Categories: []string{"outdoor"}, RecAge: "3-5 лет",
+		MinAgeMonths: 36, MaxAgeMonths: 60, Manufacturer: "Acme", ManufacturerID: 1,
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  func(toy *data.Toy)
+	}{
+		{"nothing present", `{"id": 3, "version": 2}`, func(toy *data.Toy) {}},
+		{"scalars", `{"title": "Big ball", "desc": "", "value": 3000, "manufacturer": "Zeta"}`, func(toy *data.Toy) {
+			toy.Title, toy.Desc, toy.Value, toy.Manufacturer = "Big ball", "", 3000, "Zeta"
+		}},
+		{"lists", `{"images": [], "skills": ["logic", "motor"], "categories": ["games"]}`, func(toy *data.Toy) {
+			toy.Images, toy.Skills, toy.Categories = []string{}, []string{"logic", "motor"}, []string{"games"}
+		}},
+		{"null lists keep the current ones", `{"images": null, "skills": null}`, func(toy *data.Toy) {}},
+		{"new text age clears the range", `{"recommendedAge": "1-2 года"}`, func(toy *data.Toy) {
+			toy.RecAge, toy.MinAgeMonths, toy.MaxAgeMonths = "1-2 года", 0, 0
+		}},
+		{"text age with its own range", `{"recommendedAge": "1+", "minAgeMonths": 12}`, func(toy *data.Toy) {
+			toy.RecAge, toy.MinAgeMonths, toy.MaxAgeMonths = "1+", 12, 0
+		}},
+		{"range only", `{"maxAgeMonths": 48}`, func(toy *data.Toy) {
+			toy.MaxAgeMonths = 48
+		}},
+	} {
+		var in batchToyInput
+		if err := json.Unmarshal([]byte(tc.input), &in); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		toy, want := current, current
+		tc.want(&want)
+
+		in.apply(&toy)
+		if !reflect.DeepEqual(toy, want) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, toy, want)
+		}
+	}
+}
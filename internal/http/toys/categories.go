@@ -0,0 +1,103 @@
+package toys
+
+import (
+	"net/http"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+	"toysService/storage/postgres"
+)
+
+func (h *handler) listCategories(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	categories, err := h.toys.ListCategories(r.Context())
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"categories": categories})
+}
+
+func (h *handler) getCategory(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	category, err := h.toys.GetCategory(r.Context(), pathParams["slug"])
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"category": category})
+}
+
+// createCategory derives the slug from the English (or else Russian) name
+// when none is given.
+func (h *handler) createCategory(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	var input struct {
+		Slug   string            `json:"slug"`
+		Parent string            `json:"parent"`
+		Names  map[string]string `json:"names"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	category := data.Category{Slug: input.Slug, Parent: input.Parent, Names: input.Names}
+	if category.Slug == "" {
+		name := input.Names["en"]
+		if name == "" {
+			name = input.Names["ru"]
+		}
+		category.Slug = data.Slugify(name)
+	}
+
+	v := validator.New()
+	if postgres.ValidateCategory(v, &category); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	category, err := h.toys.CreateCategory(r.Context(), category)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, envelope{"category": category})
+}
+
+// updateCategory moves a category ("parent": "" moves it to the top level)
+// and/or sets names per locale ("names": {"en": ""} removes one).
+func (h *handler) updateCategory(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	slug := pathParams["slug"]
+	var input struct {
+		Parent *string           `json:"parent"`
+		Names  map[string]string `json:"names"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Parent == nil || *input.Parent != slug, "parent", "category cannot be its own parent")
+	if postgres.ValidateCategoryNames(v, input.Names, false); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	category, err := h.toys.UpdateCategory(r.Context(), slug, data.CategoryUpdate{Parent: input.Parent, Names: input.Names})
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"category": category})
+}
+
+func (h *handler) deleteCategory(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	if err := h.toys.DeleteCategory(r.Context(), pathParams["slug"]); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"message": "category deleted"})
+}
@@ -0,0 +1,84 @@
+package toys
+
+import (
+	"net/http"
+	"strconv"
+	"toysService/internal/catalog"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+)
+
+// exportFlushRows is how often an export pushes what it has written to the
+// client.
+const exportFlushRows = 500
+
+// exportToys downloads the toys matching the search filters as ?format=csv
+// (the default), jsonl or xlsx, with the ?columns= picked (comma separated).
+// The file is written while the toys are read, so an error after the first
+// toy can only cut the download short: the connection is dropped rather than
+// the response finished, so that clients see the file as broken.
+func (h *handler) exportToys(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	qs := r.URL.Query()
+	v := validator.New()
+	query := readToyQuery(r, v)
+	data.ValidateToyQuery(v, query, data.Filters{})
+
+	format := qs.Get("format")
+	if format == "" {
+		format = catalog.FormatCSV
+	}
+	v.Check(validator.PermittedValue(format, catalog.FormatCSV, catalog.FormatJSONL, catalog.FormatXLSX), "format", "must be csv, jsonl or xlsx")
+	columns, err := catalog.ParseColumns(qs.Get("columns"))
+	if err != nil {
+		v.AddError("columns", err.Error())
+	}
+	if !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	var out catalog.Writer
+	start := func() (err error) {
+		w.Header().Set("Content-Type", catalog.ContentType(format))
+		w.Header().Set("Content-Disposition", `attachment; filename="toys.`+format+`"`)
+		w.WriteHeader(http.StatusOK)
+		out, err = catalog.NewWriter(w, format, columns)
+		return err
+	}
+
+	rows := 0
+	err = h.toys.ExportToys(r.Context(), query, func(toy *data.Toy) error {
+		if out == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := out.Write(toy); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		return nil
+	})
+	switch {
+	case err != nil && out == nil:
+		h.errorResponse(w, err)
+		return
+	case err == nil && out == nil:
+		// Nothing matched; the file still gets its header.
+		err = start()
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		h.log.PrintError(err, map[string]string{
+			"method": "toys.exportToys",
+			"rows":   strconv.Itoa(rows),
+		})
+		panic(http.ErrAbortHandler)
+	}
+}
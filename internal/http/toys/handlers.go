@@ -0,0 +1,289 @@
+package toys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"toysService/internal/auth"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+	"toysService/internal/validator"
+)
+
+// Toys is the part of the service layer that is served over plain REST on the
+// gateway mux rather than through the toysProto gRPC contract.
+type Toys interface {
+	ReserveToy(ctx context.Context, toyID int64, startsAt time.Time, endsAt time.Time) (data.Reservation, error)
+	CancelReservation(ctx context.Context, reservationID int64) error
+	ListReservations(ctx context.Context) ([]*data.Reservation, error)
+	AddToyUnit(ctx context.Context, unit data.ToyUnit) (data.ToyUnit, error)
+	ListToyUnits(ctx context.Context, toyID int64) ([]*data.ToyUnit, error)
+	ChangeToyUnitStatus(ctx context.Context, unitID int64, unitStatus string) error
+	RetireToyUnit(ctx context.Context, unitID int64) error
+	RestoreToy(ctx context.Context, toyID int64) error
+	ListDeletedToys(ctx context.Context, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata)
+	ListToyHistory(ctx context.Context, toyID int64, filters data.Filters) ([]*data.ToyAuditEntry, toys.Status, string, data.Metadata)
+	GetToy(ctx context.Context, toyID int64) (data.Toy, toys.Status, string)
+	ListToy(ctx context.Context, query data.ToyQuery, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata)
+	ExportToys(ctx context.Context, query data.ToyQuery, fn func(toy *data.Toy) error) error
+	CreateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error)
+	LoadToys(ctx context.Context, ids []int64) (map[int64]data.Toy, error)
+	UpdateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error)
+	Vocabulary(ctx context.Context) (data.Vocabulary, error)
+	SuggestToys(ctx context.Context, prefix string, limit int) ([]*data.Suggestion, error)
+	ToyFacets(ctx context.Context, query data.ToyQuery, fuzzy bool) (data.Facets, error)
+	ListCategories(ctx context.Context) ([]*data.Category, error)
+	GetCategory(ctx context.Context, slug string) (data.Category, error)
+	CreateCategory(ctx context.Context, category data.Category) (data.Category, error)
+	UpdateCategory(ctx context.Context, slug string, upd data.CategoryUpdate) (data.Category, error)
+	DeleteCategory(ctx context.Context, slug string) error
+	ListSkills(ctx context.Context) ([]*data.Skill, error)
+	CreateSkill(ctx context.Context, skill data.Skill) (data.Skill, error)
+	UpdateSkill(ctx context.Context, id int64, upd data.SkillUpdate) (data.Skill, error)
+	DeleteSkill(ctx context.Context, id int64) error
+	ListManufacturers(ctx context.Context) ([]*data.Manufacturer, error)
+	GetBrandPage(ctx context.Context, id int64) (data.BrandPage, error)
+	CreateManufacturer(ctx context.Context, m data.Manufacturer) (data.Manufacturer, error)
+	UpdateManufacturer(ctx context.Context, id int64, upd data.ManufacturerUpdate) (data.Manufacturer, error)
+	DeleteManufacturer(ctx context.Context, id int64) error
+	UploadImage(ctx context.Context, body []byte) (data.ToyImage, error)
+	OpenImage(ctx context.Context, key string) (io.ReadCloser, error)
+	ListToyImages(ctx context.Context, toyID int64) ([]*data.ToyImage, error)
+	SetToyImages(ctx context.Context, toyID int64, items []data.ToyImage) ([]*data.ToyImage, error)
+	DeleteImage(ctx context.Context, id int64) error
+}
+
+type handler struct {
+	toys     Toys
+	log      *jsonlog.Logger
+	policies auth.Policies
+	secret   []byte
+}
+
+type envelope map[string]any
+
+type route struct {
+	method  string
+	pattern string
+	handle  runtime.HandlerFunc
+}
+
+// Register adds the REST routes to mux. Uploaded images are served under the
+// path of mediaURL, the base URL the blob store builds image URLs from; a
+// host in mediaURL is expected to proxy that path to the gateway.
+func Register(mux *runtime.ServeMux, toys Toys, log *jsonlog.Logger, policies auth.Policies, secret []byte, mediaURL string) error {
+	h := &handler{toys: toys, log: log, policies: policies, secret: secret}
+
+	media, err := url.Parse(mediaURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", "toys.Register", err)
+	}
+	mediaPath := strings.TrimSuffix(media.Path, "/")
+	if mediaPath == "" {
+		return fmt.Errorf("%s: media url %q has no path to serve images under", "toys.Register", mediaURL)
+	}
+
+	routes := []route{
+		{http.MethodPost, "/v1/toys/{toy_id}/reservations", h.reserveToy},
+		{http.MethodGet, "/v1/reservations", h.listReservations},
+		{http.MethodDelete, "/v1/reservations/{reservation_id}", h.cancelReservation},
+		{http.MethodPost, "/v1/toys/{toy_id}/units", h.addToyUnit},
+		{http.MethodGet, "/v1/toys/{toy_id}/units", h.listToyUnits},
+		{http.MethodPatch, "/v1/units/{unit_id}", h.changeToyUnitStatus},
+		{http.MethodPost, "/v1/units/{unit_id}/retire", h.retireToyUnit},
+		{http.MethodPost, "/v1/toys/{toy_id}/restore", h.restoreToy},
+		{http.MethodGet, "/v1/toys/deleted", h.listDeletedToys},
+		{http.MethodGet, "/v1/toys/{toy_id}/history", h.listToyHistory},
+		{http.MethodGet, "/v1/toys/search", h.searchToys},
+		{http.MethodGet, "/v1/toys/suggest", h.suggestToys},
+		{http.MethodGet, "/v1/toys/export", h.exportToys},
+		{http.MethodPost, "/v1/toys/batch", h.createToys},
+		{http.MethodPatch, "/v1/toys/batch", h.updateToys},
+		{http.MethodGet, "/v1/categories", h.listCategories},
+		{http.MethodGet, "/v1/categories/{slug}", h.getCategory},
+		{http.MethodPost, "/v1/categories", h.createCategory},
+		{http.MethodPatch, "/v1/categories/{slug}", h.updateCategory},
+		{http.MethodDelete, "/v1/categories/{slug}", h.deleteCategory},
+		{http.MethodGet, "/v1/skills", h.listSkills},
+		{http.MethodPost, "/v1/skills", h.createSkill},
+		{http.MethodPatch, "/v1/skills/{skill_id}", h.updateSkill},
+		{http.MethodDelete, "/v1/skills/{skill_id}", h.deleteSkill},
+		{http.MethodGet, "/v1/manufacturers", h.listManufacturers},
+		{http.MethodGet, "/v1/manufacturers/{manufacturer_id}", h.getBrandPage},
+		{http.MethodPost, "/v1/manufacturers", h.createManufacturer},
+		{http.MethodPatch, "/v1/manufacturers/{manufacturer_id}", h.updateManufacturer},
+		{http.MethodDelete, "/v1/manufacturers/{manufacturer_id}", h.deleteManufacturer},
+		{http.MethodPost, "/v1/images", h.uploadImages},
+		{http.MethodDelete, "/v1/images/{image_id}", h.deleteImage},
+		{http.MethodGet, "/v1/toys/{toy_id}/images", h.listToyImages},
+		{http.MethodPut, "/v1/toys/{toy_id}/images", h.setToyImages},
+	}
+
+	for _, rt := range routes {
+		if err := mux.HandlePath(rt.method, rt.pattern, h.authorize(rt.method+" "+rt.pattern, rt.handle)); err != nil {
+			return fmt.Errorf("%s: %w", "toys.Register", err)
+		}
+	}
+	// The media route moves with the media URL but keeps its policy name.
+	if err = mux.HandlePath(http.MethodGet, mediaPath+"/{key}", h.authorize("GET /media/{key}", h.serveImage)); err != nil {
+		return fmt.Errorf("%s: %w", "toys.Register", err)
+	}
+	return nil
+}
+
+// authorize applies the same policy table as the gRPC interceptor. The bearer
+// token is also copied into incoming gRPC metadata so that service calls which
+// forward it to other services (e.g. the subscriptions client) keep working.
+func (h *handler) authorize(key string, next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		policy, ok := h.policies[key]
+		if !ok {
+			h.errorResponse(w, status.Error(codes.PermissionDenied, "insufficient permissions for this method"))
+			return
+		}
+
+		authHeader := r.Header.Get("Authorization")
+		ctx, err := auth.Authorize(r.Context(), h.secret, policy, authHeader)
+		if err != nil {
+			h.errorResponse(w, err)
+			return
+		}
+		if authHeader != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authHeader))
+		}
+
+		next(w, r.WithContext(ctx), pathParams)
+	}
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, code int, body envelope) {
+	js, err := json.Marshal(body)
+	if err != nil {
+		h.log.PrintError(err, map[string]string{
+			"method": "toys.writeJSON",
+		})
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(js)
+}
+
+func (h *handler) readJSON(r *http.Request, dst any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1_048_576))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return status.Error(codes.InvalidArgument, "body contains badly-formed JSON: "+err.Error())
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return status.Error(codes.InvalidArgument, "body must only contain a single JSON value")
+	}
+	return nil
+}
+
+// errorResponse renders a gRPC status error with the HTTP code the gateway
+// would use for it, so REST-only routes fail the same way as gateway ones.
+func (h *handler) errorResponse(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	h.writeJSON(w, runtime.HTTPStatusFromCode(st.Code()), envelope{
+		"code":    st.Code(),
+		"message": st.Message(),
+	})
+}
+
+func pathID(pathParams map[string]string, name string) (int64, error) {
+	id, err := strconv.ParseInt(pathParams[name], 10, 64)
+	if err != nil || id < 1 {
+		return 0, status.Error(codes.InvalidArgument, "invalid "+strings.ReplaceAll(name, "_", " "))
+	}
+	return id, nil
+}
+
+// readFilters builds paging and sorting from the page, page_size, sort and
+// cursor query parameters, mirroring the defaults used by ListToy.
+func readFilters(r *http.Request, defaultSort string, safelist ...string) (data.Filters, error) {
+	qs := r.URL.Query()
+	filters := data.Filters{
+		Page:         readInt32(qs.Get("page"), 1),
+		PageSize:     readInt32(qs.Get("page_size"), 20),
+		Sort:         qs.Get("sort"),
+		SortSafelist: safelist,
+		Cursor:       qs.Get("cursor"),
+	}
+	if filters.Sort == "" {
+		filters.Sort = defaultSort
+	}
+
+	v := validator.New()
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		return data.Filters{}, collectErrors(v)
+	}
+	return filters, nil
+}
+
+// readList reads a query parameter that may be repeated or comma separated.
+func readList(r *http.Request, name string) []string {
+	var items []string
+	for _, value := range r.URL.Query()[name] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func readInt32(s string, defaultValue int32) int32 {
+	if s == "" {
+		return defaultValue
+	}
+	i, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return int32(i)
+}
+
+func readInt64(s string, defaultValue int64) int64 {
+	if s == "" {
+		return defaultValue
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+// opError converts the (status, msg) results of the service layer into an error
+// for errorResponse.
+func opError(opStatus toys.Status, msg string) error {
+	if opStatus == toys.Status_STATUS_OK {
+		return nil
+	}
+	return status.Error(codes.Internal, msg)
+}
+
+func collectErrors(v *validator.Validator) error {
+	var b strings.Builder
+	for field, msg := range v.Errors {
+		fmt.Fprintf(&b, "%s:%s; ", field, msg)
+	}
+	return status.Error(codes.InvalidArgument, b.String())
+}
@@ -0,0 +1,166 @@
+package toys
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"toysService/internal/data"
+	"toysService/internal/images"
+	"toysService/internal/validator"
+	"toysService/storage/postgres"
+)
+
+// uploadImages accepts up to data.MaxToyImages files in the "images" field of
+// a multipart form and returns the stored images, ready to be attached to a
+// toy with setToyImages.
+func (h *handler) uploadImages(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	r.Body = http.MaxBytesReader(w, r.Body, data.MaxToyImages*images.MaxBytes+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.errorResponse(w, status.Error(codes.InvalidArgument, "request body is too large"))
+			return
+		}
+		h.errorResponse(w, status.Error(codes.InvalidArgument, "body must be a multipart form: "+err.Error()))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["images"]
+	v := validator.New()
+	v.Check(len(files) > 0, "images", "at least 1 image")
+	v.Check(len(files) <= data.MaxToyImages, "images", fmt.Sprintf("no more than %d images", data.MaxToyImages))
+	for _, fh := range files {
+		v.Check(fh.Size <= images.MaxBytes, "images", "image must not be more than 10 MB")
+	}
+	if !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	bodies := make([][]byte, 0, len(files))
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			h.errorResponse(w, status.Error(codes.InvalidArgument, "could not read "+fh.Filename))
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(f, images.MaxBytes+1))
+		f.Close()
+		if err != nil {
+			h.errorResponse(w, status.Error(codes.InvalidArgument, "could not read "+fh.Filename))
+			return
+		}
+		bodies = append(bodies, body)
+	}
+
+	uploaded := make([]data.ToyImage, 0, len(bodies))
+	for _, body := range bodies {
+		img, err := h.toys.UploadImage(r.Context(), body)
+		if err != nil {
+			h.errorResponse(w, err)
+			return
+		}
+		uploaded = append(uploaded, img)
+	}
+
+	h.writeJSON(w, http.StatusCreated, envelope{"images": uploaded})
+}
+
+// serveImage streams a stored image. Keys are random and never reused, so the
+// response can be cached for good.
+func (h *handler) serveImage(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	key := pathParams["key"]
+	blob, err := h.toys.OpenImage(r.Context(), key)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err = io.Copy(w, blob); err != nil {
+		h.log.PrintError(err, map[string]string{
+			"method": "toys.serveImage",
+		})
+	}
+}
+
+func (h *handler) listToyImages(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	toyID, err := pathID(pathParams, "toy_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	list, err := h.toys.ListToyImages(r.Context(), toyID)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"images": list})
+}
+
+// setToyImages replaces the images of a toy with the listed uploads, in
+// order. Images left out are detached but kept.
+func (h *handler) setToyImages(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	toyID, err := pathID(pathParams, "toy_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	var input struct {
+		Images []struct {
+			ID      int64  `json:"id"`
+			Alt     string `json:"alt"`
+			Primary bool   `json:"primary"`
+		} `json:"images"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	items := make([]data.ToyImage, 0, len(input.Images))
+	for _, item := range input.Images {
+		items = append(items, data.ToyImage{ID: item.ID, Alt: item.Alt, Primary: item.Primary})
+	}
+
+	v := validator.New()
+	if postgres.ValidateToyImages(v, items); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	list, err := h.toys.SetToyImages(r.Context(), toyID, items)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"images": list})
+}
+
+func (h *handler) deleteImage(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	imageID, err := pathID(pathParams, "image_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	if err := h.toys.DeleteImage(r.Context(), imageID); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"message": "image deleted"})
+}
@@ -0,0 +1,126 @@
+package toys
+
+import (
+	"net/http"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+	"toysService/storage/postgres"
+)
+
+func (h *handler) listManufacturers(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	manufacturers, err := h.toys.ListManufacturers(r.Context())
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"manufacturers": manufacturers})
+}
+
+// getBrandPage returns a manufacturer with its toy count and top toys.
+func (h *handler) getBrandPage(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	manufacturerID, err := pathID(pathParams, "manufacturer_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	page, err := h.toys.GetBrandPage(r.Context(), manufacturerID)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"manufacturer": page.Manufacturer, "topToys": mapToys(page.TopToys)})
+}
+
+func (h *handler) createManufacturer(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	var input struct {
+		Name        string `json:"name"`
+		Country     string `json:"country"`
+		LogoURL     string `json:"logoUrl"`
+		Description string `json:"description"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	m := data.Manufacturer{Name: input.Name, Country: input.Country, LogoURL: input.LogoURL, Description: input.Description}
+	v := validator.New()
+	if postgres.ValidateManufacturer(v, &m); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	m, err := h.toys.CreateManufacturer(r.Context(), m)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, envelope{"manufacturer": m})
+}
+
+func (h *handler) updateManufacturer(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	manufacturerID, err := pathID(pathParams, "manufacturer_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Country     *string `json:"country"`
+		LogoURL     *string `json:"logoUrl"`
+		Description *string `json:"description"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	// Validate the changed fields on top of placeholders for the rest.
+	m := data.Manufacturer{Name: "-"}
+	if input.Name != nil {
+		m.Name = *input.Name
+	}
+	if input.Country != nil {
+		m.Country = *input.Country
+	}
+	if input.LogoURL != nil {
+		m.LogoURL = *input.LogoURL
+	}
+	if input.Description != nil {
+		m.Description = *input.Description
+	}
+	v := validator.New()
+	if postgres.ValidateManufacturer(v, &m); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	upd := data.ManufacturerUpdate{Name: input.Name, Country: input.Country, LogoURL: input.LogoURL, Description: input.Description}
+	updated, err := h.toys.UpdateManufacturer(r.Context(), manufacturerID, upd)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"manufacturer": updated})
+}
+
+func (h *handler) deleteManufacturer(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	manufacturerID, err := pathID(pathParams, "manufacturer_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	if err := h.toys.DeleteManufacturer(r.Context(), manufacturerID); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"message": "manufacturer deleted"})
+}
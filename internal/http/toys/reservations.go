@@ -0,0 +1,92 @@
+package toys
+
+import (
+	"net/http"
+	"time"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+	"toysService/storage/postgres"
+)
+
+type reservationResponse struct {
+	ID        int64     `json:"id"`
+	ToyID     int64     `json:"toyId"`
+	UnitID    int64     `json:"unitId"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (h *handler) reserveToy(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	toyID, err := pathID(pathParams, "toy_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	var input struct {
+		StartsAt time.Time `json:"startsAt"`
+		EndsAt   time.Time `json:"endsAt"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if postgres.ValidateReservation(v, &data.Reservation{ToyID: toyID, StartsAt: input.StartsAt, EndsAt: input.EndsAt}); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	reservation, err := h.toys.ReserveToy(r.Context(), toyID, input.StartsAt, input.EndsAt)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, envelope{"reservation": mapReservation(&reservation)})
+}
+
+func (h *handler) listReservations(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	reservations, err := h.toys.ListReservations(r.Context())
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	items := make([]reservationResponse, 0, len(reservations))
+	for _, reservation := range reservations {
+		items = append(items, mapReservation(reservation))
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"reservations": items})
+}
+
+func (h *handler) cancelReservation(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	reservationID, err := pathID(pathParams, "reservation_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	if err := h.toys.CancelReservation(r.Context(), reservationID); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"message": "reservation cancelled"})
+}
+
+func mapReservation(r *data.Reservation) reservationResponse {
+	return reservationResponse{
+		ID:        r.ID,
+		ToyID:     r.ToyID,
+		UnitID:    r.UnitID,
+		StartsAt:  r.StartsAt,
+		EndsAt:    r.EndsAt,
+		Status:    r.Status,
+		CreatedAt: r.CreatedAt,
+	}
+}
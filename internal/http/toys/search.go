@@ -0,0 +1,117 @@
+package toys
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strconv"
+	"strings"
+	"toysService/internal/contextkeys"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+)
+
+// readPrimaryHeader is the header gRPC clients send as x-read-primary: set to
+// true, the search reads from the primary database instead of a replica.
+const readPrimaryHeader = "X-Read-Primary"
+
+// searchToys is ListToy with the parts the gRPC contract has no room for:
+// every match carries its rank and a highlighted snippet, and ?facets=true
+// adds per-filter counts for catalogue sidebars. It sorts by relevance when
+// there is a search query and by id otherwise.
+func (h *handler) searchToys(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	qs := r.URL.Query()
+	v := validator.New()
+	query := readToyQuery(r, v)
+	if !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	defaultSort := "id"
+	if query.Title != "" {
+		defaultSort = "relevance"
+	}
+	filters, err := readFilters(r, defaultSort, data.ToySortSafelist...)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+	if data.ValidateToyQuery(v, query, filters); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	ctx := r.Context()
+	if primary, _ := strconv.ParseBool(r.Header.Get(readPrimaryHeader)); primary {
+		ctx = context.WithValue(ctx, contextkeys.ReadPrimaryKey, true)
+	}
+	toyList, opStatus, msg, metadata := h.toys.ListToy(ctx, query, filters)
+	if err := opError(opStatus, msg); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	body := envelope{
+		"toys":     mapToys(toyList),
+		"metadata": mapMetadata(metadata),
+	}
+	if qs.Get("facets") == "true" {
+		facets, err := h.toys.ToyFacets(r.Context(), query, metadata.Fuzzy)
+		if err != nil {
+			h.errorResponse(w, err)
+			return
+		}
+		body["facets"] = facets
+	}
+
+	h.writeJSON(w, http.StatusOK, body)
+}
+
+// readToyQuery reads the ListToy filters shared by search and export: ?q=,
+// ?categories=, ?skills=, ?manufacturers=, ?age_months=, ?from= and ?to=.
+func readToyQuery(r *http.Request, v *validator.Validator) data.ToyQuery {
+	qs := r.URL.Query()
+	query := data.ToyQuery{
+		Title:      strings.TrimSpace(qs.Get("q")),
+		Categories: readList(r, "categories"),
+		Skills:     readList(r, "skills"),
+		From:       readInt64(qs.Get("from"), 0),
+		To:         readInt64(qs.Get("to"), 0),
+	}
+
+	if s := qs.Get("age_months"); s != "" {
+		age, err := strconv.ParseInt(s, 10, 32)
+		v.Check(err == nil && age >= 0 && age <= data.MaxAgeMonths, "age_months", "must be a number of months between 0 and 216")
+		ageMonths := int32(age)
+		query.AgeMonths = &ageMonths
+	}
+	if items := readList(r, "manufacturers"); len(items) > 0 {
+		ids, ok := data.ParseIDs(items)
+		v.Check(ok, "manufacturers", "must be a list of manufacturer ids")
+		query.Manufacturers = ids
+	}
+	return query
+}
+
+// suggestToys completes a prefix to toy titles, categories and manufacturers.
+// It reads ?q= and an optional ?limit= capped at data.SuggestionLimit.
+func (h *handler) suggestToys(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	qs := r.URL.Query()
+	prefix := qs.Get("q")
+	if len(prefix) > 100 {
+		h.errorResponse(w, status.Error(codes.InvalidArgument, "q must not be more than 100 bytes long"))
+		return
+	}
+
+	suggestions, err := h.toys.SuggestToys(r.Context(), prefix, int(readInt32(qs.Get("limit"), 10)))
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	// Suggestions change only when toys do, so browsers may reuse them briefly.
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	h.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions})
+}
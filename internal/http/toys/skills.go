@@ -0,0 +1,99 @@
+package toys
+
+import (
+	"net/http"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+	"toysService/storage/postgres"
+)
+
+// listSkills returns the skills dictionary with how many toys use each skill.
+func (h *handler) listSkills(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	skills, err := h.toys.ListSkills(r.Context())
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"skills": skills})
+}
+
+func (h *handler) createSkill(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	var input struct {
+		Name     string   `json:"name"`
+		Synonyms []string `json:"synonyms"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	skill := data.Skill{Name: input.Name, Synonyms: input.Synonyms}
+	v := validator.New()
+	if postgres.ValidateSkill(v, &skill); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	skill, err := h.toys.CreateSkill(r.Context(), skill)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, envelope{"skill": skill})
+}
+
+// updateSkill renames a skill and/or replaces its synonyms; "synonyms": []
+// removes them all.
+func (h *handler) updateSkill(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	skillID, err := pathID(pathParams, "skill_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	var input struct {
+		Name     *string  `json:"name"`
+		Synonyms []string `json:"synonyms"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	name := ""
+	if input.Name != nil {
+		name = *input.Name
+		v.Check(name != "", "name", "name must not be empty")
+		v.Check(len(name) <= 100, "name", "name must not be more than 100 bytes long")
+	}
+	if postgres.ValidateSkillSynonyms(v, name, input.Synonyms); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	skill, err := h.toys.UpdateSkill(r.Context(), skillID, data.SkillUpdate{Name: input.Name, Synonyms: input.Synonyms})
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"skill": skill})
+}
+
+func (h *handler) deleteSkill(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	skillID, err := pathID(pathParams, "skill_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	if err := h.toys.DeleteSkill(r.Context(), skillID); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"message": "skill deleted"})
+}
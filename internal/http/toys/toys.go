@@ -0,0 +1,160 @@
+package toys
+
+import (
+	"net/http"
+	"toysService/internal/data"
+)
+
+type toyResponse struct {
+	ID             int64    `json:"id"`
+	Title          string   `json:"title"`
+	Desc           string   `json:"desc,omitempty"`
+	Value          int64    `json:"value"`
+	Images         []string `json:"images,omitempty"`
+	Skills         []string `json:"skills"`
+	Categories     []string `json:"categories"`
+	RecommendedAge string   `json:"recommendedAge"`
+	MinAgeMonths   int32    `json:"minAgeMonths"`
+	MaxAgeMonths   int32    `json:"maxAgeMonths,omitempty"`
+	Manufacturer   string   `json:"manufacturer,omitempty"`
+	ManufacturerID int64    `json:"manufacturerId,omitempty"`
+	IsAvailable    bool     `json:"isAvailable"`
+	AvailableCount int32    `json:"availableCount"`
+	DeletedAt      string   `json:"deletedAt,omitempty"`
+	Rank           float32  `json:"rank,omitempty"`
+	Snippet        string   `json:"snippet,omitempty"`
+}
+
+type auditEntryResponse struct {
+	ID        int64              `json:"id"`
+	ToyID     int64              `json:"toyId"`
+	UserID    int64              `json:"userId,omitempty"`
+	Action    string             `json:"action"`
+	Changes   []data.FieldChange `json:"changes"`
+	ChangedAt string             `json:"changedAt"`
+}
+
+type metadataResponse struct {
+	CurrentPage  int32  `json:"currentPage"`
+	PageSize     int32  `json:"pageSize"`
+	FirstPage    int32  `json:"firstPage"`
+	LastPage     int32  `json:"lastPage"`
+	TotalRecords int32  `json:"totalRecords"`
+	NextCursor   string `json:"nextCursor,omitempty"`
+	PrevCursor   string `json:"prevCursor,omitempty"`
+	Fuzzy        bool   `json:"fuzzy,omitempty"`
+}
+
+func (h *handler) restoreToy(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	toyID, err := pathID(pathParams, "toy_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	if err := h.toys.RestoreToy(r.Context(), toyID); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"message": "toy restored successfully"})
+}
+
+func (h *handler) listDeletedToys(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	filters, err := readFilters(r, "-deleted_at", "id", "title", "value", "deleted_at", "-id", "-title", "-value", "-deleted_at")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	toyList, opStatus, msg, metadata := h.toys.ListDeletedToys(r.Context(), filters)
+	if err := opError(opStatus, msg); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{
+		"toys":     mapToys(toyList),
+		"metadata": mapMetadata(metadata),
+	})
+}
+
+func (h *handler) listToyHistory(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	toyID, err := pathID(pathParams, "toy_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	filters, err := readFilters(r, "-changed_at", "changed_at", "-changed_at")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	entries, opStatus, msg, metadata := h.toys.ListToyHistory(r.Context(), toyID, filters)
+	if err := opError(opStatus, msg); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	items := make([]auditEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, auditEntryResponse{
+			ID:        entry.ID,
+			ToyID:     entry.ToyID,
+			UserID:    entry.UserID,
+			Action:    entry.Action,
+			Changes:   entry.Changes,
+			ChangedAt: entry.ChangedAt,
+		})
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{
+		"history":  items,
+		"metadata": mapMetadata(metadata),
+	})
+}
+
+func mapToy(toy *data.Toy) toyResponse {
+	return toyResponse{
+		ID:             toy.ID,
+		Title:          toy.Title,
+		Desc:           toy.Desc,
+		Value:          toy.Value,
+		Images:         toy.Images,
+		Skills:         toy.Skills,
+		Categories:     toy.Categories,
+		RecommendedAge: toy.RecAge,
+		MinAgeMonths:   toy.MinAgeMonths,
+		MaxAgeMonths:   toy.MaxAgeMonths,
+		Manufacturer:   toy.Manufacturer,
+		ManufacturerID: toy.ManufacturerID,
+		IsAvailable:    toy.IsAvailable,
+		AvailableCount: toy.AvailableCount,
+		DeletedAt:      toy.DeletedAt,
+		Rank:           toy.Rank,
+		Snippet:        toy.Snippet,
+	}
+}
+
+func mapToys(toyList []*data.Toy) []toyResponse {
+	items := make([]toyResponse, 0, len(toyList))
+	for _, toy := range toyList {
+		items = append(items, mapToy(toy))
+	}
+	return items
+}
+
+func mapMetadata(metadata data.Metadata) metadataResponse {
+	return metadataResponse{
+		CurrentPage:  metadata.CurrentPage,
+		PageSize:     metadata.PageSize,
+		FirstPage:    metadata.FirstPage,
+		LastPage:     metadata.LastPage,
+		TotalRecords: metadata.TotalRecords,
+		NextCursor:   metadata.NextCursor,
+		PrevCursor:   metadata.PrevCursor,
+		Fuzzy:        metadata.Fuzzy,
+	}
+}
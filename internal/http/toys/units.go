@@ -0,0 +1,111 @@
+package toys
+
+import (
+	"net/http"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+	"toysService/storage/postgres"
+)
+
+// addToyUnit puts a physical copy of a toy into the inventory.
+func (h *handler) addToyUnit(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	toyID, err := pathID(pathParams, "toy_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	var input struct {
+		Serial    string `json:"serial"`
+		Condition string `json:"condition"`
+		Location  string `json:"location"`
+		Status    string `json:"status"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	unit := data.ToyUnit{
+		ToyID:     toyID,
+		Serial:    input.Serial,
+		Condition: input.Condition,
+		Location:  input.Location,
+		Status:    input.Status,
+	}
+	v := validator.New()
+	if postgres.ValidateToyUnit(v, &unit); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	unit, err = h.toys.AddToyUnit(r.Context(), unit)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, envelope{"unit": unit})
+}
+
+func (h *handler) listToyUnits(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	toyID, err := pathID(pathParams, "toy_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	units, err := h.toys.ListToyUnits(r.Context(), toyID)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"units": units})
+}
+
+// changeToyUnitStatus moves a unit to the status in the body, e.g. into
+// maintenance and back. Retired units stay retired.
+func (h *handler) changeToyUnitStatus(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	unitID, err := pathID(pathParams, "unit_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	var input struct {
+		Status string `json:"status"`
+	}
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if postgres.ValidateUnitStatus(v, input.Status); !v.Valid() {
+		h.errorResponse(w, collectErrors(v))
+		return
+	}
+
+	if err := h.toys.ChangeToyUnitStatus(r.Context(), unitID, input.Status); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"message": "unit status changed successfully"})
+}
+
+func (h *handler) retireToyUnit(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	unitID, err := pathID(pathParams, "unit_id")
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	if err := h.toys.RetireToyUnit(r.Context(), unitID); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envelope{"message": "unit retired successfully"})
+}
@@ -0,0 +1,33 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"toysService/internal/data"
+)
+
+// WriterPublisher writes every event as one JSON line. Point it at os.Stdout or
+// a file to watch the event stream locally or to assert on it in tests.
+type WriterPublisher struct {
+	out io.Writer
+	mu  sync.Mutex
+}
+
+func NewWriterPublisher(out io.Writer) *WriterPublisher {
+	return &WriterPublisher{out: out}
+}
+
+func (p *WriterPublisher) Publish(ctx context.Context, event *data.ToyEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.out.Write(append(line, '\n'))
+	return err
+}
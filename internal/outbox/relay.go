@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"context"
+	"time"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+)
+
+// Publisher delivers a single toy event to the outside world. Returning an
+// error leaves the event in the outbox to be retried later.
+type Publisher interface {
+	Publish(ctx context.Context, event *data.ToyEvent) error
+}
+
+type eventStore interface {
+	DeliverToyEvents(ctx context.Context, limit int, publish func(context.Context, *data.ToyEvent) error, backoff func(attempts int32) time.Duration) (int, error)
+}
+
+type Relay struct {
+	log        *jsonlog.Logger
+	store      eventStore
+	publisher  Publisher
+	interval   time.Duration
+	batchSize  int
+	maxBackoff time.Duration
+}
+
+func NewRelay(log *jsonlog.Logger, store eventStore, publisher Publisher, interval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		log:        log,
+		store:      store,
+		publisher:  publisher,
+		interval:   interval,
+		batchSize:  batchSize,
+		maxBackoff: 10 * time.Minute,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled. A full batch is followed
+// immediately by the next one so a backlog drains without waiting for the
+// ticker.
+func (r *Relay) Run(ctx context.Context) {
+	r.log.PrintInfo("outbox relay started", map[string]string{
+		"interval": r.interval.String(),
+	})
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		delivered, err := r.store.DeliverToyEvents(ctx, r.batchSize, r.publisher.Publish, r.backoff)
+		if err != nil && ctx.Err() == nil {
+			r.log.PrintError(err, map[string]string{
+				"method": "outbox.Run",
+			})
+		}
+		if err == nil && delivered == r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			r.log.PrintInfo("outbox relay stopped", nil)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) backoff(attempts int32) time.Duration {
+	delay := time.Second
+	for i := int32(1); i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+	return delay
+}
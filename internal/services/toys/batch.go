@@ -0,0 +1,81 @@
+package toys
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"toysService/internal/data"
+)
+
+// CreateToys creates a batch of validated toys. atomic selects the
+// all-or-nothing mode; otherwise every item that can be written is.
+func (t *Toys) CreateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.CreateToys",
+	})
+	results, err := t.toysProvider.CreateToys(ctx, items, atomic)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.CreateToys",
+		})
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return t.batchResults(results, data.BatchStatusCreated, "toys.CreateToys"), nil
+}
+
+// LoadToys reads the live toys a batch update changes, keyed by id. Toys
+// that are missing or deleted are left out.
+func (t *Toys) LoadToys(ctx context.Context, ids []int64) (map[int64]data.Toy, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.LoadToys",
+	})
+	found, err := t.toysProvider.LoadToys(ctx, ids)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.LoadToys",
+		})
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return found, nil
+}
+
+// UpdateToys changes a batch of validated toys, each at the version the
+// caller read it at.
+func (t *Toys) UpdateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.UpdateToys",
+	})
+	results, err := t.toysProvider.UpdateToys(ctx, items, atomic)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.UpdateToys",
+		})
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return t.batchResults(results, data.BatchStatusUpdated, "toys.UpdateToys"), nil
+}
+
+// batchResults turns the errors storage reported per item into statuses and
+// client-facing messages. Unexpected errors are logged and hidden.
+func (t *Toys) batchResults(results []data.BatchResult, written string, method string) []data.BatchResult {
+	for i := range results {
+		r := &results[i]
+		switch {
+		case r.Err == nil:
+			r.Status = written
+		case errors.Is(r.Err, data.ErrRecordNotFound):
+			r.Status, r.Error = data.BatchStatusNotFound, "toy not found"
+		case errors.Is(r.Err, data.ErrEditConflict):
+			r.Status, r.Error = data.BatchStatusConflict, "toy was changed since it was read, reload it and try again"
+		case errors.Is(r.Err, data.ErrBatchAborted):
+			r.Status, r.Error = data.BatchStatusAborted, r.Err.Error()
+		default:
+			t.log.PrintError(r.Err, map[string]string{
+				"method": method,
+			})
+			r.Status, r.Error = data.BatchStatusFailed, "internal error"
+		}
+	}
+	return results
+}
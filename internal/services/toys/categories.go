@@ -0,0 +1,78 @@
+package toys
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"toysService/internal/data"
+)
+
+func (t *Toys) ListCategories(ctx context.Context) ([]*data.Category, error) {
+	categories, err := t.toysProvider.ListCategories(ctx)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.ListCategories",
+		})
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return categories, nil
+}
+
+func (t *Toys) GetCategory(ctx context.Context, slug string) (data.Category, error) {
+	category, err := t.toysProvider.GetCategory(ctx, slug)
+	if err != nil {
+		return data.Category{}, t.categoryError(err, "toys.GetCategory")
+	}
+	return category, nil
+}
+
+func (t *Toys) CreateCategory(ctx context.Context, category data.Category) (data.Category, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.CreateCategory",
+	})
+	category, err := t.toysProvider.CreateCategory(ctx, category)
+	if err != nil {
+		return data.Category{}, t.categoryError(err, "toys.CreateCategory")
+	}
+	return category, nil
+}
+
+func (t *Toys) UpdateCategory(ctx context.Context, slug string, upd data.CategoryUpdate) (data.Category, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.UpdateCategory",
+	})
+	category, err := t.toysProvider.UpdateCategory(ctx, slug, upd)
+	if err != nil {
+		return data.Category{}, t.categoryError(err, "toys.UpdateCategory")
+	}
+	return category, nil
+}
+
+func (t *Toys) DeleteCategory(ctx context.Context, slug string) error {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.DeleteCategory",
+	})
+	if err := t.toysProvider.DeleteCategory(ctx, slug); err != nil {
+		return t.categoryError(err, "toys.DeleteCategory")
+	}
+	return nil
+}
+
+func (t *Toys) categoryError(err error, method string) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "category not found")
+	case errors.Is(err, data.ErrDuplicateSlug):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, data.ErrCategoryInUse):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, data.ErrCategoryCycle), errors.Is(err, data.ErrNoCategoryNames):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		t.log.PrintError(err, map[string]string{
+			"method": method,
+		})
+		return status.Error(codes.Internal, "internal error")
+	}
+}
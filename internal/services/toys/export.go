@@ -0,0 +1,24 @@
+package toys
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"toysService/internal/data"
+)
+
+// ExportToys streams every toy matching the ListToy filters in query to fn.
+// It returns early with fn's error, e.g. when the client downloading the
+// export goes away.
+func (t *Toys) ExportToys(ctx context.Context, query data.ToyQuery, fn func(toy *data.Toy) error) error {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.ExportToys",
+	})
+	if err := t.toysProvider.ExportToys(ctx, withValueDefaults(query), fn); err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.ExportToys",
+		})
+		return status.Error(codes.Internal, "internal error")
+	}
+	return nil
+}
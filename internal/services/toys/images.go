@@ -0,0 +1,139 @@
+package toys
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"toysService/internal/blobstore"
+	"toysService/internal/data"
+	"toysService/internal/images"
+)
+
+// UploadImage stores an image and its thumbnail and records it as an
+// unattached upload.
+func (t *Toys) UploadImage(ctx context.Context, body []byte) (data.ToyImage, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.UploadImage",
+	})
+	if len(body) > images.MaxBytes {
+		return data.ToyImage{}, status.Error(codes.InvalidArgument, "image must not be more than 10 MB")
+	}
+	info, err := images.Inspect(body)
+	if err != nil {
+		return data.ToyImage{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	thumbnail, err := images.Thumbnail(body, images.ThumbnailSize)
+	if err != nil {
+		return data.ToyImage{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return data.ToyImage{}, t.imageError(err, "toys.UploadImage")
+	}
+	img := data.ToyImage{
+		Key:          name + info.Ext,
+		ThumbnailKey: name + "_thumb.jpg",
+		ContentType:  info.ContentType,
+		Width:        int32(info.Width),
+		Height:       int32(info.Height),
+		Size:         int64(len(body)),
+	}
+	img.URL, img.ThumbnailURL = t.images.URL(img.Key), t.images.URL(img.ThumbnailKey)
+
+	if err = t.images.Put(ctx, img.Key, bytes.NewReader(body)); err != nil {
+		return data.ToyImage{}, t.imageError(err, "toys.UploadImage")
+	}
+	if err = t.images.Put(ctx, img.ThumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+		t.deleteBlobs(ctx, img)
+		return data.ToyImage{}, t.imageError(err, "toys.UploadImage")
+	}
+
+	img, err = t.toysProvider.InsertImage(ctx, img)
+	if err != nil {
+		t.deleteBlobs(ctx, img)
+		return data.ToyImage{}, t.imageError(err, "toys.UploadImage")
+	}
+	return img, nil
+}
+
+// OpenImage streams a stored blob; the caller closes it.
+func (t *Toys) OpenImage(ctx context.Context, key string) (io.ReadCloser, error) {
+	blob, err := t.images.Open(ctx, key)
+	if err != nil {
+		return nil, t.imageError(err, "toys.OpenImage")
+	}
+	return blob, nil
+}
+
+func (t *Toys) ListToyImages(ctx context.Context, toyID int64) ([]*data.ToyImage, error) {
+	list, err := t.toysProvider.ListToyImages(ctx, toyID)
+	if err != nil {
+		return nil, t.imageError(err, "toys.ListToyImages")
+	}
+	return list, nil
+}
+
+func (t *Toys) SetToyImages(ctx context.Context, toyID int64, items []data.ToyImage) ([]*data.ToyImage, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.SetToyImages",
+	})
+	list, err := t.toysProvider.SetToyImages(ctx, toyID, items)
+	if err != nil {
+		return nil, t.imageError(err, "toys.SetToyImages")
+	}
+	return list, nil
+}
+
+// DeleteImage deletes an unattached upload together with its blobs.
+func (t *Toys) DeleteImage(ctx context.Context, id int64) error {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.DeleteImage",
+	})
+	img, err := t.toysProvider.DeleteImage(ctx, id)
+	if err != nil {
+		return t.imageError(err, "toys.DeleteImage")
+	}
+	t.deleteBlobs(ctx, img)
+	return nil
+}
+
+// deleteBlobs removes an image's blobs on a best-effort basis; a leftover blob
+// only wastes space.
+func (t *Toys) deleteBlobs(ctx context.Context, img data.ToyImage) {
+	for _, key := range []string{img.Key, img.ThumbnailKey} {
+		if err := t.images.Delete(ctx, key); err != nil {
+			t.log.PrintError(err, map[string]string{
+				"method": "toys.deleteBlobs",
+				"key":    key,
+			})
+		}
+	}
+}
+
+func (t *Toys) imageError(err error, method string) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, blobstore.ErrNotFound):
+		return status.Error(codes.NotFound, "image not found")
+	case errors.Is(err, data.ErrImageAttached):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		t.log.PrintError(err, map[string]string{
+			"method": method,
+		})
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
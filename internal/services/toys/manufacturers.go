@@ -0,0 +1,76 @@
+package toys
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"toysService/internal/data"
+)
+
+func (t *Toys) ListManufacturers(ctx context.Context) ([]*data.Manufacturer, error) {
+	manufacturers, err := t.toysProvider.ListManufacturers(ctx)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.ListManufacturers",
+		})
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return manufacturers, nil
+}
+
+func (t *Toys) GetBrandPage(ctx context.Context, id int64) (data.BrandPage, error) {
+	page, err := t.toysProvider.GetBrandPage(ctx, id)
+	if err != nil {
+		return data.BrandPage{}, t.manufacturerError(err, "toys.GetBrandPage")
+	}
+	return page, nil
+}
+
+func (t *Toys) CreateManufacturer(ctx context.Context, m data.Manufacturer) (data.Manufacturer, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.CreateManufacturer",
+	})
+	m, err := t.toysProvider.CreateManufacturer(ctx, m)
+	if err != nil {
+		return data.Manufacturer{}, t.manufacturerError(err, "toys.CreateManufacturer")
+	}
+	return m, nil
+}
+
+func (t *Toys) UpdateManufacturer(ctx context.Context, id int64, upd data.ManufacturerUpdate) (data.Manufacturer, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.UpdateManufacturer",
+	})
+	m, err := t.toysProvider.UpdateManufacturer(ctx, id, upd)
+	if err != nil {
+		return data.Manufacturer{}, t.manufacturerError(err, "toys.UpdateManufacturer")
+	}
+	return m, nil
+}
+
+func (t *Toys) DeleteManufacturer(ctx context.Context, id int64) error {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.DeleteManufacturer",
+	})
+	if err := t.toysProvider.DeleteManufacturer(ctx, id); err != nil {
+		return t.manufacturerError(err, "toys.DeleteManufacturer")
+	}
+	return nil
+}
+
+func (t *Toys) manufacturerError(err error, method string) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "manufacturer not found")
+	case errors.Is(err, data.ErrDuplicateManufacturer):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, data.ErrManufacturerInUse):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		t.log.PrintError(err, map[string]string{
+			"method": method,
+		})
+		return status.Error(codes.Internal, "internal error")
+	}
+}
@@ -0,0 +1,97 @@
+package toys
+
+import (
+	"context"
+	"errors"
+	subs "github.com/spacecowboytobykty123/subsProto/gen/go/subscription"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+	"toysService/internal/data"
+)
+
+func (t *Toys) ReserveToy(ctx context.Context, toyID int64, startsAt time.Time, endsAt time.Time) (data.Reservation, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.ReserveToy",
+	})
+	userID, err := getUserFromContext(ctx)
+	if err != nil {
+		return data.Reservation{}, err
+	}
+
+	subsResp := t.subsClient.CheckSubscription(ctx, userID)
+	switch subsResp.GetSubStatus() {
+	case subs.Status_STATUS_OK, subs.Status_STATUS_SUBSCRIBED:
+	case subs.Status_STATUS_INTERNAL_ERROR:
+		return data.Reservation{}, status.Error(codes.Unavailable, "could not verify subscription")
+	default:
+		return data.Reservation{}, status.Error(codes.FailedPrecondition, "an active subscription is required to reserve toys")
+	}
+
+	reservation, err := t.toysProvider.ReserveToy(ctx, data.Reservation{
+		ToyID:    toyID,
+		UserID:   userID,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return data.Reservation{}, status.Error(codes.NotFound, "toy not found")
+		case errors.Is(err, data.ErrReservationConflict):
+			return data.Reservation{}, status.Error(codes.AlreadyExists, err.Error())
+		default:
+			t.log.PrintError(err, map[string]string{
+				"method": "toys.ReserveToy",
+			})
+			return data.Reservation{}, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	return reservation, nil
+}
+
+func (t *Toys) CancelReservation(ctx context.Context, reservationID int64) error {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.CancelReservation",
+	})
+	userID, err := getUserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = t.toysProvider.CancelReservation(ctx, reservationID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return status.Error(codes.NotFound, "reservation not found")
+		default:
+			t.log.PrintError(err, map[string]string{
+				"method": "toys.CancelReservation",
+			})
+			return status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	return nil
+}
+
+func (t *Toys) ListReservations(ctx context.Context) ([]*data.Reservation, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.ListReservations",
+	})
+	userID, err := getUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations, err := t.toysProvider.ListReservations(ctx, userID)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.ListReservations",
+		})
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return reservations, nil
+}
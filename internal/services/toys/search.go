@@ -0,0 +1,39 @@
+package toys
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"toysService/internal/data"
+)
+
+func (t *Toys) SuggestToys(ctx context.Context, prefix string, limit int) ([]*data.Suggestion, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return []*data.Suggestion{}, nil
+	}
+	if limit <= 0 || limit > data.SuggestionLimit {
+		limit = data.SuggestionLimit
+	}
+
+	suggestions, err := t.toysProvider.SuggestToys(ctx, prefix, limit)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.SuggestToys",
+		})
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return suggestions, nil
+}
+
+func (t *Toys) ToyFacets(ctx context.Context, query data.ToyQuery, fuzzy bool) (data.Facets, error) {
+	facets, err := t.toysProvider.ToyFacets(ctx, withValueDefaults(query), fuzzy)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.ToyFacets",
+		})
+		return data.Facets{}, status.Error(codes.Internal, "internal error")
+	}
+	return facets, nil
+}
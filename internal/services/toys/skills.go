@@ -0,0 +1,68 @@
+package toys
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"toysService/internal/data"
+)
+
+func (t *Toys) ListSkills(ctx context.Context) ([]*data.Skill, error) {
+	skills, err := t.toysProvider.ListSkills(ctx)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.ListSkills",
+		})
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return skills, nil
+}
+
+func (t *Toys) CreateSkill(ctx context.Context, skill data.Skill) (data.Skill, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.CreateSkill",
+	})
+	skill, err := t.toysProvider.CreateSkill(ctx, skill)
+	if err != nil {
+		return data.Skill{}, t.skillError(err, "toys.CreateSkill")
+	}
+	return skill, nil
+}
+
+func (t *Toys) UpdateSkill(ctx context.Context, id int64, upd data.SkillUpdate) (data.Skill, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.UpdateSkill",
+	})
+	skill, err := t.toysProvider.UpdateSkill(ctx, id, upd)
+	if err != nil {
+		return data.Skill{}, t.skillError(err, "toys.UpdateSkill")
+	}
+	return skill, nil
+}
+
+func (t *Toys) DeleteSkill(ctx context.Context, id int64) error {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.DeleteSkill",
+	})
+	if err := t.toysProvider.DeleteSkill(ctx, id); err != nil {
+		return t.skillError(err, "toys.DeleteSkill")
+	}
+	return nil
+}
+
+func (t *Toys) skillError(err error, method string) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "skill not found")
+	case errors.Is(err, data.ErrDuplicateSkill):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, data.ErrSkillInUse):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		t.log.PrintError(err, map[string]string{
+			"method": method,
+		})
+		return status.Error(codes.Internal, "internal error")
+	}
+}
@@ -2,10 +2,12 @@ package toys
 
 import (
 	"context"
+	"errors"
 	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"time"
+	"toysService/internal/blobstore"
 	subgrpc "toysService/internal/clients/subscriptions/grpc"
 	"toysService/internal/contextkeys"
 	"toysService/internal/data"
@@ -17,24 +19,63 @@ type Toys struct {
 	toysProvider toysProvider
 	tokenTTL     time.Duration
 	subsClient   *subgrpc.Client
+	images       blobstore.Store
 }
 
 type toysProvider interface {
 	CreateToy(ctx context.Context, inputToy data.Toy) (toys.Status, string, data.Toy)
 	DeleteToy(ctx context.Context, toyID int64) (toys.Status, string)
-	ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string)
+	RestoreToy(ctx context.Context, toyID int64) error
+	ListDeletedToys(ctx context.Context, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata)
+	ListToyHistory(ctx context.Context, toyID int64, filters data.Filters) ([]*data.ToyAuditEntry, toys.Status, string, data.Metadata)
+	ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string, error)
 	GetToy(ctx context.Context, toyID int64) (data.Toy, toys.Status, string)
 	GetToysByIds(ctx context.Context, ids []int64) ([]*data.ToySummary, string)
-	ListToy(ctx context.Context, to int64, from int64, filters data.Filters, categories []string, skills []string, title string) ([]*data.Toy, toys.Status, string, data.Metadata)
-	ListRecommended(ctx context.Context, userID int64) ([]*data.Toy, toys.Status, string, data.Metadata)
+	ListToy(ctx context.Context, query data.ToyQuery, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata)
+	ExportToys(ctx context.Context, q data.ToyQuery, fn func(toy *data.Toy) error) error
+	CreateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error)
+	LoadToys(ctx context.Context, ids []int64) (map[int64]data.Toy, error)
+	UpdateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error)
+	ListRecommended(ctx context.Context, userID int64, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata)
+	SuggestToys(ctx context.Context, prefix string, limit int) ([]*data.Suggestion, error)
+	ToyFacets(ctx context.Context, q data.ToyQuery, fuzzy bool) (data.Facets, error)
+	AddToyUnit(ctx context.Context, unit data.ToyUnit) (data.ToyUnit, error)
+	ListToyUnits(ctx context.Context, toyID int64) ([]*data.ToyUnit, error)
+	ChangeToyUnitStatus(ctx context.Context, unitID int64, unitStatus string) error
+	RetireToyUnit(ctx context.Context, unitID int64) error
+	ReserveToy(ctx context.Context, r data.Reservation) (data.Reservation, error)
+	CancelReservation(ctx context.Context, reservationID int64, userID int64) error
+	ListReservations(ctx context.Context, userID int64) ([]*data.Reservation, error)
+	ListCategories(ctx context.Context) ([]*data.Category, error)
+	GetCategory(ctx context.Context, slug string) (data.Category, error)
+	CreateCategory(ctx context.Context, c data.Category) (data.Category, error)
+	UpdateCategory(ctx context.Context, slug string, upd data.CategoryUpdate) (data.Category, error)
+	DeleteCategory(ctx context.Context, slug string) error
+	Taxonomy(ctx context.Context) (*data.Taxonomy, error)
+	ListSkills(ctx context.Context) ([]*data.Skill, error)
+	CreateSkill(ctx context.Context, skill data.Skill) (data.Skill, error)
+	UpdateSkill(ctx context.Context, id int64, upd data.SkillUpdate) (data.Skill, error)
+	DeleteSkill(ctx context.Context, id int64) error
+	SkillDictionary(ctx context.Context) (*data.SkillDictionary, error)
+	ListManufacturers(ctx context.Context) ([]*data.Manufacturer, error)
+	GetBrandPage(ctx context.Context, id int64) (data.BrandPage, error)
+	CreateManufacturer(ctx context.Context, m data.Manufacturer) (data.Manufacturer, error)
+	UpdateManufacturer(ctx context.Context, id int64, upd data.ManufacturerUpdate) (data.Manufacturer, error)
+	DeleteManufacturer(ctx context.Context, id int64) error
+	ManufacturerDirectory(ctx context.Context) (*data.ManufacturerDirectory, error)
+	InsertImage(ctx context.Context, img data.ToyImage) (data.ToyImage, error)
+	ListToyImages(ctx context.Context, toyID int64) ([]*data.ToyImage, error)
+	SetToyImages(ctx context.Context, toyID int64, items []data.ToyImage) ([]*data.ToyImage, error)
+	DeleteImage(ctx context.Context, id int64) (data.ToyImage, error)
 }
 
-func New(log *jsonlog.Logger, toysProvider toysProvider, tokenTTL time.Duration, subsClient *subgrpc.Client) *Toys {
+func New(log *jsonlog.Logger, toysProvider toysProvider, tokenTTL time.Duration, subsClient *subgrpc.Client, images blobstore.Store) *Toys {
 	return &Toys{
 		log:          log,
 		toysProvider: toysProvider,
 		tokenTTL:     tokenTTL,
 		subsClient:   subsClient,
+		images:       images,
 	}
 }
 
@@ -43,7 +84,6 @@ func (t *Toys) CreateToy(ctx context.Context, inputToy data.Toy) (toys.Status, s
 		"method": "toys.CreateToy",
 	})
 	println("До db")
-	// TODO: нужен метод проверяющий админ ли или нет
 	opStatus, msg, toy := t.toysProvider.CreateToy(ctx, inputToy)
 	println("past db")
 	println(opStatus.String())
@@ -60,7 +100,6 @@ func (t *Toys) CreateToy(ctx context.Context, inputToy data.Toy) (toys.Status, s
 }
 
 func (t *Toys) DeleteToy(ctx context.Context, toyID int64) (toys.Status, string) {
-	// TODO: нужен метод проверяющий админ ли или нет
 	t.log.PrintInfo("business logic layer", map[string]string{
 		"method": "toys.DeleteToy",
 	})
@@ -77,20 +116,81 @@ func (t *Toys) DeleteToy(ctx context.Context, toyID int64) (toys.Status, string)
 	return opStatus, msg
 }
 
-func (t *Toys) ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string) {
-	// TODO: нужен метод проверяющий админ ли или нет
+func (t *Toys) RestoreToy(ctx context.Context, toyID int64) error {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.RestoreToy",
+	})
+	err := t.toysProvider.RestoreToy(ctx, toyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return status.Error(codes.NotFound, "toy not found")
+		case errors.Is(err, data.ErrToyNotDeleted):
+			return status.Error(codes.FailedPrecondition, err.Error())
+		default:
+			t.log.PrintError(err, map[string]string{
+				"method": "toys.RestoreToy",
+			})
+			return status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	return nil
+}
+
+func (t *Toys) ListDeletedToys(ctx context.Context, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.ListDeletedToys",
+	})
+	toyList, opStatus, msg, metadata := t.toysProvider.ListDeletedToys(ctx, filters)
+
+	if opStatus != toys.Status_STATUS_OK {
+		t.log.PrintError(status.Error(codes.Internal, "internal error"), map[string]string{
+			"method": "toys.ListDeletedToys",
+		})
+
+		return []*data.Toy{}, toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Metadata{}
+	}
+
+	return toyList, opStatus, msg, metadata
+}
+
+func (t *Toys) ListToyHistory(ctx context.Context, toyID int64, filters data.Filters) ([]*data.ToyAuditEntry, toys.Status, string, data.Metadata) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.ListToyHistory",
+	})
+	entries, opStatus, msg, metadata := t.toysProvider.ListToyHistory(ctx, toyID, filters)
+
+	if opStatus != toys.Status_STATUS_OK {
+		t.log.PrintError(status.Error(codes.Internal, "internal error"), map[string]string{
+			"method": "toys.ListToyHistory",
+		})
+
+		return []*data.ToyAuditEntry{}, toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Metadata{}
+	}
+
+	return entries, opStatus, msg, metadata
+}
+
+func (t *Toys) ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string, error) {
 	t.log.PrintInfo("business logic layer", map[string]string{
 		"method": "toys.ChangeToy",
 	})
-	opStatus, msg := t.toysProvider.ChangeToy(ctx, toy)
+	opStatus, msg, err := t.toysProvider.ChangeToy(ctx, toy)
+	switch {
+	case errors.Is(err, data.ErrEditConflict):
+		return toys.Status_STATUS_INTERNAL_ERROR, msg, status.Error(codes.Aborted, "toy was changed since it was read, reload it and try again")
+	case errors.Is(err, data.ErrRecordNotFound):
+		return toys.Status_STATUS_INTERNAL_ERROR, msg, status.Error(codes.NotFound, "toy not found")
+	}
 	if opStatus != toys.Status_STATUS_OK {
 		t.log.PrintError(status.Error(codes.Internal, "internal error"), map[string]string{
 			"method": "toys.ChangeToy",
 		})
 
-		return toys.Status_STATUS_INTERNAL_ERROR, "internal error"
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error", status.Error(codes.Internal, "internal error!")
 	}
-	return opStatus, msg
+	return opStatus, msg, nil
 
 }
 
@@ -110,17 +210,11 @@ func (t *Toys) GetToy(ctx context.Context, toyID int64) (data.Toy, toys.Status,
 	return toy, opStatus, msg
 }
 
-func (t *Toys) ListToy(ctx context.Context, to int64, from int64, filters data.Filters, categories []string, skills []string, title string) ([]*data.Toy, toys.Status, string, data.Metadata) {
+func (t *Toys) ListToy(ctx context.Context, query data.ToyQuery, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
 	t.log.PrintInfo("business logic layer", map[string]string{
 		"method": "toys.ListToy",
 	})
-	if from == 0 {
-		from = 10
-	}
-	if to == 0 {
-		to = 10000000
-	}
-	toyList, opStatus, msg, metadata := t.toysProvider.ListToy(ctx, to, from, filters, categories, skills, title)
+	toyList, opStatus, msg, metadata := t.toysProvider.ListToy(ctx, withValueDefaults(query), filters)
 
 	if opStatus != toys.Status_STATUS_OK {
 		t.log.PrintError(status.Error(codes.Internal, "internal error"), map[string]string{
@@ -134,7 +228,7 @@ func (t *Toys) ListToy(ctx context.Context, to int64, from int64, filters data.F
 
 }
 
-func (t *Toys) ListRecommended(ctx context.Context) ([]*data.Toy, toys.Status, string, data.Metadata) {
+func (t *Toys) ListRecommended(ctx context.Context, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
 	t.log.PrintInfo("business logic layer", map[string]string{
 		"method": "toys.ListRec",
 	})
@@ -143,7 +237,7 @@ func (t *Toys) ListRecommended(ctx context.Context) ([]*data.Toy, toys.Status, s
 		return []*data.Toy{}, toys.Status_STATUS_INTERNAL_ERROR, "internal error", data.Metadata{}
 	}
 
-	toyList, opStatus, msg, metadata := t.toysProvider.ListRecommended(ctx, userId)
+	toyList, opStatus, msg, metadata := t.toysProvider.ListRecommended(ctx, userId, filters)
 	if opStatus != toys.Status_STATUS_OK {
 		t.log.PrintError(status.Error(codes.Internal, "internal error"), map[string]string{
 			"method": "toys.ListRecommended",
@@ -167,6 +261,77 @@ func (t *Toys) GetToysByIds(ctx context.Context, ids []int64) ([]*data.ToySummar
 	return toyList, msg
 }
 
+func (t *Toys) AddToyUnit(ctx context.Context, unit data.ToyUnit) (data.ToyUnit, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.AddToyUnit",
+	})
+	unit, err := t.toysProvider.AddToyUnit(ctx, unit)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return data.ToyUnit{}, status.Error(codes.NotFound, "toy not found")
+		case errors.Is(err, data.ErrDuplicateSerial):
+			return data.ToyUnit{}, status.Error(codes.AlreadyExists, err.Error())
+		default:
+			t.log.PrintError(err, map[string]string{
+				"method": "toys.AddToyUnit",
+			})
+			return data.ToyUnit{}, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	return unit, nil
+}
+
+func (t *Toys) ListToyUnits(ctx context.Context, toyID int64) ([]*data.ToyUnit, error) {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.ListToyUnits",
+	})
+	units, err := t.toysProvider.ListToyUnits(ctx, toyID)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.ListToyUnits",
+		})
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return units, nil
+}
+
+func (t *Toys) ChangeToyUnitStatus(ctx context.Context, unitID int64, unitStatus string) error {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.ChangeToyUnitStatus",
+	})
+	if err := t.toysProvider.ChangeToyUnitStatus(ctx, unitID, unitStatus); err != nil {
+		return t.unitError(err, "toys.ChangeToyUnitStatus")
+	}
+	return nil
+}
+
+func (t *Toys) RetireToyUnit(ctx context.Context, unitID int64) error {
+	t.log.PrintInfo("business logic layer", map[string]string{
+		"method": "toys.RetireToyUnit",
+	})
+	if err := t.toysProvider.RetireToyUnit(ctx, unitID); err != nil {
+		return t.unitError(err, "toys.RetireToyUnit")
+	}
+	return nil
+}
+
+func (t *Toys) unitError(err error, method string) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "unit not found")
+	case errors.Is(err, data.ErrUnitRetired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		t.log.PrintError(err, map[string]string{
+			"method": method,
+		})
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
 func getUserFromContext(ctx context.Context) (int64, error) {
 	val := ctx.Value(contextkeys.UserIDKey)
 	userID, ok := val.(int64)
@@ -177,3 +342,14 @@ func getUserFromContext(ctx context.Context) (int64, error) {
 	return userID, nil
 
 }
+
+// withValueDefaults opens up an unset value range to every toy.
+func withValueDefaults(query data.ToyQuery) data.ToyQuery {
+	if query.From == 0 {
+		query.From = 10
+	}
+	if query.To == 0 {
+		query.To = 10000000
+	}
+	return query
+}
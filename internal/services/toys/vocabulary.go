@@ -0,0 +1,35 @@
+package toys
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"toysService/internal/data"
+)
+
+// Vocabulary loads the category taxonomy, the skills dictionary and the
+// manufacturers that the toys being written are validated against.
+func (t *Toys) Vocabulary(ctx context.Context) (data.Vocabulary, error) {
+	taxonomy, err := t.toysProvider.Taxonomy(ctx)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.Vocabulary",
+		})
+		return data.Vocabulary{}, status.Error(codes.Internal, "internal error")
+	}
+	skills, err := t.toysProvider.SkillDictionary(ctx)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.Vocabulary",
+		})
+		return data.Vocabulary{}, status.Error(codes.Internal, "internal error")
+	}
+	manufacturers, err := t.toysProvider.ManufacturerDirectory(ctx)
+	if err != nil {
+		t.log.PrintError(err, map[string]string{
+			"method": "toys.Vocabulary",
+		})
+		return data.Vocabulary{}, status.Error(codes.Internal, "internal error")
+	}
+	return data.Vocabulary{Categories: taxonomy, Skills: skills, Manufacturers: manufacturers}, nil
+}
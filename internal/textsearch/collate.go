@@ -0,0 +1,33 @@
+package textsearch
+
+import (
+	"cmp"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// collators hands out root-locale collators, which are not safe for
+// concurrent use.
+var collators = sync.Pool{
+	New: func() any { return collate.New(language.Und) },
+}
+
+// Compare orders two texts the way the postgres backend sorts titles: by the
+// Unicode collation of the database locale, so case and accents only break
+// ties ("ball" < "Ball" < "bat") and Ё sorts with Е. Texts the collation
+// finds equal fall back to byte order, as postgres' deterministic
+// collations do.
+func Compare(a, b string) int {
+	c := collators.Get().(*collate.Collator)
+	defer collators.Put(c)
+	return cmp.Or(c.CompareString(a, b), strings.Compare(a, b))
+}
+
+// CompareLists orders two lists element by element with Compare, like
+// postgres compares text arrays.
+func CompareLists(a, b []string) int {
+	return slices.CompareFunc(a, b, Compare)
+}
@@ -0,0 +1,42 @@
+package textsearch
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"ball", "Ball", -1},
+		{"Ball", "bat", -1},
+		{"Äpfel", "apple", -1},
+		{"Zebra", "Аист", -1},
+		{"Аист", "Ёлка", -1},
+		{"Ёлка", "Жук", -1},
+		{"Елка", "Ёлка", -1},
+		{"same", "same", 0},
+	} {
+		if got := Compare(tc.a, tc.b); got != tc.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+		if got := Compare(tc.b, tc.a); got != -tc.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tc.b, tc.a, got, -tc.want)
+		}
+	}
+}
+
+func TestCompareLists(t *testing.T) {
+	for _, tc := range []struct {
+		a, b []string
+		want int
+	}{
+		{[]string{"logic"}, []string{"logic", "motor"}, -1},
+		{[]string{"logic", "motor"}, []string{"logica"}, -1},
+		{[]string{"Motor"}, []string{"motor", "social"}, 1},
+		{[]string{"a", "b"}, []string{"a", "b"}, 0},
+	} {
+		if got := CompareLists(tc.a, tc.b); got != tc.want {
+			t.Errorf("CompareLists(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
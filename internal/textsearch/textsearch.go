@@ -0,0 +1,350 @@
+// Package textsearch approximates the toys_search configuration of the
+// postgres backend for the backends that have no Snowball stemmers: words
+// are lowercased, stop words dropped and the rest cut down to a stem with a
+// light suffix stripper, Cyrillic and Latin words each with their own rules.
+// It is not Snowball, so the odd word form stems differently than in
+// postgres, but "машинки" still finds "машинка" and "cars" finds "car".
+package textsearch
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Weights of the search document parts, as ts_rank_cd weighs the A, B, C and
+// D labels.
+const (
+	WeightTitle        float32 = 1.0
+	WeightManufacturer float32 = 0.4
+	WeightTags         float32 = 0.2
+	WeightDescription  float32 = 0.1
+)
+
+// FuzzyThreshold is the word similarity a fuzzy match needs, as in the
+// postgres backend.
+const FuzzyThreshold = 0.4
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"the": true, "to": true, "with": true,
+	"а": true, "в": true, "во": true, "для": true, "до": true, "за": true, "и": true, "из": true,
+	"к": true, "на": true, "не": true, "о": true, "от": true, "по": true, "с": true, "со": true,
+	"у": true,
+}
+
+var russianEndings = []string{
+	"иями", "ями", "ами", "ией", "иях", "ого", "его", "ому", "ему", "ыми", "ими",
+	"ий", "ый", "ой", "ая", "яя", "ое", "ее", "ые", "ие", "ов", "ев", "ей", "ам", "ям",
+	"ах", "ях", "ом", "ем", "ию", "ью", "ия", "ья", "ую", "юю",
+	"и", "ы", "а", "я", "о", "е", "у", "ю", "ь", "й",
+}
+
+// Word is a word of a text and where it sits, in bytes.
+type Word struct {
+	Text       string
+	Start, End int
+}
+
+// SplitWords splits text into runs of letters and digits.
+func SplitWords(text string) []Word {
+	var words []Word
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			words = append(words, Word{text[start:i], start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, Word{text[start:], start, len(text)})
+	}
+	return words
+}
+
+// Lexeme normalizes a word the way documents and queries are indexed. Stop
+// words yield "".
+func Lexeme(w string) string {
+	w = strings.ToLower(w)
+	if stopWords[w] {
+		return ""
+	}
+	for _, r := range w {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return stemRussian(w)
+		}
+	}
+	return stemEnglish(w)
+}
+
+// Lexemes returns the lexemes of every word of text, in order.
+func Lexemes(text string) []string {
+	var lexemes []string
+	for _, w := range SplitWords(text) {
+		if l := Lexeme(w.Text); l != "" {
+			lexemes = append(lexemes, l)
+		}
+	}
+	return lexemes
+}
+
+func stemRussian(w string) string {
+	w = strings.ReplaceAll(w, "ё", "е")
+	for _, ending := range russianEndings {
+		if stem, ok := strings.CutSuffix(w, ending); ok && utf8.RuneCountInString(stem) >= 3 {
+			return stem
+		}
+	}
+	return w
+}
+
+func stemEnglish(w string) string {
+	w = strings.TrimSuffix(w, "'s")
+	switch {
+	case strings.HasSuffix(w, "sses"):
+		w = strings.TrimSuffix(w, "es")
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		w = strings.TrimSuffix(w, "es")
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us") && len(w) > 3:
+		w = strings.TrimSuffix(w, "s")
+	}
+	for _, suffix := range []string{"ing", "ed", "ly"} {
+		if stem, ok := strings.CutSuffix(w, suffix); ok && len(stem) >= 3 && strings.ContainsAny(stem, "aeiouy") {
+			return stem
+		}
+	}
+	return w
+}
+
+// posting is one indexed word of a search document.
+type posting struct {
+	lexeme string
+	weight float32
+	pos    int
+}
+
+// Document is the search document of a toy: its title, manufacturer name,
+// category names and skills, and description, in that order.
+type Document []posting
+
+// Add indexes text with weight. Like concatenated tsvectors, every part
+// continues the positions of the previous one.
+func (d Document) Add(text string, weight float32) Document {
+	base := 0
+	if len(d) > 0 {
+		base = d[len(d)-1].pos + 1
+	}
+	for i, w := range SplitWords(text) {
+		if l := Lexeme(w.Text); l != "" {
+			d = append(d, posting{l, weight, base + i})
+		}
+	}
+	return d
+}
+
+// Atom is a word or quoted phrase of a search, possibly negated.
+type Atom struct {
+	Lexemes []string
+	Negated bool
+}
+
+// Query is a parsed search in disjunctive form: it matches a document if all
+// atoms of any one clause do.
+type Query [][]Atom
+
+// Parse reads search text with the websearch syntax of postgres: words are
+// ANDed, "quoted phrases" must appear in order, a leading minus negates a
+// word or phrase and "or" separates alternatives.
+func Parse(text string) Query {
+	var query Query
+	var clause []Atom
+	flush := func() {
+		if len(clause) > 0 {
+			query = append(query, clause)
+			clause = nil
+		}
+	}
+	add := func(raw string, negated bool) {
+		atom := Atom{Lexemes: Lexemes(raw), Negated: negated}
+		if len(atom.Lexemes) > 0 {
+			clause = append(clause, atom)
+		}
+	}
+
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		negated := false
+		if rest, ok := strings.CutPrefix(text, "-"); ok {
+			negated, text = true, rest
+		}
+		if rest, ok := strings.CutPrefix(text, `"`); ok {
+			phrase, after, _ := strings.Cut(rest, `"`)
+			add(phrase, negated)
+			text = after
+			continue
+		}
+
+		token, after, _ := strings.Cut(text, " ")
+		text = after
+		if !negated && strings.EqualFold(token, "or") && len(clause) > 0 {
+			flush()
+			continue
+		}
+		add(token, negated)
+	}
+	flush()
+	return query
+}
+
+// phraseAt reports whether the lexemes appear in the document in order
+// starting at position pos.
+func (d Document) phraseAt(lexemes []string, pos int) bool {
+	for i, l := range lexemes[1:] {
+		found := false
+		for _, p := range d {
+			if p.pos == pos+i+1 && p.lexeme == l {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// atomRank sums the weights of the atom's occurrences in the document; zero
+// means it does not occur.
+func (d Document) atomRank(atom Atom) float32 {
+	var rank float32
+	for _, p := range d {
+		if p.lexeme == atom.Lexemes[0] && d.phraseAt(atom.Lexemes, p.pos) {
+			rank += p.weight
+		}
+	}
+	return rank
+}
+
+// Match reports whether the document matches the query and ranks it. The
+// rank adds up the weights of every matched occurrence, which orders results
+// much like ts_rank_cd without its proximity bonus.
+func (d Document) Match(query Query) (float32, bool) {
+	var best float32
+	matched := false
+	for _, clause := range query {
+		var rank float32
+		ok := true
+		for _, atom := range clause {
+			r := d.atomRank(atom)
+			if atom.Negated {
+				ok = r == 0
+			} else {
+				ok, rank = r > 0, rank+r
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			matched = true
+			best = max(best, rank)
+		}
+	}
+	return best, matched
+}
+
+// searchedLexemes lists the lexemes a snippet highlights.
+func (query Query) searchedLexemes() map[string]bool {
+	lexemes := map[string]bool{}
+	for _, clause := range query {
+		for _, atom := range clause {
+			if !atom.Negated {
+				for _, l := range atom.Lexemes {
+					lexemes[l] = true
+				}
+			}
+		}
+	}
+	return lexemes
+}
+
+// snippetWords is the most words a snippet shows, as MaxWords of the
+// postgres headline.
+const snippetWords = 25
+
+// Snippet highlights the searched words of text with <mark> tags, showing
+// at most snippetWords words starting a little before the first match.
+func Snippet(text string, query Query) string {
+	lexemes := query.searchedLexemes()
+	words := SplitWords(text)
+	if len(words) == 0 {
+		return text
+	}
+
+	first, last := 0, len(words)-1
+	if len(words) > snippetWords {
+		for i, w := range words {
+			if lexemes[Lexeme(w.Text)] {
+				first = max(0, min(i-3, len(words)-snippetWords))
+				break
+			}
+		}
+		last = first + snippetWords - 1
+	}
+
+	var b strings.Builder
+	if first > 0 {
+		b.WriteString("… ")
+	}
+	at := words[first].Start
+	for _, w := range words[first : last+1] {
+		b.WriteString(text[at:w.Start])
+		if lexemes[Lexeme(w.Text)] {
+			b.WriteString("<mark>" + w.Text + "</mark>")
+		} else {
+			b.WriteString(w.Text)
+		}
+		at = w.End
+	}
+	if last < len(words)-1 {
+		b.WriteString(" …")
+	} else {
+		b.WriteString(text[at:])
+	}
+	return b.String()
+}
+
+// trigrams returns the pg_trgm trigrams of text: every word is lowercased and
+// padded with two spaces in front and one behind.
+func trigrams(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range SplitWords(text) {
+		runes := []rune("  " + strings.ToLower(w.Text) + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// WordSimilarity approximates the pg_trgm word_similarity of search and
+// text: the share of the search's trigrams that also occur in text.
+func WordSimilarity(search, text string) float32 {
+	want := trigrams(search)
+	if len(want) == 0 {
+		return 0
+	}
+	have := trigrams(text)
+	shared := 0
+	for t := range want {
+		if have[t] {
+			shared++
+		}
+	}
+	return float32(shared) / float32(len(want))
+}
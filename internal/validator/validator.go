@@ -35,9 +35,12 @@ func PermittedValue[T comparable](value T, permittedValues ...T) bool {
 	return false
 }
 
+// ImageUrlsCheck accepts absolute https URLs and the root-relative paths the
+// gateway serves uploaded images under.
 func (v *Validator) ImageUrlsCheck(images []string) bool {
 	for i := range images {
-		if !strings.HasPrefix(images[i], "https://") {
+		relative := strings.HasPrefix(images[i], "/") && !strings.HasPrefix(images[i], "//")
+		if !strings.HasPrefix(images[i], "https://") && !relative {
 			return false
 		}
 	}
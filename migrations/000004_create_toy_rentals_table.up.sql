@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS toy_rentals (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    toy_id bigint NOT NULL REFERENCES toys ON DELETE CASCADE,
+    rented_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    returned_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS toy_rentals_user_id_idx ON toy_rentals (user_id);
+CREATE INDEX IF NOT EXISTS toy_rentals_toy_id_idx ON toy_rentals (toy_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS toy_units (
+    id bigserial PRIMARY KEY,
+    toy_id bigint NOT NULL REFERENCES toys ON DELETE CASCADE,
+    serial text NOT NULL UNIQUE,
+    condition text NOT NULL,
+    location text NOT NULL,
+    status text NOT NULL DEFAULT 'available',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    retired_at timestamp(0) with time zone
+);
+
+ALTER TABLE toy_units ADD CONSTRAINT toy_units_condition_check CHECK (condition IN ('new', 'good', 'fair', 'poor'));
+ALTER TABLE toy_units ADD CONSTRAINT toy_units_status_check CHECK (status IN ('available', 'rented', 'reserved', 'maintenance', 'retired'));
+
+CREATE INDEX IF NOT EXISTS toy_units_toy_id_status_idx ON toy_units (toy_id, status);
@@ -0,0 +1,18 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS toy_reservations (
+    id bigserial PRIMARY KEY,
+    toy_id bigint NOT NULL REFERENCES toys ON DELETE CASCADE,
+    unit_id bigint NOT NULL REFERENCES toy_units ON DELETE CASCADE,
+    user_id bigint NOT NULL,
+    period tstzrange NOT NULL,
+    status text NOT NULL DEFAULT 'active',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+ALTER TABLE toy_reservations ADD CONSTRAINT toy_reservations_status_check CHECK (status IN ('active', 'cancelled'));
+ALTER TABLE toy_reservations ADD CONSTRAINT toy_reservations_period_check CHECK (NOT isempty(period));
+ALTER TABLE toy_reservations ADD CONSTRAINT toy_reservations_no_overlap
+    EXCLUDE USING gist (unit_id WITH =, period WITH &&) WHERE (status = 'active');
+
+CREATE INDEX IF NOT EXISTS toy_reservations_user_id_idx ON toy_reservations (user_id);
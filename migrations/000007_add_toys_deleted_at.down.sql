@@ -0,0 +1 @@
+ALTER TABLE toys DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS toy_audit (
+    id bigserial PRIMARY KEY,
+    toy_id bigint NOT NULL REFERENCES toys ON DELETE CASCADE,
+    user_id bigint,
+    action text NOT NULL,
+    changes jsonb NOT NULL DEFAULT '[]',
+    changed_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS toy_audit_toy_id_changed_at_idx ON toy_audit (toy_id, changed_at DESC);
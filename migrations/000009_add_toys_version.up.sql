@@ -0,0 +1 @@
+ALTER TABLE toys ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
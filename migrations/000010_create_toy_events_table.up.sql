@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS toy_events (
+    id bigserial PRIMARY KEY,
+    toy_id bigint NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    published_at timestamp(0) with time zone,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    last_error text
+);
+
+CREATE INDEX IF NOT EXISTS toy_events_pending_idx ON toy_events (toy_id, id) WHERE published_at IS NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE toys DROP CONSTRAINT IF EXISTS toys_skills_length_check;
+ALTER TABLE toys DROP CONSTRAINT IF EXISTS toys_categories_length_check;
+ALTER TABLE toys ADD CONSTRAINT toys_skills_length_check CHECK (array_length(skills, 1) BETWEEN 1 AND 5);
+ALTER TABLE toys ADD CONSTRAINT toys_categories_length_check CHECK (array_length(categories, 1) BETWEEN 1 AND 5);
+
+ALTER TABLE toys ALTER COLUMN is_available DROP DEFAULT;
+ALTER TABLE toys ALTER COLUMN is_available TYPE text USING is_available::text;
@@ -0,0 +1,7 @@
+ALTER TABLE toys ALTER COLUMN is_available TYPE boolean USING (lower(trim(is_available)) IN ('t', 'true', '1', 'yes'));
+ALTER TABLE toys ALTER COLUMN is_available SET DEFAULT false;
+
+ALTER TABLE toys DROP CONSTRAINT IF EXISTS toys_skills_length_check;
+ALTER TABLE toys DROP CONSTRAINT IF EXISTS toys_categories_length_check;
+ALTER TABLE toys ADD CONSTRAINT toys_skills_length_check CHECK (array_length(skills, 1) BETWEEN 1 AND 7);
+ALTER TABLE toys ADD CONSTRAINT toys_categories_length_check CHECK (array_length(categories, 1) BETWEEN 1 AND 7);
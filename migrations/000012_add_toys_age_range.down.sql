@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS toys_age_range_idx;
+
+ALTER TABLE toys DROP CONSTRAINT IF EXISTS toys_age_range_check;
+
+ALTER TABLE toys DROP COLUMN IF EXISTS max_age_months;
+ALTER TABLE toys DROP COLUMN IF EXISTS min_age_months;
@@ -0,0 +1,6 @@
+ALTER TABLE toys ADD COLUMN IF NOT EXISTS min_age_months integer NOT NULL DEFAULT 0;
+ALTER TABLE toys ADD COLUMN IF NOT EXISTS max_age_months integer;
+
+ALTER TABLE toys ADD CONSTRAINT toys_age_range_check CHECK (min_age_months >= 0 AND (max_age_months IS NULL OR max_age_months >= min_age_months));
+
+CREATE INDEX IF NOT EXISTS toys_age_range_idx ON toys (min_age_months, max_age_months);
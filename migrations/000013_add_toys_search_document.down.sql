@@ -0,0 +1,10 @@
+CREATE INDEX IF NOT EXISTS toys_title_idx ON toys USING GIN (to_tsvector('simple', title));
+DROP INDEX IF EXISTS toys_search_document_idx;
+
+DROP TRIGGER IF EXISTS toys_search_document_update ON toys;
+DROP FUNCTION IF EXISTS toys_search_document_trigger();
+DROP FUNCTION IF EXISTS toys_search_document(text, text, text, text[], text[]);
+
+ALTER TABLE toys DROP COLUMN IF EXISTS search_document;
+
+DROP TEXT SEARCH CONFIGURATION IF EXISTS toys_search;
@@ -0,0 +1,39 @@
+-- toys_search stems Cyrillic words with russian_stem and Latin ones with
+-- english_stem, so "машинки" finds "машинка" and "cars" finds "car".
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'toys_search') THEN
+        CREATE TEXT SEARCH CONFIGURATION toys_search (COPY = pg_catalog.russian);
+    END IF;
+END
+$$;
+ALTER TEXT SEARCH CONFIGURATION toys_search ALTER MAPPING FOR asciiword, asciihword, hword_asciipart WITH english_stem;
+ALTER TEXT SEARCH CONFIGURATION toys_search ALTER MAPPING FOR word, hword, hword_part WITH russian_stem;
+
+ALTER TABLE toys ADD COLUMN IF NOT EXISTS search_document tsvector;
+
+CREATE OR REPLACE FUNCTION toys_search_document(title text, description text, manufacturer text, categories text[], skills text[])
+RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('toys_search', coalesce(title, '')), 'A')
+        || setweight(to_tsvector('toys_search', coalesce(manufacturer, '')), 'B')
+        || setweight(to_tsvector('toys_search', array_to_string(coalesce(categories, '{}'), ' ')), 'C')
+        || setweight(to_tsvector('toys_search', array_to_string(coalesce(skills, '{}'), ' ')), 'C')
+        || setweight(to_tsvector('toys_search', coalesce(description, '')), 'D');
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION toys_search_document_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_document := toys_search_document(NEW.title, NEW.description, NEW.manufacturer, NEW.categories, NEW.skills);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS toys_search_document_update ON toys;
+CREATE TRIGGER toys_search_document_update
+    BEFORE INSERT OR UPDATE OF title, description, manufacturer, categories, skills ON toys
+    FOR EACH ROW EXECUTE FUNCTION toys_search_document_trigger();
+
+UPDATE toys SET search_document = toys_search_document(title, description, manufacturer, categories, skills);
+
+CREATE INDEX IF NOT EXISTS toys_search_document_idx ON toys USING GIN (search_document);
+DROP INDEX IF EXISTS toys_title_idx;
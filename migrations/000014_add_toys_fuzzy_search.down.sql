@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS toy_search_terms_update ON toys;
+DROP FUNCTION IF EXISTS toy_search_terms_trigger();
+DROP FUNCTION IF EXISTS toy_search_terms_of(text, text, text[]);
+
+DROP TABLE IF EXISTS toy_search_terms;
+
+DROP INDEX IF EXISTS toys_manufacturer_trgm_idx;
+DROP INDEX IF EXISTS toys_title_trgm_idx;
@@ -0,0 +1,59 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS toys_title_trgm_idx ON toys USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS toys_manufacturer_trgm_idx ON toys USING GIN (manufacturer gin_trgm_ops);
+
+-- toy_search_terms holds every distinct title, category and manufacturer of
+-- live toys with the number of toys using it, so autocomplete is a single
+-- index range scan instead of a pass over the catalogue.
+CREATE TABLE IF NOT EXISTS toy_search_terms (
+    kind text NOT NULL,
+    term text NOT NULL,
+    toys_count integer NOT NULL,
+    PRIMARY KEY (kind, term)
+);
+
+CREATE INDEX IF NOT EXISTS toy_search_terms_prefix_idx ON toy_search_terms (lower(term) text_pattern_ops);
+
+CREATE OR REPLACE FUNCTION toy_search_terms_of(title text, manufacturer text, categories text[])
+RETURNS TABLE (kind text, term text) AS $$
+    SELECT 'title', title WHERE coalesce(title, '') <> ''
+    UNION
+    SELECT 'manufacturer', manufacturer WHERE coalesce(manufacturer, '') <> ''
+    UNION
+    SELECT 'category', c FROM unnest(categories) AS c WHERE c <> ''
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION toy_search_terms_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.deleted_at IS NULL THEN
+        UPDATE toy_search_terms s SET toys_count = s.toys_count - 1
+        FROM toy_search_terms_of(OLD.title, OLD.manufacturer, OLD.categories) t
+        WHERE s.kind = t.kind AND s.term = t.term;
+
+        DELETE FROM toy_search_terms s
+        USING toy_search_terms_of(OLD.title, OLD.manufacturer, OLD.categories) t
+        WHERE s.kind = t.kind AND s.term = t.term AND s.toys_count <= 0;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        INSERT INTO toy_search_terms (kind, term, toys_count)
+        SELECT t.kind, t.term, 1 FROM toy_search_terms_of(NEW.title, NEW.manufacturer, NEW.categories) t
+        ON CONFLICT ON CONSTRAINT toy_search_terms_pkey DO UPDATE SET toys_count = toy_search_terms.toys_count + 1;
+    END IF;
+
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS toy_search_terms_update ON toys;
+CREATE TRIGGER toy_search_terms_update
+    AFTER INSERT OR DELETE OR UPDATE OF title, manufacturer, categories, deleted_at ON toys
+    FOR EACH ROW EXECUTE FUNCTION toy_search_terms_trigger();
+
+INSERT INTO toy_search_terms (kind, term, toys_count)
+SELECT t.kind, t.term, count(*)
+FROM toys, toy_search_terms_of(toys.title, toys.manufacturer, toys.categories) t
+WHERE toys.deleted_at IS NULL
+GROUP BY t.kind, t.term
+ON CONFLICT ON CONSTRAINT toy_search_terms_pkey DO NOTHING;
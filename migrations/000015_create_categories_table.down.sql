@@ -0,0 +1,39 @@
+UPDATE toys SET categories = ARRAY(
+    SELECT coalesce(c.names->>'ru', c.names->>'en', s.slug)
+    FROM unnest(toys.categories) WITH ORDINALITY AS s(slug, n)
+    LEFT JOIN categories c ON c.slug = s.slug
+    ORDER BY s.n
+);
+
+CREATE OR REPLACE FUNCTION toy_search_terms_of(title text, manufacturer text, categories text[])
+RETURNS TABLE (kind text, term text) AS $$
+    SELECT 'title', title WHERE coalesce(title, '') <> ''
+    UNION
+    SELECT 'manufacturer', manufacturer WHERE coalesce(manufacturer, '') <> ''
+    UNION
+    SELECT 'category', c FROM unnest(categories) AS c WHERE c <> ''
+$$ LANGUAGE sql IMMUTABLE;
+
+INSERT INTO toy_search_terms (kind, term, toys_count)
+SELECT 'category', c, count(*)
+FROM toys, unnest(toys.categories) AS c
+WHERE toys.deleted_at IS NULL AND c <> ''
+GROUP BY c
+ON CONFLICT ON CONSTRAINT toy_search_terms_pkey DO NOTHING;
+
+CREATE OR REPLACE FUNCTION toys_search_document(title text, description text, manufacturer text, categories text[], skills text[])
+RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('toys_search', coalesce(title, '')), 'A')
+        || setweight(to_tsvector('toys_search', coalesce(manufacturer, '')), 'B')
+        || setweight(to_tsvector('toys_search', array_to_string(coalesce(categories, '{}'), ' ')), 'C')
+        || setweight(to_tsvector('toys_search', array_to_string(coalesce(skills, '{}'), ' ')), 'C')
+        || setweight(to_tsvector('toys_search', coalesce(description, '')), 'D');
+$$ LANGUAGE sql STABLE;
+
+UPDATE toys SET search_document = toys_search_document(title, description, manufacturer, categories, skills);
+
+DROP TRIGGER IF EXISTS categories_names_update ON categories;
+DROP FUNCTION IF EXISTS categories_names_trigger();
+DROP FUNCTION IF EXISTS category_subtree(text);
+
+DROP TABLE IF EXISTS categories;
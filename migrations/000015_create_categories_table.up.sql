@@ -0,0 +1,60 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id bigserial PRIMARY KEY,
+    slug text NOT NULL UNIQUE CHECK (slug ~ '^[a-z0-9]+(-[a-z0-9]+)*$'),
+    parent_id bigint REFERENCES categories (id) ON DELETE RESTRICT,
+    names jsonb NOT NULL CHECK (jsonb_typeof(names) = 'object' AND names <> '{}'::jsonb),
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    CHECK (parent_id <> id)
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+
+-- category_subtree returns the slug and the slugs of all its descendants,
+-- so filtering by a parent category also finds toys filed under its children.
+CREATE OR REPLACE FUNCTION category_subtree(root text) RETURNS text[] AS $$
+    WITH RECURSIVE tree AS (
+        SELECT id, slug FROM categories WHERE slug = root
+        UNION
+        SELECT c.id, c.slug FROM categories c JOIN tree ON c.parent_id = tree.id
+    )
+    SELECT coalesce(array_agg(slug), ARRAY[root]) FROM tree
+$$ LANGUAGE sql STABLE;
+
+-- toys.categories now holds slugs, so the search document indexes the
+-- category names instead.
+CREATE OR REPLACE FUNCTION toys_search_document(title text, description text, manufacturer text, categories text[], skills text[])
+RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('toys_search', coalesce(title, '')), 'A')
+        || setweight(to_tsvector('toys_search', coalesce(manufacturer, '')), 'B')
+        || setweight(to_tsvector('toys_search', coalesce((
+               SELECT string_agg(n.value, ' ')
+               FROM categories c, jsonb_each_text(c.names) n
+               WHERE c.slug = ANY (toys_search_document.categories)), '')), 'C')
+        || setweight(to_tsvector('toys_search', array_to_string(coalesce(skills, '{}'), ' ')), 'C')
+        || setweight(to_tsvector('toys_search', coalesce(description, '')), 'D');
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION categories_names_trigger() RETURNS trigger AS $$
+BEGIN
+    UPDATE toys SET search_document = toys_search_document(title, description, manufacturer, categories, skills)
+    WHERE categories @> ARRAY[NEW.slug];
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS categories_names_update ON categories;
+CREATE TRIGGER categories_names_update
+    AFTER UPDATE OF names ON categories
+    FOR EACH ROW EXECUTE FUNCTION categories_names_trigger();
+
+-- Autocomplete looks categories up in the categories table by their
+-- localized names, so toy_search_terms drops them and keeps titles and
+-- manufacturer names; 000017 later cuts it down to titles.
+CREATE OR REPLACE FUNCTION toy_search_terms_of(title text, manufacturer text, categories text[])
+RETURNS TABLE (kind text, term text) AS $$
+    SELECT 'title', title WHERE coalesce(title, '') <> ''
+    UNION
+    SELECT 'manufacturer', manufacturer WHERE coalesce(manufacturer, '') <> ''
+$$ LANGUAGE sql IMMUTABLE;
+
+DELETE FROM toy_search_terms WHERE kind = 'category';
@@ -0,0 +1,4 @@
+DROP FUNCTION IF EXISTS skill_variants(text);
+
+DROP TABLE IF EXISTS skill_synonyms;
+DROP TABLE IF EXISTS skills;
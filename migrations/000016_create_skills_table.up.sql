@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS skills (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL CHECK (name <> ''),
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS skills_name_idx ON skills (lower(name));
+
+CREATE TABLE IF NOT EXISTS skill_synonyms (
+    skill_id bigint NOT NULL REFERENCES skills (id) ON DELETE CASCADE,
+    synonym text NOT NULL CHECK (synonym <> '')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS skill_synonyms_synonym_idx ON skill_synonyms (lower(synonym));
+CREATE INDEX IF NOT EXISTS skill_synonyms_skill_id_idx ON skill_synonyms (skill_id);
+
+-- skill_variants returns the canonical name and every synonym of the skill
+-- that term names, so filtering by "fine motor" also finds toys tagged
+-- "Мелкая моторика". Unknown terms only match themselves.
+CREATE OR REPLACE FUNCTION skill_variants(term text) RETURNS text[] AS $$
+    WITH skill AS (
+        SELECT id, name FROM skills WHERE lower(name) = lower(term)
+        UNION
+        SELECT s.id, s.name
+        FROM skills s JOIN skill_synonyms sy ON sy.skill_id = s.id
+        WHERE lower(sy.synonym) = lower(term)
+    )
+    SELECT coalesce((
+        SELECT array_agg(v)
+        FROM (
+            SELECT name FROM skill
+            UNION
+            SELECT sy.synonym FROM skill_synonyms sy JOIN skill ON sy.skill_id = skill.id
+        ) AS variants(v)
+    ), ARRAY[term])
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,69 @@
+ALTER TABLE toys ADD COLUMN IF NOT EXISTS manufacturer text;
+
+UPDATE toys SET manufacturer = m.name
+FROM manufacturers m
+WHERE m.id = toys.manufacturer_id;
+
+CREATE INDEX IF NOT EXISTS toys_manufacturer_trgm_idx ON toys USING GIN (manufacturer gin_trgm_ops);
+
+CREATE OR REPLACE FUNCTION toy_search_terms_of(title text, manufacturer text, categories text[])
+RETURNS TABLE (kind text, term text) AS $$
+    SELECT 'title', title WHERE coalesce(title, '') <> ''
+    UNION
+    SELECT 'manufacturer', manufacturer WHERE coalesce(manufacturer, '') <> ''
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION toy_search_terms_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.deleted_at IS NULL THEN
+        UPDATE toy_search_terms s SET toys_count = s.toys_count - 1
+        FROM toy_search_terms_of(OLD.title, OLD.manufacturer, OLD.categories) t
+        WHERE s.kind = t.kind AND s.term = t.term;
+
+        DELETE FROM toy_search_terms s
+        USING toy_search_terms_of(OLD.title, OLD.manufacturer, OLD.categories) t
+        WHERE s.kind = t.kind AND s.term = t.term AND s.toys_count <= 0;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        INSERT INTO toy_search_terms (kind, term, toys_count)
+        SELECT t.kind, t.term, 1 FROM toy_search_terms_of(NEW.title, NEW.manufacturer, NEW.categories) t
+        ON CONFLICT ON CONSTRAINT toy_search_terms_pkey DO UPDATE SET toys_count = toy_search_terms.toys_count + 1;
+    END IF;
+
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS toy_search_terms_update ON toys;
+CREATE TRIGGER toy_search_terms_update
+    AFTER INSERT OR DELETE OR UPDATE OF title, manufacturer, categories, deleted_at ON toys
+    FOR EACH ROW EXECUTE FUNCTION toy_search_terms_trigger();
+
+INSERT INTO toy_search_terms (kind, term, toys_count)
+SELECT 'manufacturer', manufacturer, count(*)
+FROM toys
+WHERE deleted_at IS NULL AND coalesce(manufacturer, '') <> ''
+GROUP BY manufacturer
+ON CONFLICT ON CONSTRAINT toy_search_terms_pkey DO NOTHING;
+
+CREATE OR REPLACE FUNCTION toys_search_document_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_document := toys_search_document(NEW.title, NEW.description, NEW.manufacturer, NEW.categories, NEW.skills);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS toys_search_document_update ON toys;
+CREATE TRIGGER toys_search_document_update
+    BEFORE INSERT OR UPDATE OF title, description, manufacturer, categories, skills ON toys
+    FOR EACH ROW EXECUTE FUNCTION toys_search_document_trigger();
+
+DROP TRIGGER IF EXISTS manufacturers_name_update ON manufacturers;
+DROP FUNCTION IF EXISTS manufacturers_name_trigger();
+
+DROP INDEX IF EXISTS toys_manufacturer_id_idx;
+ALTER TABLE toys DROP COLUMN IF EXISTS manufacturer_id;
+
+DROP TABLE IF EXISTS manufacturers;
+DROP FUNCTION IF EXISTS manufacturer_key(text);
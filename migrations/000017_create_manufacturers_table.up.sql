@@ -0,0 +1,103 @@
+-- manufacturer_key is what makes two manufacturer names the same brand:
+-- " LEGO ", "Lego" and "lego" all map to "lego".
+CREATE OR REPLACE FUNCTION manufacturer_key(name text) RETURNS text AS $$
+    SELECT lower(regexp_replace(btrim(name), '\s+', ' ', 'g'))
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE TABLE IF NOT EXISTS manufacturers (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL CHECK (btrim(name) <> ''),
+    country text NOT NULL DEFAULT '',
+    logo_url text NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS manufacturers_name_idx ON manufacturers (manufacturer_key(name));
+CREATE INDEX IF NOT EXISTS manufacturers_name_trgm_idx ON manufacturers USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS manufacturers_name_prefix_idx ON manufacturers (lower(name) text_pattern_ops);
+
+-- Every brand is named after its most used spelling.
+INSERT INTO manufacturers (name)
+SELECT DISTINCT ON (manufacturer_key(name)) name
+FROM (
+    SELECT regexp_replace(btrim(manufacturer), '\s+', ' ', 'g') AS name, count(*) AS toys_count
+    FROM toys
+    WHERE btrim(coalesce(manufacturer, '')) <> ''
+    GROUP BY 1
+) AS spellings
+ORDER BY manufacturer_key(name), toys_count DESC, name;
+
+ALTER TABLE toys ADD COLUMN IF NOT EXISTS manufacturer_id bigint REFERENCES manufacturers (id) ON DELETE RESTRICT;
+
+UPDATE toys SET manufacturer_id = m.id
+FROM manufacturers m
+WHERE manufacturer_key(toys.manufacturer) = manufacturer_key(m.name);
+
+CREATE INDEX IF NOT EXISTS toys_manufacturer_id_idx ON toys (manufacturer_id);
+
+-- The search document indexes the brand name through the reference.
+CREATE OR REPLACE FUNCTION toys_search_document_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_document := toys_search_document(NEW.title, NEW.description,
+        (SELECT name FROM manufacturers WHERE id = NEW.manufacturer_id), NEW.categories, NEW.skills);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS toys_search_document_update ON toys;
+CREATE TRIGGER toys_search_document_update
+    BEFORE INSERT OR UPDATE OF title, description, manufacturer_id, categories, skills ON toys
+    FOR EACH ROW EXECUTE FUNCTION toys_search_document_trigger();
+
+CREATE OR REPLACE FUNCTION manufacturers_name_trigger() RETURNS trigger AS $$
+BEGIN
+    UPDATE toys SET search_document = toys_search_document(title, description, NEW.name, categories, skills)
+    WHERE manufacturer_id = NEW.id;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS manufacturers_name_update ON manufacturers;
+CREATE TRIGGER manufacturers_name_update
+    AFTER UPDATE OF name ON manufacturers
+    FOR EACH ROW EXECUTE FUNCTION manufacturers_name_trigger();
+
+-- Autocomplete suggests brands straight from the manufacturers table, so
+-- toy_search_terms is down to titles.
+CREATE OR REPLACE FUNCTION toy_search_terms_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.deleted_at IS NULL THEN
+        UPDATE toy_search_terms s SET toys_count = s.toys_count - 1
+        FROM toy_search_terms_of(OLD.title, NULL, NULL) t
+        WHERE s.kind = t.kind AND s.term = t.term;
+
+        DELETE FROM toy_search_terms s
+        USING toy_search_terms_of(OLD.title, NULL, NULL) t
+        WHERE s.kind = t.kind AND s.term = t.term AND s.toys_count <= 0;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        INSERT INTO toy_search_terms (kind, term, toys_count)
+        SELECT t.kind, t.term, 1 FROM toy_search_terms_of(NEW.title, NULL, NULL) t
+        ON CONFLICT ON CONSTRAINT toy_search_terms_pkey DO UPDATE SET toys_count = toy_search_terms.toys_count + 1;
+    END IF;
+
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION toy_search_terms_of(title text, manufacturer text, categories text[])
+RETURNS TABLE (kind text, term text) AS $$
+    SELECT 'title', title WHERE coalesce(title, '') <> ''
+$$ LANGUAGE sql IMMUTABLE;
+
+DROP TRIGGER IF EXISTS toy_search_terms_update ON toys;
+CREATE TRIGGER toy_search_terms_update
+    AFTER INSERT OR DELETE OR UPDATE OF title, deleted_at ON toys
+    FOR EACH ROW EXECUTE FUNCTION toy_search_terms_trigger();
+
+DELETE FROM toy_search_terms WHERE kind = 'manufacturer';
+
+DROP INDEX IF EXISTS toys_manufacturer_trgm_idx;
+ALTER TABLE toys DROP COLUMN IF EXISTS manufacturer;
@@ -0,0 +1,22 @@
+-- toy_images keeps uploaded images. An upload starts out unattached
+-- (toy_id IS NULL) until it is attached to a toy with its order, alt text and
+-- primary flag.
+CREATE TABLE IF NOT EXISTS toy_images (
+    id bigserial PRIMARY KEY,
+    toy_id bigint REFERENCES toys ON DELETE SET NULL,
+    blob_key text NOT NULL UNIQUE,
+    thumbnail_key text NOT NULL,
+    url text NOT NULL,
+    thumbnail_url text NOT NULL,
+    content_type text NOT NULL,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    size_bytes bigint NOT NULL,
+    alt text NOT NULL DEFAULT '',
+    position integer NOT NULL DEFAULT 0,
+    is_primary boolean NOT NULL DEFAULT false,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS toy_images_toy_id_idx ON toy_images (toy_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS toy_images_primary_idx ON toy_images (toy_id) WHERE is_primary;
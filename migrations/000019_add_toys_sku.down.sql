@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS toys_sku_idx;
+
+ALTER TABLE toys DROP COLUMN IF EXISTS sku;
@@ -0,0 +1,4 @@
+ALTER TABLE toys ADD COLUMN sku text;
+
+-- SKUs are the external identifiers catalog imports match toys by.
+CREATE UNIQUE INDEX toys_sku_idx ON toys (sku) WHERE sku IS NOT NULL;
@@ -0,0 +1,3 @@
+DELETE FROM toy_rentals WHERE reservation_id IS NOT NULL;
+
+ALTER TABLE toy_rentals DROP COLUMN IF EXISTS reservation_id;
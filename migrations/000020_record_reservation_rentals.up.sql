@@ -0,0 +1,8 @@
+-- Every reservation is recorded as a rental of its toy for the reserved
+-- period; cancelling the reservation withdraws the rental.
+ALTER TABLE toy_rentals ADD COLUMN reservation_id bigint UNIQUE REFERENCES toy_reservations ON DELETE CASCADE;
+
+INSERT INTO toy_rentals (user_id, toy_id, reservation_id, rented_at, returned_at)
+SELECT user_id, toy_id, id, lower(period), upper(period)
+FROM toy_reservations
+WHERE status = 'active';
@@ -0,0 +1,4 @@
+-- Toys that have more than 5 images or none by now keep them; the old limit
+-- only applies to later writes.
+ALTER TABLE toys DROP CONSTRAINT IF EXISTS toys_images_length_check;
+ALTER TABLE toys ADD CONSTRAINT toys_images_length_check CHECK (array_length(images, 1) BETWEEN 1 AND 5) NOT VALID;
@@ -0,0 +1,3 @@
+-- A toy holds up to 10 images, and none until its first upload is attached.
+ALTER TABLE toys DROP CONSTRAINT IF EXISTS toys_images_length_check;
+ALTER TABLE toys ADD CONSTRAINT toys_images_length_check CHECK (array_length(images, 1) <= 10);
@@ -0,0 +1,8 @@
+-- Cursor pages of ListToy continue from the last (sort key, id) pair. These
+-- indexes cover every sort key in live toys' order, under the same default
+-- collation ORDER BY uses, so a page starts with an index seek.
+CREATE INDEX IF NOT EXISTS toys_title_id_idx ON toys (title, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS toys_value_id_idx ON toys (value, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS toys_min_age_months_id_idx ON toys (min_age_months, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS toys_skills_id_idx ON toys (skills, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS toys_categories_id_idx ON toys (categories, id) WHERE deleted_at IS NULL;
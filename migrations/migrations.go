@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS holds every up/down migration so the binary can apply its own schema.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS manufacturers;
+DROP TABLE IF EXISTS skill_synonyms;
+DROP TABLE IF EXISTS skills;
+-- Dropping a table deletes its rows first, and parent_id would refuse to
+-- let a category go before its subcategories.
+UPDATE categories SET parent_id = NULL;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,43 @@
+-- The SQLite schema follows the postgres one as its migrations leave it.
+-- text[] columns are JSON arrays, jsonb columns JSON text and timestamps
+-- UTC text like 2006-01-02T15:04:05Z, which sorts in time order.
+--
+-- casefold and manufacturer_key are Go functions the storage registers with
+-- the driver: SQLite's own lower() only folds ASCII.
+CREATE TABLE IF NOT EXISTS categories (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    slug text NOT NULL UNIQUE CHECK (slug <> '' AND slug NOT GLOB '*[^a-z0-9-]*' AND slug NOT GLOB '-*' AND slug NOT GLOB '*-' AND slug NOT GLOB '*--*'),
+    parent_id integer REFERENCES categories (id) ON DELETE RESTRICT,
+    names text NOT NULL CHECK (json_type(names) = 'object' AND names <> '{}'),
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    CHECK (parent_id <> id)
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+
+CREATE TABLE IF NOT EXISTS skills (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL CHECK (name <> ''),
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS skills_name_idx ON skills (casefold(name));
+
+CREATE TABLE IF NOT EXISTS skill_synonyms (
+    skill_id integer NOT NULL REFERENCES skills (id) ON DELETE CASCADE,
+    synonym text NOT NULL CHECK (synonym <> '')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS skill_synonyms_synonym_idx ON skill_synonyms (casefold(synonym));
+CREATE INDEX IF NOT EXISTS skill_synonyms_skill_id_idx ON skill_synonyms (skill_id);
+
+CREATE TABLE IF NOT EXISTS manufacturers (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL CHECK (trim(name) <> ''),
+    country text NOT NULL DEFAULT '',
+    logo_url text NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS manufacturers_name_idx ON manufacturers (manufacturer_key(name));
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS toys (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    sku text,
+    title text NOT NULL,
+    description text NOT NULL,
+    skills text NOT NULL CHECK (json_array_length(skills) BETWEEN 1 AND 7),
+    images text NOT NULL CHECK (json_type(images) = 'array'),
+    categories text NOT NULL CHECK (json_array_length(categories) BETWEEN 1 AND 7),
+    recommended_age text,
+    min_age_months integer NOT NULL DEFAULT 0,
+    max_age_months integer,
+    manufacturer_id integer REFERENCES manufacturers (id) ON DELETE RESTRICT,
+    value integer CHECK (value >= 2000),
+    version integer NOT NULL DEFAULT 1,
+    deleted_at timestamp,
+    CHECK (min_age_months >= 0 AND (max_age_months IS NULL OR max_age_months >= min_age_months))
+);
+
+-- SKUs are the external identifiers catalog imports match toys by.
+CREATE UNIQUE INDEX IF NOT EXISTS toys_sku_idx ON toys (sku) WHERE sku IS NOT NULL;
+CREATE INDEX IF NOT EXISTS toys_manufacturer_id_idx ON toys (manufacturer_id);
+CREATE INDEX IF NOT EXISTS toys_age_range_idx ON toys (min_age_months, max_age_months);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS toy_reservations;
+DROP TABLE IF EXISTS toy_units;
+DROP TABLE IF EXISTS toy_rentals;
@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS toy_rentals (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL,
+    toy_id integer NOT NULL REFERENCES toys ON DELETE CASCADE,
+    rented_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    returned_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS toy_rentals_user_id_idx ON toy_rentals (user_id);
+CREATE INDEX IF NOT EXISTS toy_rentals_toy_id_idx ON toy_rentals (toy_id);
+
+CREATE TABLE IF NOT EXISTS toy_units (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    toy_id integer NOT NULL REFERENCES toys ON DELETE CASCADE,
+    serial text NOT NULL UNIQUE,
+    condition text NOT NULL CHECK (condition IN ('new', 'good', 'fair', 'poor')),
+    location text NOT NULL,
+    status text NOT NULL DEFAULT 'available' CHECK (status IN ('available', 'rented', 'reserved', 'maintenance', 'retired')),
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    retired_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS toy_units_toy_id_status_idx ON toy_units (toy_id, status);
+
+-- A reservation covers [starts_at, ends_at).
+CREATE TABLE IF NOT EXISTS toy_reservations (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    toy_id integer NOT NULL REFERENCES toys ON DELETE CASCADE,
+    unit_id integer NOT NULL REFERENCES toy_units ON DELETE CASCADE,
+    user_id integer NOT NULL,
+    starts_at timestamp NOT NULL,
+    ends_at timestamp NOT NULL,
+    status text NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'cancelled')),
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS toy_reservations_user_id_idx ON toy_reservations (user_id);
+CREATE INDEX IF NOT EXISTS toy_reservations_unit_id_idx ON toy_reservations (unit_id, starts_at);
+
+-- SQLite has no exclusion constraints; this trigger stands in for
+-- toy_reservations_no_overlap.
+CREATE TRIGGER IF NOT EXISTS toy_reservations_no_overlap
+    BEFORE INSERT ON toy_reservations
+    WHEN NEW.status = 'active' AND EXISTS (
+        SELECT 1 FROM toy_reservations r
+        WHERE r.unit_id = NEW.unit_id AND r.status = 'active' AND r.starts_at < NEW.ends_at AND NEW.starts_at < r.ends_at
+    )
+BEGIN
+    SELECT RAISE(ABORT, 'toy_reservations_no_overlap');
+END;
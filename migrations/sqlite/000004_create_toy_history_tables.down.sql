@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS toy_events;
+DROP TABLE IF EXISTS toy_audit;
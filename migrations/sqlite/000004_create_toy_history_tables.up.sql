@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS toy_audit (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    toy_id integer NOT NULL REFERENCES toys ON DELETE CASCADE,
+    user_id integer,
+    action text NOT NULL,
+    changes text NOT NULL DEFAULT '[]',
+    changed_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS toy_audit_toy_id_changed_at_idx ON toy_audit (toy_id, changed_at DESC);
+
+CREATE TABLE IF NOT EXISTS toy_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    toy_id integer NOT NULL,
+    event_type text NOT NULL,
+    payload text NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    published_at timestamp,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    last_error text
+);
+
+CREATE INDEX IF NOT EXISTS toy_events_pending_idx ON toy_events (toy_id, id) WHERE published_at IS NULL;
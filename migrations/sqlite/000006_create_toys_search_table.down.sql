@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS manufacturers_name_update;
+DROP TRIGGER IF EXISTS categories_names_update;
+DROP TRIGGER IF EXISTS toys_search_delete;
+DROP TRIGGER IF EXISTS toys_search_update;
+DROP TRIGGER IF EXISTS toys_search_insert;
+DROP VIEW IF EXISTS toys_search_source;
+DROP TABLE IF EXISTS toys_search;
@@ -0,0 +1,57 @@
+-- toys_search is the FTS5 counterpart of the search_document column: title
+-- (A), manufacturer name (B), category names and skills (C) and description
+-- (D) of every toy, keyed by the toy's id. FTS5 has no Russian stemmer, so
+-- the columns hold what the registered toys_lexemes function makes of the
+-- text, the same stems the queries are built from, rather than the text
+-- itself.
+CREATE VIRTUAL TABLE IF NOT EXISTS toys_search USING fts5(title, manufacturer, tags, description);
+
+CREATE VIEW IF NOT EXISTS toys_search_source AS
+SELECT toys.id,
+    toys_lexemes(toys.title) AS title,
+    toys_lexemes((SELECT name FROM manufacturers WHERE id = toys.manufacturer_id)) AS manufacturer,
+    toys_lexemes(coalesce((
+        SELECT group_concat(n.value, ' ')
+        FROM categories c, json_each(c.names) AS n
+        WHERE c.slug IN (SELECT value FROM json_each(toys.categories))), '')
+        || ' ' || coalesce((SELECT group_concat(value, ' ') FROM json_each(toys.skills)), '')) AS tags,
+    toys_lexemes(toys.description) AS description
+FROM toys;
+
+CREATE TRIGGER IF NOT EXISTS toys_search_insert AFTER INSERT ON toys
+BEGIN
+    INSERT INTO toys_search (rowid, title, manufacturer, tags, description)
+    SELECT id, title, manufacturer, tags, description FROM toys_search_source WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS toys_search_update AFTER UPDATE OF title, description, manufacturer_id, categories, skills ON toys
+BEGIN
+    DELETE FROM toys_search WHERE rowid = OLD.id;
+    INSERT INTO toys_search (rowid, title, manufacturer, tags, description)
+    SELECT id, title, manufacturer, tags, description FROM toys_search_source WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS toys_search_delete AFTER DELETE ON toys
+BEGIN
+    DELETE FROM toys_search WHERE rowid = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS categories_names_update AFTER UPDATE OF names ON categories
+BEGIN
+    DELETE FROM toys_search WHERE rowid IN (
+        SELECT toys.id FROM toys, json_each(toys.categories) AS c WHERE c.value = NEW.slug);
+    INSERT INTO toys_search (rowid, title, manufacturer, tags, description)
+    SELECT id, title, manufacturer, tags, description FROM toys_search_source WHERE id IN (
+        SELECT toys.id FROM toys, json_each(toys.categories) AS c WHERE c.value = NEW.slug);
+END;
+
+CREATE TRIGGER IF NOT EXISTS manufacturers_name_update AFTER UPDATE OF name ON manufacturers
+BEGIN
+    DELETE FROM toys_search WHERE rowid IN (SELECT id FROM toys WHERE manufacturer_id = NEW.id);
+    INSERT INTO toys_search (rowid, title, manufacturer, tags, description)
+    SELECT id, title, manufacturer, tags, description FROM toys_search_source WHERE id IN (
+        SELECT id FROM toys WHERE manufacturer_id = NEW.id);
+END;
+
+INSERT INTO toys_search (rowid, title, manufacturer, tags, description)
+SELECT id, title, manufacturer, tags, description FROM toys_search_source;
@@ -0,0 +1,5 @@
+DELETE FROM toy_rentals WHERE reservation_id IS NOT NULL;
+
+DROP INDEX IF EXISTS toy_rentals_reservation_id_idx;
+
+ALTER TABLE toy_rentals DROP COLUMN reservation_id;
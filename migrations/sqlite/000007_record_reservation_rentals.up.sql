@@ -0,0 +1,10 @@
+-- Every reservation is recorded as a rental of its toy for the reserved
+-- period; cancelling the reservation withdraws the rental.
+ALTER TABLE toy_rentals ADD COLUMN reservation_id integer;
+
+CREATE UNIQUE INDEX IF NOT EXISTS toy_rentals_reservation_id_idx ON toy_rentals (reservation_id);
+
+INSERT INTO toy_rentals (user_id, toy_id, reservation_id, rented_at, returned_at)
+SELECT user_id, toy_id, id, starts_at, ends_at
+FROM toy_reservations
+WHERE status = 'active';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS toys_title_id_idx;
+DROP INDEX IF EXISTS toys_value_id_idx;
+DROP INDEX IF EXISTS toys_min_age_months_id_idx;
@@ -0,0 +1,7 @@
+-- Cursor pages of ListToy continue from the last (sort key, id) pair. Titles
+-- are indexed under the toys_text collation ORDER BY sorts them by. Skills
+-- and categories sort by a key built in a subquery, which SQLite cannot
+-- index.
+CREATE INDEX IF NOT EXISTS toys_title_id_idx ON toys (title COLLATE toys_text, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS toys_value_id_idx ON toys (value, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS toys_min_age_months_id_idx ON toys (min_age_months, id) WHERE deleted_at IS NULL;
@@ -0,0 +1,8 @@
+package sqlite
+
+import "embed"
+
+// FS holds the up/down migrations of the SQLite storage backend.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,130 @@
+// Package facetsql builds the ToyFacets query the SQL backends share and
+// reads its rows. A backend only supplies its dialect: the SQL each facet
+// draws its values from and how integer constants are written.
+package facetsql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"toysService/internal/data"
+)
+
+// Filter is one ListToy filter as an SQL condition, tagged with the facet it
+// narrows down.
+type Filter struct {
+	Facet string
+	Cond  string
+}
+
+// order is the order the facets come out of the query in.
+var order = []string{data.FacetCategory, data.FacetSkill, data.FacetManufacturer, data.FacetAge, data.FacetValue}
+
+// Dialect is what differs between the backends' facet queries.
+type Dialect struct {
+	// Sources selects, for each facet, the name, id, lo and hi columns of
+	// the values it counts from the searched toys in base; %s takes the
+	// conditions the facet is subject to. Age bands and value buckets join
+	// against the age_bands and value_buckets lists built from
+	// data.AgeBands and data.ValueBuckets.
+	Sources map[string]string
+	// Int is the format of an integer constant in those lists, such as
+	// "%d::bigint".
+	Int string
+}
+
+// Query returns the facet query over the live toys that match, when $1 is
+// not empty, the search condition match. Every filter becomes a boolean
+// column of base, so each facet can pick the ones it is subject to: all
+// but its own. Columns are named by position because one facet can have
+// several filters, e.g. one per requested category. Ordering by lo keeps
+// age bands and value buckets in range order while the other facets go by
+// count.
+func (d Dialect) Query(match string, filters []Filter) string {
+	columns := []string{"categories", "skills", "manufacturer_id", "min_age_months", "max_age_months", "value"}
+	byFacet := map[string][]int{}
+	for i, f := range filters {
+		columns = append(columns, fmt.Sprintf("(%s) AS f_%d", f.Cond, i))
+		byFacet[f.Facet] = append(byFacet[f.Facet], i)
+	}
+
+	parts := make([]string, 0, len(order))
+	for _, facet := range order {
+		conds := []string{"true"}
+		for _, other := range order {
+			if other == facet {
+				continue
+			}
+			for _, i := range byFacet[other] {
+				conds = append(conds, fmt.Sprintf("f_%d", i))
+			}
+		}
+		parts = append(parts, fmt.Sprintf(`SELECT '%s', r.name, r.id, r.lo, r.hi, count(*) FROM (`+d.Sources[facet]+`) AS r GROUP BY 1, 2, 3, 4, 5`,
+			facet, strings.Join(conds, " AND ")))
+	}
+
+	return `
+WITH base AS (
+    SELECT ` + strings.Join(columns, ", ") + `
+    FROM toys
+    WHERE deleted_at IS NULL AND ($1 = '' OR ` + match + `)
+),
+age_bands(name, lo, hi) AS (` + d.ranges(data.AgeBands) + `),
+value_buckets(name, lo, hi) AS (` + d.ranges(data.ValueBuckets) + `)
+` + strings.Join(parts, "\nUNION ALL\n") + `
+ORDER BY 1, 4, 6 DESC, 2`
+}
+
+// ranges renders facet ranges as a VALUES list. The ranges are constants
+// defined in the data package, never user input.
+func (d Dialect) ranges(ranges []data.FacetRange) string {
+	rows := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		rows = append(rows, fmt.Sprintf("('%s', "+d.Int+", "+d.Int+")", r.Name, r.From, r.To))
+	}
+	return "VALUES " + strings.Join(rows, ", ")
+}
+
+// Scan reads the rows of a Query into facets, keeping at most
+// data.FacetLimit values of the category, skill and manufacturer facets.
+func Scan(rows *sql.Rows) (data.Facets, error) {
+	facets := data.Facets{
+		Categories:    []data.FacetCount{},
+		Skills:        []data.FacetCount{},
+		Manufacturers: []data.FacetCount{},
+		AgeBands:      []data.FacetCount{},
+		ValueBuckets:  []data.FacetCount{},
+	}
+	for rows.Next() {
+		var facet string
+		var fc data.FacetCount
+		if err := rows.Scan(&facet, &fc.Value, &fc.ID, &fc.From, &fc.To, &fc.Count); err != nil {
+			return data.Facets{}, err
+		}
+
+		switch facet {
+		case data.FacetCategory:
+			facets.Categories = appendLimited(facets.Categories, fc)
+		case data.FacetSkill:
+			facets.Skills = appendLimited(facets.Skills, fc)
+		case data.FacetManufacturer:
+			facets.Manufacturers = appendLimited(facets.Manufacturers, fc)
+		case data.FacetAge:
+			facets.AgeBands = append(facets.AgeBands, fc)
+		case data.FacetValue:
+			facets.ValueBuckets = append(facets.ValueBuckets, fc)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return data.Facets{}, err
+	}
+
+	return facets, nil
+}
+
+func appendLimited(counts []data.FacetCount, fc data.FacetCount) []data.FacetCount {
+	if len(counts) >= data.FacetLimit {
+		return counts
+	}
+	return append(counts, fc)
+}
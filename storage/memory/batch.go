@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"toysService/internal/data"
+)
+
+// CreateToys inserts validated toys. See writeBatch for how atomic and
+// best-effort batches differ.
+func (s *Storage) CreateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.CreateToys",
+		"items":  strconv.Itoa(len(items)),
+	})
+
+	return s.writeBatch(ctx, items, atomic, s.insertToy)
+}
+
+// LoadToys returns the live toys a batch update applies its changes to,
+// keyed by id. Missing and deleted toys are left out.
+func (s *Storage) LoadToys(ctx context.Context, ids []int64) (map[int64]data.Toy, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.LoadToys",
+		"items":  strconv.Itoa(len(ids)),
+	})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := make(map[int64]data.Toy, len(ids))
+	for _, id := range ids {
+		if t, ok := s.liveToy(id); ok {
+			found[id] = *s.withLiveColumns(cloneToy(t))
+		}
+	}
+	return found, nil
+}
+
+// UpdateToys changes validated toys. Like ChangeToy, every item carries the
+// version it was read at and fails with data.ErrEditConflict if the toy has
+// changed since.
+func (s *Storage) UpdateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.UpdateToys",
+		"items":  strconv.Itoa(len(items)),
+	})
+
+	return s.writeBatch(ctx, items, atomic, func(ctx context.Context, toy data.Toy) (data.Toy, error) {
+		if _, ok := s.liveToy(toy.ID); !ok {
+			return data.Toy{}, data.ErrRecordNotFound
+		}
+		if err := s.updateToy(ctx, &toy); err != nil {
+			return data.Toy{}, err
+		}
+		return toy, nil
+	})
+}
+
+// writeBatch runs write for every item under one lock. An atomic batch stops
+// at the first failing item, undoes the items written so far and marks the
+// other items data.ErrBatchAborted. A best-effort batch skips failing items
+// and keeps the rest.
+func (s *Storage) writeBatch(ctx context.Context, items []data.BatchItem, atomic bool, write func(ctx context.Context, toy data.Toy) (data.Toy, error)) ([]data.BatchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The undo log keeps each touched toy as it was before the batch, nil
+	// for toys the batch created, plus where the audit log, outbox and
+	// manufacturer IDs ended.
+	before := map[int64]*data.Toy{}
+	mark := batchMark{auditLen: len(s.audit), eventsLen: len(s.events), lastManufacturer: s.seq["manufacturers"]}
+
+	results := make([]data.BatchResult, len(items))
+	for i, item := range items {
+		results[i].Index = item.Index
+	}
+	for i, item := range items {
+		if t, ok := s.toys[item.Toy.ID]; ok && atomic {
+			if _, seen := before[t.ID]; !seen {
+				before[t.ID] = cloneToy(t)
+			}
+		}
+
+		toy, err := write(ctx, item.Toy)
+		if err != nil {
+			results[i].Err = err
+			if atomic {
+				s.undoBatch(before, mark)
+				return abortBatch(results, i), nil
+			}
+			continue
+		}
+		if _, seen := before[toy.ID]; atomic && !seen {
+			before[toy.ID] = nil
+		}
+		results[i].ID, results[i].Version = toy.ID, toy.Version
+	}
+	return results, nil
+}
+
+// batchMark is where the audit log, the outbox and the manufacturer IDs
+// stood when a batch began.
+type batchMark struct {
+	auditLen, eventsLen int
+	lastManufacturer    int64
+}
+
+// undoBatch restores the toys of an atomic batch and drops the audit entries,
+// events and manufacturers it recorded. Like a rolled back sequence, the IDs
+// it used are not handed out again.
+func (s *Storage) undoBatch(before map[int64]*data.Toy, mark batchMark) {
+	for id, t := range before {
+		if t == nil {
+			delete(s.toys, id)
+			continue
+		}
+		s.toys[id] = t
+	}
+	for id := range s.manufacturers {
+		if id > mark.lastManufacturer {
+			delete(s.manufacturers, id)
+		}
+	}
+	s.audit = s.audit[:mark.auditLen]
+	s.events = s.events[:mark.eventsLen]
+}
+
+// abortBatch marks every item but the failed one as not written.
+func abortBatch(results []data.BatchResult, failed int) []data.BatchResult {
+	for i := range results {
+		if i != failed {
+			results[i].Err = data.ErrBatchAborted
+			results[i].ID, results[i].Version = 0, 0
+		}
+	}
+	return results
+}
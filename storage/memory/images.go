@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"toysService/internal/data"
+)
+
+// InsertImage stores a detached copy of an uploaded image with a fresh id.
+func (s *Storage) InsertImage(ctx context.Context, img data.ToyImage) (data.ToyImage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	img.ID = s.nextID("toy_images")
+	img.ToyID, img.Alt, img.Position, img.Primary = 0, "", 0, false
+	img.CreatedAt = now()
+	stored := img
+	s.images[img.ID] = &stored
+	return img, nil
+}
+
+func (s *Storage) ListToyImages(ctx context.Context, toyID int64) ([]*data.ToyImage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.toyImages(toyID), nil
+}
+
+func (s *Storage) toyImages(toyID int64) []*data.ToyImage {
+	images := []*data.ToyImage{}
+	for _, stored := range s.images {
+		if stored.ToyID == toyID {
+			img := *stored
+			images = append(images, &img)
+		}
+	}
+	slices.SortFunc(images, func(a, b *data.ToyImage) int {
+		return cmp.Or(compareInt(a.Position, b.Position), compareInt(a.ID, b.ID))
+	})
+	return images
+}
+
+// SetToyImages makes items, in order, the images of the toy. Images left out
+// are detached, and the first image is primary unless another one is marked.
+// The toy's images are rewritten to the image URLs, which counts as a change
+// of the toy.
+func (s *Storage) SetToyImages(ctx context.Context, toyID int64, items []data.ToyImage) ([]*data.ToyImage, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.SetToyImages",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	toy, ok := s.liveToy(toyID)
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	primary := -1
+	for i, item := range items {
+		img, ok := s.images[item.ID]
+		if !ok {
+			return nil, data.ErrRecordNotFound
+		}
+		if img.ToyID != 0 && img.ToyID != toyID {
+			return nil, data.ErrImageAttached
+		}
+		if item.Primary {
+			primary = i
+		}
+	}
+	if primary < 0 && len(items) > 0 {
+		primary = 0
+	}
+
+	for _, img := range s.images {
+		if img.ToyID == toyID {
+			img.ToyID, img.Position, img.Primary = 0, 0, false
+		}
+	}
+
+	images := make([]*data.ToyImage, 0, len(items))
+	urls := make([]string, 0, len(items))
+	for i, item := range items {
+		img := s.images[item.ID]
+		img.ToyID, img.Position, img.Alt, img.Primary = toyID, int32(i), item.Alt, i == primary
+		c := *img
+		images = append(images, &c)
+		urls = append(urls, img.URL)
+	}
+
+	before := *s.withLiveColumns(cloneToy(toy))
+	toy.Images = slices.Clone(urls)
+	toy.Version++
+
+	after := before
+	after.Images = urls
+	changes := data.DiffToys(before, after)
+	s.insertAudit(ctx, toyID, data.AuditActionChange, changes)
+	after.Version++
+	s.insertEvent(data.EventToyChanged, data.ToyEventPayload{ToyID: toyID, Toy: cloneToy(&after), Changes: changes})
+
+	return images, nil
+}
+
+// DeleteImage forgets an image that no toy has and returns it, so the caller
+// can delete its blobs.
+func (s *Storage) DeleteImage(ctx context.Context, id int64) (data.ToyImage, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.DeleteImage",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	img, ok := s.images[id]
+	if !ok {
+		return data.ToyImage{}, data.ErrRecordNotFound
+	}
+	if img.ToyID != 0 {
+		return data.ToyImage{}, data.ErrImageAttached
+	}
+	delete(s.images, id)
+	return *img, nil
+}
@@ -0,0 +1,586 @@
+// Package memory is a toys storage provider that keeps everything in process
+// memory. It implements the same contract as storage/postgres so the service
+// can run without a database, e.g. for frontend development and demos; all
+// data is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
+	"slices"
+	"sync"
+	"time"
+	"toysService/internal/contextkeys"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+)
+
+var errDuplicateSKU = errors.New("another toy already has this sku")
+
+// Storage guards all of its tables with one lock, which makes every method
+// atomic the way a database transaction would be. Methods never hand out
+// pointers into the tables, only copies.
+type Storage struct {
+	mu  sync.RWMutex
+	log *jsonlog.Logger
+
+	seq           map[string]int64
+	toys          map[int64]*data.Toy
+	audit         []*data.ToyAuditEntry
+	events        []*toyEvent
+	units         map[int64]*data.ToyUnit
+	reservations  map[int64]*data.Reservation
+	categories    map[int64]*data.Category
+	skills        map[int64]*data.Skill
+	manufacturers map[int64]*data.Manufacturer
+	images        map[int64]*data.ToyImage
+}
+
+func New(logger *jsonlog.Logger) *Storage {
+	return &Storage{
+		log:           logger,
+		seq:           map[string]int64{},
+		toys:          map[int64]*data.Toy{},
+		units:         map[int64]*data.ToyUnit{},
+		reservations:  map[int64]*data.Reservation{},
+		categories:    map[int64]*data.Category{},
+		skills:        map[int64]*data.Skill{},
+		manufacturers: map[int64]*data.Manufacturer{},
+		images:        map[int64]*data.ToyImage{},
+	}
+}
+
+// nextID works like a serial column: IDs of a table start at 1 and are never
+// reused.
+func (s *Storage) nextID(table string) int64 {
+	s.seq[table]++
+	return s.seq[table]
+}
+
+// timestamp formats a time the way the postgres driver hands timestamps to
+// string fields.
+func timestamp(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func actorFromContext(ctx context.Context) int64 {
+	userID, _ := ctx.Value(contextkeys.UserIDKey).(int64)
+	return userID
+}
+
+func cloneToy(t *data.Toy) *data.Toy {
+	c := *t
+	c.Images = slices.Clone(t.Images)
+	c.Skills = slices.Clone(t.Skills)
+	c.Categories = slices.Clone(t.Categories)
+	return &c
+}
+
+// liveToy returns the toy if it exists and is not deleted.
+func (s *Storage) liveToy(id int64) (*data.Toy, bool) {
+	t, ok := s.toys[id]
+	if !ok || t.DeletedAt != "" {
+		return nil, false
+	}
+	return t, true
+}
+
+// availableCount counts the toy's units that can be handed out right now.
+func (s *Storage) availableCount(toyID int64) int32 {
+	var count int32
+	for _, u := range s.units {
+		if u.ToyID == toyID && u.Status == data.UnitStatusAvailable {
+			count++
+		}
+	}
+	return count
+}
+
+// manufacturerName is what the postgres queries read through manufacturer_id.
+func (s *Storage) manufacturerName(id int64) string {
+	if m, ok := s.manufacturers[id]; ok {
+		return m.Name
+	}
+	return ""
+}
+
+// withLiveColumns fills in the columns postgres computes on read.
+func (s *Storage) withLiveColumns(t *data.Toy) *data.Toy {
+	t.Manufacturer = s.manufacturerName(t.ManufacturerID)
+	t.AvailableCount = s.availableCount(t.ID)
+	t.IsAvailable = t.AvailableCount > 0
+	return t
+}
+
+func (s *Storage) insertAudit(ctx context.Context, toyID int64, action string, changes []data.FieldChange) {
+	s.audit = append(s.audit, &data.ToyAuditEntry{
+		ID:        s.nextID("toy_audit"),
+		ToyID:     toyID,
+		UserID:    actorFromContext(ctx),
+		Action:    action,
+		Changes:   changes,
+		ChangedAt: timestamp(time.Now()),
+	})
+}
+
+func (s *Storage) CreateToy(ctx context.Context, inputToy data.Toy) (toys.Status, string, data.Toy) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.CreateToy",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	toy, err := s.insertToy(ctx, inputToy)
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Toy{}
+	}
+	return toys.Status_STATUS_OK, "toy added successfuly!", toy
+}
+
+// insertToy stores a new toy with its audit entry and creation event and
+// returns what postgres.CreateToy returns for it. Like the unique index on
+// toys.sku, it refuses an SKU another toy already has.
+func (s *Storage) insertToy(ctx context.Context, inputToy data.Toy) (data.Toy, error) {
+	if inputToy.SKU != "" {
+		if _, _, ok := s.toyBySKU(inputToy.SKU); ok {
+			return data.Toy{}, errDuplicateSKU
+		}
+	}
+	s.registerManufacturer(&inputToy)
+
+	toy := data.Toy{
+		ID:             s.nextID("toys"),
+		SKU:            inputToy.SKU,
+		Title:          inputToy.Title,
+		Desc:           inputToy.Desc,
+		Value:          inputToy.Value,
+		Images:         slices.Clone(inputToy.Images),
+		Skills:         slices.Clone(inputToy.Skills),
+		Categories:     slices.Clone(inputToy.Categories),
+		RecAge:         inputToy.RecAge,
+		MinAgeMonths:   inputToy.MinAgeMonths,
+		MaxAgeMonths:   inputToy.MaxAgeMonths,
+		Manufacturer:   inputToy.Manufacturer,
+		ManufacturerID: inputToy.ManufacturerID,
+		Version:        1,
+	}
+	stored := cloneToy(&toy)
+	stored.CreatedAt = timestamp(time.Now())
+	s.toys[toy.ID] = stored
+
+	s.insertAudit(ctx, toy.ID, data.AuditActionCreate, data.DiffToys(data.Toy{}, toy))
+	s.insertEvent(data.EventToyCreated, data.ToyEventPayload{ToyID: toy.ID, Toy: cloneToy(&toy)})
+	return toy, nil
+}
+
+// toyBySKU finds the toy with an SKU, deleted or not, and reports whether it
+// is deleted.
+func (s *Storage) toyBySKU(sku string) (*data.Toy, bool, bool) {
+	for _, t := range s.toys {
+		if t.SKU == sku {
+			return t, t.DeletedAt != "", true
+		}
+	}
+	return nil, false, false
+}
+
+func (s *Storage) DeleteToy(ctx context.Context, toyID int64) (toys.Status, string) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.DeleteToy",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.liveToy(toyID)
+	if !ok {
+		return toys.Status_STATUS_INTERNAL_ERROR, "it affected 0 rows!"
+	}
+	t.DeletedAt = timestamp(time.Now())
+
+	s.insertAudit(ctx, toyID, data.AuditActionDelete, []data.FieldChange{})
+	s.insertEvent(data.EventToyDeleted, data.ToyEventPayload{ToyID: toyID})
+	return toys.Status_STATUS_OK, "toy deletion was successful"
+}
+
+// RestoreToy undeletes a toy, failing like the SQL backends when there is no
+// such toy or it is not deleted.
+func (s *Storage) RestoreToy(ctx context.Context, toyID int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.RestoreToy",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.toys[toyID]
+	switch {
+	case !ok:
+		return data.ErrRecordNotFound
+	case t.DeletedAt == "":
+		return data.ErrToyNotDeleted
+	}
+	t.DeletedAt = ""
+
+	s.insertAudit(ctx, toyID, data.AuditActionRestore, []data.FieldChange{})
+	s.insertEvent(data.EventToyRestored, data.ToyEventPayload{ToyID: toyID})
+	return nil
+}
+
+func (s *Storage) ListDeletedToys(ctx context.Context, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ListDeletedToys",
+	})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var deleted []*data.Toy
+	for _, t := range s.toys {
+		if t.DeletedAt == "" {
+			continue
+		}
+		deleted = append(deleted, &data.Toy{
+			ID:             t.ID,
+			Title:          t.Title,
+			Categories:     slices.Clone(t.Categories),
+			Skills:         slices.Clone(t.Skills),
+			RecAge:         t.RecAge,
+			MinAgeMonths:   t.MinAgeMonths,
+			MaxAgeMonths:   t.MaxAgeMonths,
+			ManufacturerID: t.ManufacturerID,
+			Manufacturer:   s.manufacturerName(t.ManufacturerID),
+			Value:          t.Value,
+			DeletedAt:      t.DeletedAt,
+		})
+	}
+	sortToys(deleted, filters)
+
+	page := paginate(deleted, filters)
+	metadata := filters.CalculateMetadata(len(deleted), filters.Page, filters.PageSize)
+	return page, toys.Status_STATUS_OK, "deleted toys listing was successful", metadata
+}
+
+// ListToyHistory lists a toy's audit entries by id, which is also the order
+// they were recorded in.
+func (s *Storage) ListToyHistory(ctx context.Context, toyID int64, filters data.Filters) ([]*data.ToyAuditEntry, toys.Status, string, data.Metadata) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ListToyHistory",
+	})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []*data.ToyAuditEntry
+	for _, e := range s.audit {
+		if e.ToyID == toyID {
+			c := *e
+			c.Changes = slices.Clone(e.Changes)
+			entries = append(entries, &c)
+		}
+	}
+
+	desc := filters.SortDirection() == "DESC"
+	slices.SortFunc(entries, func(a, b *data.ToyAuditEntry) int {
+		if desc {
+			return compareInt(b.ID, a.ID)
+		}
+		return compareInt(a.ID, b.ID)
+	})
+
+	page := paginate(entries, filters)
+	metadata := filters.CalculateMetadata(len(entries), filters.Page, filters.PageSize)
+	return page, toys.Status_STATUS_OK, "toy history listing was successful", metadata
+}
+
+// ChangeToy writes the toy only if its version still matches the one the
+// caller read; otherwise it returns data.ErrEditConflict.
+func (s *Storage) ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ChangeToy",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.liveToy(toy.ID); !ok {
+		return toys.Status_STATUS_INTERNAL_ERROR, "operation affect zero rows!", data.ErrRecordNotFound
+	}
+	if err := s.updateToy(ctx, &toy); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "toy was changed by someone else", err
+	}
+	return toys.Status_STATUS_OK, "toys updated successfully!", nil
+}
+
+// updateToy overwrites the editable fields of a live toy, bumps its version
+// and records the audit entry and change event. It returns
+// data.ErrEditConflict if toy.Version no longer matches.
+func (s *Storage) updateToy(ctx context.Context, toy *data.Toy) error {
+	stored, _ := s.liveToy(toy.ID)
+	if stored.Version != toy.Version {
+		return data.ErrEditConflict
+	}
+	before := *s.withLiveColumns(cloneToy(stored))
+	s.registerManufacturer(toy)
+
+	stored.Title = toy.Title
+	stored.Desc = toy.Desc
+	stored.Skills = slices.Clone(toy.Skills)
+	stored.Images = slices.Clone(toy.Images)
+	stored.Categories = slices.Clone(toy.Categories)
+	stored.RecAge = toy.RecAge
+	stored.ManufacturerID = toy.ManufacturerID
+	stored.Value = toy.Value
+	stored.MinAgeMonths = toy.MinAgeMonths
+	stored.MaxAgeMonths = toy.MaxAgeMonths
+	stored.Version++
+
+	changes := data.DiffToys(before, *toy)
+	s.insertAudit(ctx, toy.ID, data.AuditActionChange, changes)
+
+	toy.Version++
+	toy.AvailableCount = before.AvailableCount
+	toy.IsAvailable = before.IsAvailable
+	s.insertEvent(data.EventToyChanged, data.ToyEventPayload{ToyID: toy.ID, Toy: cloneToy(toy), Changes: changes})
+	return nil
+}
+
+func (s *Storage) GetToy(ctx context.Context, toyID int64) (data.Toy, toys.Status, string) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.GetToy",
+	})
+	if toyID < 1 {
+		return data.Toy{}, toys.Status_STATUS_INTERNAL_ERROR, "invalid toy id"
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.liveToy(toyID)
+	if !ok {
+		return data.Toy{}, toys.Status_STATUS_INTERNAL_ERROR, "operation affect zero rows!"
+	}
+	toy := s.withLiveColumns(cloneToy(t))
+	toy.DeletedAt = ""
+	return *toy, toys.Status_STATUS_OK, "toy get successfully"
+}
+
+// GetToysByIds summarizes the toys, deleted ones included. The summary shows
+// the thumbnail of the primary uploaded image, or the first image URL for
+// toys without uploads.
+func (s *Storage) GetToysByIds(ctx context.Context, ids []int64) ([]*data.ToySummary, string) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.gettoysbyid",
+	})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := []*data.ToySummary{}
+	for _, id := range ids {
+		t, ok := s.toys[id]
+		if !ok {
+			continue
+		}
+		summary := &data.ToySummary{ID: t.ID, Title: t.Title, Value: t.Value}
+		if len(t.Images) > 0 {
+			summary.URL = t.Images[0]
+		}
+		for _, img := range s.images {
+			if img.ToyID == id && img.Primary {
+				summary.URL = img.ThumbnailURL
+			}
+		}
+		results = append(results, summary)
+	}
+	return results, "toy fetch was successful"
+}
+
+// ListRecommended scores toys the way postgres.ListRecommended does. The
+// rentals it learns from are the reservations that were not cancelled, as
+// ReserveToy records them in the databases.
+func (s *Storage) ListRecommended(ctx context.Context, userID int64, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ListRec",
+	})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	categoryWeights, skillWeights, ageWeights := map[string]int64{}, map[string]int64{}, map[string]int64{}
+	popularity := map[int64]int64{}
+	out := map[int64]bool{}
+	now := time.Now()
+	for _, r := range s.reservations {
+		if r.Status != data.ReservationStatusActive {
+			continue
+		}
+		popularity[r.ToyID]++
+		if r.UserID != userID {
+			continue
+		}
+		if r.EndsAt.After(now) {
+			out[r.ToyID] = true
+		}
+		t := s.toys[r.ToyID]
+		for _, c := range t.Categories {
+			categoryWeights[c]++
+		}
+		for _, sk := range t.Skills {
+			skillWeights[sk]++
+		}
+		ageWeights[t.RecAge]++
+	}
+
+	var list []*data.Toy
+	scores := map[int64]int64{}
+	for _, t := range s.toys {
+		if t.DeletedAt != "" || out[t.ID] {
+			continue
+		}
+		var score int64
+		for _, c := range distinct(t.Categories) {
+			score += 3 * categoryWeights[c]
+		}
+		for _, sk := range distinct(t.Skills) {
+			score += 2 * skillWeights[sk]
+		}
+		scores[t.ID] = score + ageWeights[t.RecAge]
+		list = append(list, &data.Toy{
+			ID:         t.ID,
+			Title:      t.Title,
+			Categories: slices.Clone(t.Categories),
+			Skills:     slices.Clone(t.Skills),
+			RecAge:     t.RecAge,
+			Value:      t.Value,
+		})
+	}
+	slices.SortFunc(list, func(a, b *data.Toy) int {
+		if c := compareInt(scores[b.ID], scores[a.ID]); c != 0 {
+			return c
+		}
+		if c := compareInt(popularity[b.ID], popularity[a.ID]); c != 0 {
+			return c
+		}
+		return compareInt(a.ID, b.ID)
+	})
+
+	page := paginate(list, filters)
+	metadata := filters.CalculateMetadata(len(list), filters.Page, filters.PageSize)
+	return page, toys.Status_STATUS_OK, "recommendations listing was successful", metadata
+}
+
+// distinct returns the values of list without repeats, as = ANY() counts
+// every value once.
+func distinct(list []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range list {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// paginate returns the filters' page of a sorted list. It never returns nil,
+// like the postgres listings.
+func paginate[T any](list []T, filters data.Filters) []T {
+	offset, limit := int(filters.Offset()), int(filters.Limit())
+	if offset < 0 || offset >= len(list) {
+		return []T{}
+	}
+	end := min(offset+limit, len(list))
+	return slices.Clone(list[offset:end])
+}
+
+func compareInt[T int64 | int32 | int](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// toyEvent is an outbox entry with the delivery state toy_events keeps.
+type toyEvent struct {
+	data.ToyEvent
+	published     bool
+	nextAttemptAt time.Time
+}
+
+func (s *Storage) insertEvent(eventType string, payload data.ToyEventPayload) {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		// The payload only holds plain data, so this cannot happen.
+		panic(err)
+	}
+	s.events = append(s.events, &toyEvent{ToyEvent: data.ToyEvent{
+		ID:        s.nextID("toy_events"),
+		ToyID:     payload.ToyID,
+		Type:      eventType,
+		Payload:   js,
+		CreatedAt: time.Now(),
+	}})
+}
+
+// insertAvailabilityEvent records the current number of available copies of
+// a toy.
+func (s *Storage) insertAvailabilityEvent(toyID int64) {
+	count := s.availableCount(toyID)
+	isAvailable := count > 0
+	s.insertEvent(data.EventToyAvailabilityChanged, data.ToyEventPayload{
+		ToyID:          toyID,
+		AvailableCount: &count,
+		IsAvailable:    &isAvailable,
+	})
+}
+
+// DeliverToyEvents hands pending events to publish in order, with the same
+// per-toy ordering and backoff as postgres.DeliverToyEvents. The lock is
+// held while publishing, which also keeps concurrent relays from delivering
+// an event twice.
+func (s *Storage) DeliverToyEvents(
+	ctx context.Context,
+	limit int,
+	publish func(context.Context, *data.ToyEvent) error,
+	backoff func(attempts int32) time.Duration,
+) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	delivered, picked := 0, 0
+	blocked := map[int64]bool{}
+	for _, event := range s.events {
+		if event.published {
+			continue
+		}
+		if blocked[event.ToyID] || event.nextAttemptAt.After(now) {
+			blocked[event.ToyID] = true
+			continue
+		}
+		if picked == limit {
+			break
+		}
+		picked++
+
+		e := event.ToyEvent
+		event.Attempts++
+		if err := publish(ctx, &e); err != nil {
+			blocked[event.ToyID] = true
+			event.nextAttemptAt = time.Now().Add(backoff(event.Attempts))
+			continue
+		}
+		event.published = true
+		delivered++
+	}
+	return delivered, nil
+}
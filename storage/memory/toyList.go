@@ -0,0 +1,597 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
+	"maps"
+	"slices"
+	"strings"
+	"toysService/internal/data"
+	"toysService/internal/textsearch"
+	"unicode/utf8"
+)
+
+// toySortColumns maps the sort names accepted by ListToy to the sort keys
+// toySortKey knows, mirroring the postgres backend's column mapping.
+var toySortColumns = map[string]string{
+	"recAge": "min_age_months",
+	"age":    "min_age_months",
+	"from":   "value",
+	"to":     "value",
+}
+
+func toySortColumn(filters data.Filters) string {
+	column := filters.SortColumn()
+	if mapped, ok := toySortColumns[column]; ok {
+		return mapped
+	}
+	return column
+}
+
+// toySortKey returns the value of the sort column for a listed toy, which is
+// what a cursor pointing at that toy has to remember. The relevance key is
+// the negated rank, so that ascending order puts the best matches first.
+func toySortKey(column string, toy *data.Toy) any {
+	switch column {
+	case "title":
+		return toy.Title
+	case "value":
+		return toy.Value
+	case "min_age_months":
+		return toy.MinAgeMonths
+	case "skills":
+		return toy.Skills
+	case "categories":
+		return toy.Categories
+	case "relevance":
+		return -toy.Rank
+	default:
+		return toy.ID
+	}
+}
+
+// toyCursorKey decodes a cursor's sort key into the type toySortKey returns
+// for the column.
+func toyCursorKey(column string, c data.Cursor) (any, error) {
+	switch column {
+	case "title":
+		var key string
+		err := c.KeyInto(&key)
+		return key, err
+	case "value", "id":
+		var key int64
+		err := c.KeyInto(&key)
+		return key, err
+	case "min_age_months":
+		var key int32
+		err := c.KeyInto(&key)
+		return key, err
+	case "skills", "categories":
+		var key []string
+		err := c.KeyInto(&key)
+		return key, err
+	case "relevance":
+		var key float32
+		err := c.KeyInto(&key)
+		return key, err
+	default:
+		return nil, fmt.Errorf("cursor pagination is not supported for %q", column)
+	}
+}
+
+// compareSortKeys orders two keys of the same column. Text follows the
+// database collation and arrays compare element by element, like postgres
+// sorts them.
+func compareSortKeys(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		return textsearch.Compare(a, b.(string))
+	case int64:
+		return compareInt(a, b.(int64))
+	case int32:
+		return compareInt(a, b.(int32))
+	case float32:
+		return cmp.Compare(a, b.(float32))
+	case []string:
+		return textsearch.CompareLists(a, b.([]string))
+	}
+	panic(fmt.Sprintf("unsupported sort key %T", a))
+}
+
+// toyOrder compares two toys in "column direction, id ASC" order.
+func toyOrder(column, direction string) func(a, b *data.Toy) int {
+	return func(a, b *data.Toy) int {
+		c := compareSortKeys(toySortKey(column, a), toySortKey(column, b))
+		if direction == "DESC" {
+			c = -c
+		}
+		return cmp.Or(c, compareInt(a.ID, b.ID))
+	}
+}
+
+func sortToys(list []*data.Toy, filters data.Filters) {
+	slices.SortFunc(list, toyOrder(toySortColumn(filters), filters.SortDirection()))
+}
+
+// toyFilter is one ListToy filter, tagged with the facet it narrows down.
+type toyFilter struct {
+	facet string
+	match func(t *data.Toy) bool
+}
+
+// toyFilters turns the query's filters into predicates with the same meaning
+// as the SQL conditions of the postgres backend.
+func (s *Storage) toyFilters(q data.ToyQuery) []toyFilter {
+	var filters []toyFilter
+
+	// Every requested category must match, either itself or through one of
+	// its subcategories.
+	taxonomy := s.taxonomy()
+	for _, slug := range q.Categories {
+		subtree := taxonomy.Subtree(slug)
+		filters = append(filters, toyFilter{data.FacetCategory, func(t *data.Toy) bool {
+			return overlaps(t.Categories, subtree)
+		}})
+	}
+	// Skills match by canonical name or any synonym.
+	for _, skill := range q.Skills {
+		variants := s.skillVariants(skill)
+		filters = append(filters, toyFilter{data.FacetSkill, func(t *data.Toy) bool {
+			return overlaps(t.Skills, variants)
+		}})
+	}
+	if q.AgeMonths != nil {
+		age := *q.AgeMonths
+		filters = append(filters, toyFilter{data.FacetAge, func(t *data.Toy) bool {
+			return t.MinAgeMonths <= age && (t.MaxAgeMonths == 0 || t.MaxAgeMonths >= age)
+		}})
+	}
+	if len(q.Manufacturers) > 0 {
+		filters = append(filters, toyFilter{data.FacetManufacturer, func(t *data.Toy) bool {
+			return slices.Contains(q.Manufacturers, t.ManufacturerID)
+		}})
+	}
+	filters = append(filters, toyFilter{data.FacetValue, func(t *data.Toy) bool {
+		return t.Value >= q.From && t.Value <= q.To
+	}})
+
+	return filters
+}
+
+func overlaps(a, b []string) bool {
+	return slices.ContainsFunc(a, func(v string) bool { return slices.Contains(b, v) })
+}
+
+// searchDocument indexes a toy like the search_document column.
+func (s *Storage) searchDocument(t *data.Toy) textsearch.Document {
+	var tags []string
+	for _, slug := range t.Categories {
+		if c, ok := s.categoryBySlug(slug); ok {
+			for _, locale := range slices.Sorted(maps.Keys(c.Names)) {
+				tags = append(tags, c.Names[locale])
+			}
+		}
+	}
+	var d textsearch.Document
+	d = d.Add(t.Title, textsearch.WeightTitle)
+	d = d.Add(s.manufacturerName(t.ManufacturerID), textsearch.WeightManufacturer)
+	d = d.Add(strings.Join(tags, " "), textsearch.WeightTags)
+	d = d.Add(strings.Join(t.Skills, " "), textsearch.WeightTags)
+	d = d.Add(t.Desc, textsearch.WeightDescription)
+	return d
+}
+
+// searchToys returns the live toys matching the search text, with the
+// fields ListToy lists and their rank and snippet. A fuzzy search matches
+// titles and manufacturer names that contain something close to the text.
+// Toys are returned in no particular order.
+func (s *Storage) searchToys(title string, fuzzy bool) []*data.Toy {
+	query := textsearch.Parse(title)
+
+	var found []*data.Toy
+	for _, t := range s.toys {
+		if t.DeletedAt != "" {
+			continue
+		}
+		toy := s.withLiveColumns(&data.Toy{
+			ID:             t.ID,
+			Title:          t.Title,
+			Categories:     slices.Clone(t.Categories),
+			Skills:         slices.Clone(t.Skills),
+			RecAge:         t.RecAge,
+			MinAgeMonths:   t.MinAgeMonths,
+			MaxAgeMonths:   t.MaxAgeMonths,
+			ManufacturerID: t.ManufacturerID,
+			Value:          t.Value,
+		})
+
+		switch {
+		case title == "":
+		case fuzzy:
+			toy.Rank = max(textsearch.WordSimilarity(title, t.Title), textsearch.WordSimilarity(title, toy.Manufacturer))
+			if toy.Rank < textsearch.FuzzyThreshold {
+				continue
+			}
+		default:
+			rank, ok := s.searchDocument(t).Match(query)
+			if !ok {
+				continue
+			}
+			toy.Rank = rank
+			toy.Snippet = textsearch.Snippet(t.Title+" — "+t.Desc, query)
+		}
+		found = append(found, toy)
+	}
+	return found
+}
+
+// matchToys returns the toys matching the search text and every filter of
+// q.
+func (s *Storage) matchToys(q data.ToyQuery, fuzzy bool) []*data.Toy {
+	filters := s.toyFilters(q)
+	return slices.DeleteFunc(s.searchToys(q.Title, fuzzy), func(t *data.Toy) bool {
+		return slices.ContainsFunc(filters, func(f toyFilter) bool { return !f.match(t) })
+	})
+}
+
+// ListToy pages with page numbers unless filters.Cursor is set, in which
+// case it continues from the cursor's toy. Like the postgres backend it
+// falls back to fuzzy matching when a search matches nothing at all, and the
+// metadata carries cursors for the neighbouring pages either way.
+func (s *Storage) ListToy(ctx context.Context, q data.ToyQuery, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ListToy",
+	})
+
+	var cursor data.Cursor
+	if filters.Cursor != "" {
+		var err error
+		if cursor, err = data.DecodeCursor(filters.Cursor); err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "invalid cursor", data.Metadata{}
+		}
+	}
+	fuzzy := cursor.Fuzzy
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	toysList, totalRecords, err := s.queryToys(q, filters, cursor, fuzzy)
+	if err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+	}
+
+	// An empty later page may just be past the end of the matches, so only
+	// a search without any match falls back.
+	if totalRecords == 0 && q.Title != "" && !fuzzy && filters.Cursor == "" {
+		fuzzy = true
+		toysList, totalRecords, err = s.queryToys(q, filters, cursor, fuzzy)
+		if err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+		}
+	}
+
+	var metadata data.Metadata
+	var hasNext, hasPrev bool
+	if filters.Cursor != "" {
+		hasMore := len(toysList) > int(filters.Limit())
+		if hasMore {
+			toysList = toysList[:filters.Limit()]
+		}
+		if cursor.Backward {
+			slices.Reverse(toysList)
+			hasNext, hasPrev = true, hasMore
+		} else {
+			hasNext, hasPrev = hasMore, true
+		}
+		metadata = data.Metadata{PageSize: filters.PageSize}
+	} else {
+		metadata = filters.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		hasNext = int(filters.Offset())+len(toysList) < totalRecords
+		hasPrev = filters.Page > 1
+	}
+	metadata.Fuzzy = fuzzy
+
+	if err = pageCursors(&metadata, filters, toySortColumn(filters), fuzzy, toysList, hasNext, hasPrev); err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+	}
+	return toysList, toys.Status_STATUS_OK, "toy listing was successful", metadata
+}
+
+// queryToys returns one ListToy page and the number of matching toys. In
+// cursor mode it returns one toy more than the page size so the caller can
+// tell whether another page follows, a backward page comes in reverse order,
+// and the returned total is zero.
+func (s *Storage) queryToys(q data.ToyQuery, filters data.Filters, cursor data.Cursor, fuzzy bool) ([]*data.Toy, int, error) {
+	column := toySortColumn(filters)
+	order := toyOrder(column, filters.SortDirection())
+
+	matches := s.matchToys(q, fuzzy)
+	slices.SortFunc(matches, order)
+
+	if filters.Cursor == "" {
+		return paginate(matches, filters), len(matches), nil
+	}
+
+	key, err := toyCursorKey(column, cursor)
+	if err != nil {
+		return nil, 0, err
+	}
+	// positionOf tells whether a toy sorts after (> 0) or before (< 0) the
+	// cursor's toy.
+	positionOf := func(t *data.Toy) int {
+		c := compareSortKeys(toySortKey(column, t), key)
+		if filters.SortDirection() == "DESC" {
+			c = -c
+		}
+		return cmp.Or(c, compareInt(t.ID, cursor.ID))
+	}
+
+	limit := int(filters.Limit()) + 1
+	page := []*data.Toy{}
+	if cursor.Backward {
+		for i := len(matches) - 1; i >= 0 && len(page) < limit; i-- {
+			if positionOf(matches[i]) < 0 {
+				page = append(page, matches[i])
+			}
+		}
+		return page, 0, nil
+	}
+	for _, t := range matches {
+		if len(page) == limit {
+			break
+		}
+		if positionOf(t) > 0 {
+			page = append(page, t)
+		}
+	}
+	return page, 0, nil
+}
+
+// pageCursors fills the next/prev cursors of a page of toys. hasNext and
+// hasPrev tell whether there is anything beyond either end of the page.
+func pageCursors(metadata *data.Metadata, filters data.Filters, column string, fuzzy bool, page []*data.Toy, hasNext, hasPrev bool) error {
+	if len(page) == 0 {
+		return nil
+	}
+	var err error
+	if hasNext {
+		last := page[len(page)-1]
+		c := data.Cursor{Sort: filters.Sort, ID: last.ID, Fuzzy: fuzzy}
+		if metadata.NextCursor, err = c.Encode(toySortKey(column, last)); err != nil {
+			return err
+		}
+	}
+	if hasPrev {
+		first := page[0]
+		c := data.Cursor{Sort: filters.Sort, ID: first.ID, Backward: true, Fuzzy: fuzzy}
+		if metadata.PrevCursor, err = c.Encode(toySortKey(column, first)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportToys calls fn for every toy matching the ListToy filters in q, in id
+// order. The matching toys are copied under the read lock first, so the
+// export sees one snapshot of the catalogue and fn never blocks writers; an
+// error from fn or the context stops it.
+func (s *Storage) ExportToys(ctx context.Context, q data.ToyQuery, fn func(toy *data.Toy) error) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ExportToys",
+	})
+
+	s.mu.RLock()
+	matches := s.matchToys(q, false)
+	snapshot := make([]*data.Toy, 0, len(matches))
+	for _, m := range matches {
+		t := s.toys[m.ID]
+		snapshot = append(snapshot, &data.Toy{
+			ID:             t.ID,
+			SKU:            t.SKU,
+			Title:          t.Title,
+			Desc:           t.Desc,
+			Value:          t.Value,
+			RecAge:         t.RecAge,
+			MinAgeMonths:   t.MinAgeMonths,
+			MaxAgeMonths:   t.MaxAgeMonths,
+			ManufacturerID: t.ManufacturerID,
+			Manufacturer:   m.Manufacturer,
+			Categories:     slices.Clone(t.Categories),
+			Skills:         slices.Clone(t.Skills),
+			Images:         slices.Clone(t.Images),
+			AvailableCount: m.AvailableCount,
+			IsAvailable:    m.IsAvailable,
+			CreatedAt:      t.CreatedAt,
+		})
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(snapshot, func(a, b *data.Toy) int { return compareInt(a.ID, b.ID) })
+	for _, toy := range snapshot {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(toy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToyFacets counts facets over the searched toys in Go. For each facet it
+// keeps the toys that pass every filter of the other facets and tallies
+// their values.
+func (s *Storage) ToyFacets(ctx context.Context, q data.ToyQuery, fuzzy bool) (data.Facets, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filters := s.toyFilters(q)
+	searched := s.searchToys(q.Title, fuzzy)
+	// base returns the searched toys that pass every filter but the facet's.
+	base := func(facet string) []*data.Toy {
+		var list []*data.Toy
+		for _, t := range searched {
+			if !slices.ContainsFunc(filters, func(f toyFilter) bool { return f.facet != facet && !f.match(t) }) {
+				list = append(list, t)
+			}
+		}
+		return list
+	}
+
+	return data.Facets{
+		Categories: countFacet(base(data.FacetCategory), func(t *data.Toy) []data.FacetCount {
+			return facetValues(t.Categories)
+		}),
+		Skills: countFacet(base(data.FacetSkill), func(t *data.Toy) []data.FacetCount {
+			return facetValues(t.Skills)
+		}),
+		Manufacturers: countFacet(base(data.FacetManufacturer), func(t *data.Toy) []data.FacetCount {
+			if _, ok := s.manufacturers[t.ManufacturerID]; !ok {
+				return nil
+			}
+			return []data.FacetCount{{Value: t.Manufacturer, ID: t.ManufacturerID}}
+		}),
+		AgeBands: countRanges(base(data.FacetAge), data.AgeBands, func(t *data.Toy, r data.FacetRange) bool {
+			return int64(t.MinAgeMonths) <= r.To && (t.MaxAgeMonths == 0 || int64(t.MaxAgeMonths) >= r.From)
+		}),
+		ValueBuckets: countRanges(base(data.FacetValue), data.ValueBuckets, func(t *data.Toy, r data.FacetRange) bool {
+			return t.Value >= r.From && t.Value <= r.To
+		}),
+	}, nil
+}
+
+func facetValues(values []string) []data.FacetCount {
+	counts := make([]data.FacetCount, 0, len(values))
+	for _, v := range values {
+		counts = append(counts, data.FacetCount{Value: v})
+	}
+	return counts
+}
+
+// countFacet counts the toys per facet value, most common first, and keeps
+// the top data.FacetLimit values.
+func countFacet(list []*data.Toy, values func(t *data.Toy) []data.FacetCount) []data.FacetCount {
+	type facetKey struct {
+		value string
+		id    int64
+	}
+	counts := map[facetKey]int32{}
+	for _, t := range list {
+		for _, fc := range values(t) {
+			counts[facetKey{fc.Value, fc.ID}]++
+		}
+	}
+
+	facet := []data.FacetCount{}
+	for k, count := range counts {
+		facet = append(facet, data.FacetCount{Value: k.value, ID: k.id, Count: count})
+	}
+	slices.SortFunc(facet, func(a, b data.FacetCount) int {
+		return cmp.Or(compareInt(b.Count, a.Count), strings.Compare(a.Value, b.Value), compareInt(a.ID, b.ID))
+	})
+	if len(facet) > data.FacetLimit {
+		facet = facet[:data.FacetLimit]
+	}
+	return facet
+}
+
+// countRanges counts the toys in each range, in range order, leaving out
+// ranges without toys.
+func countRanges(list []*data.Toy, ranges []data.FacetRange, in func(t *data.Toy, r data.FacetRange) bool) []data.FacetCount {
+	facet := []data.FacetCount{}
+	for _, r := range ranges {
+		var count int32
+		for _, t := range list {
+			if in(t, r) {
+				count++
+			}
+		}
+		if count > 0 {
+			facet = append(facet, data.FacetCount{Value: r.Name, Count: count, From: r.From, To: r.To})
+		}
+	}
+	return facet
+}
+
+// SuggestToys groups the live toys matching prefix by title, then adds
+// every category with a matching localized name and every matching
+// manufacturer, whether or not a toy uses them yet, and sorts the lot by
+// toy count, length and text.
+func (s *Storage) SuggestToys(ctx context.Context, prefix string, limit int) ([]*data.Suggestion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefix = strings.ToLower(prefix)
+	matches := func(term string) bool {
+		return strings.HasPrefix(strings.ToLower(term), prefix)
+	}
+
+	var suggestions []*data.Suggestion
+	titles := map[string]*data.Suggestion{}
+	for _, t := range s.toys {
+		if t.DeletedAt != "" || t.Title == "" || !matches(t.Title) {
+			continue
+		}
+		if sg, ok := titles[t.Title]; ok {
+			sg.Toys++
+			continue
+		}
+		titles[t.Title] = &data.Suggestion{Kind: data.SuggestionTitle, Text: t.Title, Toys: 1}
+		suggestions = append(suggestions, titles[t.Title])
+	}
+
+	taxonomy := s.taxonomy()
+	for _, c := range s.categories {
+		var names []string
+		for _, name := range c.Names {
+			if matches(name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		suggestions = append(suggestions, &data.Suggestion{
+			Kind: data.SuggestionCategory,
+			Text: slices.Min(names),
+			Slug: c.Slug,
+			Toys: s.countLiveToys(func(t *data.Toy) bool { return overlaps(t.Categories, taxonomy.Subtree(c.Slug)) }),
+		})
+	}
+
+	for _, m := range s.manufacturers {
+		if !matches(m.Name) {
+			continue
+		}
+		suggestions = append(suggestions, &data.Suggestion{
+			Kind: data.SuggestionManufacturer,
+			Text: m.Name,
+			ID:   m.ID,
+			Toys: s.manufacturerToys(m.ID),
+		})
+	}
+
+	slices.SortFunc(suggestions, func(a, b *data.Suggestion) int {
+		return cmp.Or(
+			compareInt(b.Toys, a.Toys),
+			compareInt(utf8.RuneCountInString(a.Text), utf8.RuneCountInString(b.Text)),
+			strings.Compare(a.Text, b.Text),
+		)
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return append([]*data.Suggestion{}, suggestions...), nil
+}
+
+func (s *Storage) countLiveToys(match func(t *data.Toy) bool) int32 {
+	var count int32
+	for _, t := range s.toys {
+		if t.DeletedAt == "" && match(t) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,16 @@
+package memory
+
+import (
+	"io"
+	"testing"
+	"toysService/internal/jsonlog"
+	"toysService/storage/storagetest"
+)
+
+func TestListToy(t *testing.T) {
+	storagetest.TestListToy(t, New(jsonlog.New(io.Discard, jsonlog.LevelFatal)))
+}
+
+func TestToyFacets(t *testing.T) {
+	storagetest.TestToyFacets(t, New(jsonlog.New(io.Discard, jsonlog.LevelFatal)))
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"toysService/internal/data"
+)
+
+func (s *Storage) AddToyUnit(ctx context.Context, unit data.ToyUnit) (data.ToyUnit, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.AddToyUnit",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if unit.Status == "" {
+		unit.Status = data.UnitStatusAvailable
+	}
+	for _, u := range s.units {
+		if u.Serial == unit.Serial {
+			return data.ToyUnit{}, data.ErrDuplicateSerial
+		}
+	}
+	// Like the foreign key, deleted toys still count as existing.
+	if _, ok := s.toys[unit.ToyID]; !ok {
+		return data.ToyUnit{}, data.ErrRecordNotFound
+	}
+
+	unit.ID = s.nextID("toy_units")
+	unit.CreatedAt = timestamp(time.Now())
+	unit.RetiredAt = ""
+	stored := unit
+	s.units[unit.ID] = &stored
+
+	if unit.Status == data.UnitStatusAvailable {
+		s.insertAvailabilityEvent(unit.ToyID)
+	}
+	return unit, nil
+}
+
+func (s *Storage) ListToyUnits(ctx context.Context, toyID int64) ([]*data.ToyUnit, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ListToyUnits",
+	})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	units := []*data.ToyUnit{}
+	for _, u := range s.units {
+		if u.ToyID == toyID {
+			c := *u
+			units = append(units, &c)
+		}
+	}
+	slices.SortFunc(units, func(a, b *data.ToyUnit) int { return compareInt(a.ID, b.ID) })
+	return units, nil
+}
+
+func (s *Storage) ChangeToyUnitStatus(ctx context.Context, unitID int64, unitStatus string) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ChangeToyUnitStatus",
+	})
+	return s.updateUnitStatus(unitID, unitStatus)
+}
+
+func (s *Storage) RetireToyUnit(ctx context.Context, unitID int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.RetireToyUnit",
+	})
+	return s.updateUnitStatus(unitID, data.UnitStatusRetired)
+}
+
+// updateUnitStatus moves a unit to newStatus and, when the unit entered or
+// left the "available" status, records an availability event for its toy.
+// Changing a retired unit fails with data.ErrUnitRetired.
+func (s *Storage) updateUnitStatus(unitID int64, newStatus string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unit, ok := s.units[unitID]
+	switch {
+	case !ok:
+		return data.ErrRecordNotFound
+	case unit.Status == data.UnitStatusRetired:
+		return data.ErrUnitRetired
+	}
+
+	oldStatus := unit.Status
+	unit.Status = newStatus
+	if newStatus == data.UnitStatusRetired {
+		unit.RetiredAt = timestamp(time.Now())
+	}
+
+	if (oldStatus == data.UnitStatusAvailable) != (newStatus == data.UnitStatusAvailable) {
+		s.insertAvailabilityEvent(unit.ToyID)
+	}
+
+	return nil
+}
+
+// ReserveToy books the first copy of the toy that is in service and free for
+// the whole requested period.
+func (s *Storage) ReserveToy(ctx context.Context, r data.Reservation) (data.Reservation, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ReserveToy",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.liveToy(r.ToyID); !ok {
+		return data.Reservation{}, data.ErrRecordNotFound
+	}
+
+	r.UnitID = 0
+	for _, u := range s.units {
+		if u.ToyID != r.ToyID || u.Status == data.UnitStatusRetired || u.Status == data.UnitStatusMaintenance || u.Status == data.UnitStatusRented {
+			continue
+		}
+		if (r.UnitID == 0 || u.ID < r.UnitID) && !s.unitBooked(u.ID, r.StartsAt, r.EndsAt) {
+			r.UnitID = u.ID
+		}
+	}
+	if r.UnitID == 0 {
+		return data.Reservation{}, data.ErrReservationConflict
+	}
+
+	r.ID = s.nextID("toy_reservations")
+	r.Status = data.ReservationStatusActive
+	r.CreatedAt = time.Now()
+	stored := r
+	s.reservations[r.ID] = &stored
+	return r, nil
+}
+
+// unitBooked reports whether an active reservation of the unit overlaps the
+// half-open period [startsAt, endsAt).
+func (s *Storage) unitBooked(unitID int64, startsAt, endsAt time.Time) bool {
+	for _, r := range s.reservations {
+		if r.UnitID == unitID && r.Status == data.ReservationStatusActive &&
+			r.StartsAt.Before(endsAt) && startsAt.Before(r.EndsAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) CancelReservation(ctx context.Context, reservationID int64, userID int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.CancelReservation",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reservations[reservationID]
+	if !ok || r.UserID != userID || r.Status != data.ReservationStatusActive {
+		return data.ErrRecordNotFound
+	}
+	r.Status = data.ReservationStatusCancelled
+	return nil
+}
+
+func (s *Storage) ListReservations(ctx context.Context, userID int64) ([]*data.Reservation, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.ListReservations",
+	})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reservations := []*data.Reservation{}
+	for _, r := range s.reservations {
+		if r.UserID == userID {
+			c := *r
+			reservations = append(reservations, &c)
+		}
+	}
+	slices.SortFunc(reservations, func(a, b *data.Reservation) int {
+		if c := b.StartsAt.Compare(a.StartsAt); c != 0 {
+			return c
+		}
+		return compareInt(b.ID, a.ID)
+	})
+	return reservations, nil
+}
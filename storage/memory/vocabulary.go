@@ -0,0 +1,576 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"toysService/internal/data"
+)
+
+// now returns the current time at the second precision of the created_at
+// columns of the dictionary tables.
+func now() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+func cloneCategory(c *data.Category) *data.Category {
+	copied := *c
+	copied.Names = maps.Clone(c.Names)
+	return &copied
+}
+
+func (s *Storage) categoryBySlug(slug string) (*data.Category, bool) {
+	for _, c := range s.categories {
+		if c.Slug == slug {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// ListCategories lists top-level categories first, then the others grouped
+// by parent.
+func (s *Storage) ListCategories(ctx context.Context) ([]*data.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.listCategories(), nil
+}
+
+func (s *Storage) listCategories() []*data.Category {
+	parentID := func(c *data.Category) int64 {
+		if p, ok := s.categoryBySlug(c.Parent); ok {
+			return p.ID
+		}
+		return 0
+	}
+
+	categories := []*data.Category{}
+	for _, c := range s.categories {
+		categories = append(categories, cloneCategory(c))
+	}
+	slices.SortFunc(categories, func(a, b *data.Category) int {
+		if c := compareInt(parentID(a), parentID(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Slug, b.Slug)
+	})
+	return categories
+}
+
+func (s *Storage) GetCategory(ctx context.Context, slug string) (data.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.categoryBySlug(slug)
+	if !ok {
+		return data.Category{}, data.ErrRecordNotFound
+	}
+	return *cloneCategory(c), nil
+}
+
+func (s *Storage) CreateCategory(ctx context.Context, c data.Category) (data.Category, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.CreateCategory",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.categoryBySlug(c.Parent); c.Parent != "" && !ok {
+		return data.Category{}, data.ErrRecordNotFound
+	}
+	if _, ok := s.categoryBySlug(c.Slug); ok {
+		return data.Category{}, data.ErrDuplicateSlug
+	}
+
+	c.ID = s.nextID("categories")
+	c.CreatedAt = now()
+	s.categories[c.ID] = cloneCategory(&c)
+	return c, nil
+}
+
+// UpdateCategory applies upd to a copy of the category, following the new
+// parent's ancestors to rule out cycles, and stores the copy once every
+// check has passed. An empty name removes its locale.
+func (s *Storage) UpdateCategory(ctx context.Context, slug string, upd data.CategoryUpdate) (data.Category, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.UpdateCategory",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.categoryBySlug(slug)
+	if !ok {
+		return data.Category{}, data.ErrRecordNotFound
+	}
+	c := cloneCategory(stored)
+
+	if upd.Parent != nil {
+		c.Parent = *upd.Parent
+	}
+	if c.Parent != "" {
+		if _, ok := s.categoryBySlug(c.Parent); !ok {
+			return data.Category{}, data.ErrRecordNotFound
+		}
+		// Walking up from the new parent must not reach the category itself.
+		for ancestor := c.Parent; ancestor != ""; {
+			if ancestor == c.Slug {
+				return data.Category{}, data.ErrCategoryCycle
+			}
+			p, _ := s.categoryBySlug(ancestor)
+			ancestor = p.Parent
+		}
+	}
+
+	for locale, name := range upd.Names {
+		if name == "" {
+			delete(c.Names, locale)
+			continue
+		}
+		c.Names[locale] = name
+	}
+	if len(c.Names) == 0 {
+		return data.Category{}, data.ErrNoCategoryNames
+	}
+
+	s.categories[c.ID] = cloneCategory(c)
+	return *c, nil
+}
+
+// DeleteCategory drops a category unless a toy, deleted or not, lists it or
+// another category has it as parent.
+func (s *Storage) DeleteCategory(ctx context.Context, slug string) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.DeleteCategory",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.toys {
+		if slices.Contains(t.Categories, slug) {
+			return data.ErrCategoryInUse
+		}
+	}
+	c, ok := s.categoryBySlug(slug)
+	if !ok {
+		return data.ErrRecordNotFound
+	}
+	for _, child := range s.categories {
+		if child.Parent == slug {
+			return data.ErrCategoryInUse
+		}
+	}
+
+	delete(s.categories, c.ID)
+	return nil
+}
+
+// Taxonomy snapshots every category for validating and expanding toy
+// categories.
+func (s *Storage) Taxonomy(ctx context.Context) (*data.Taxonomy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.taxonomy(), nil
+}
+
+func (s *Storage) taxonomy() *data.Taxonomy {
+	return data.NewTaxonomy(s.listCategories())
+}
+
+// ListSkills copies the skills, counting the live toys that list each one,
+// and sorts them by that count, then by name.
+func (s *Storage) ListSkills(ctx context.Context) ([]*data.Skill, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.listSkills(), nil
+}
+
+func (s *Storage) listSkills() []*data.Skill {
+	skills := []*data.Skill{}
+	for _, stored := range s.skills {
+		skill := *stored
+		skill.Synonyms = sortedSynonyms(stored)
+		skill.Toys = 0
+		for _, t := range s.toys {
+			if t.DeletedAt == "" && slices.Contains(t.Skills, skill.Name) {
+				skill.Toys++
+			}
+		}
+		skills = append(skills, &skill)
+	}
+	slices.SortFunc(skills, func(a, b *data.Skill) int {
+		if c := compareInt(b.Toys, a.Toys); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return skills
+}
+
+// sortedSynonyms returns a sorted copy of the skill's synonyms, never nil.
+func sortedSynonyms(skill *data.Skill) []string {
+	synonyms := append([]string{}, skill.Synonyms...)
+	slices.Sort(synonyms)
+	return synonyms
+}
+
+func (s *Storage) CreateSkill(ctx context.Context, skill data.Skill) (data.Skill, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.CreateSkill",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.skillTermsTaken(0, skill.Name, skill.Synonyms) {
+		return data.Skill{}, data.ErrDuplicateSkill
+	}
+
+	skill.ID = s.nextID("skills")
+	skill.CreatedAt = now()
+	if skill.Synonyms == nil {
+		skill.Synonyms = []string{}
+	}
+	stored := skill
+	stored.Synonyms = slices.Clone(skill.Synonyms)
+	s.skills[skill.ID] = &stored
+	return skill, nil
+}
+
+// UpdateSkill renames a skill and/or replaces its synonyms, renaming the
+// skill on every toy that lists it, deleted ones included.
+func (s *Storage) UpdateSkill(ctx context.Context, id int64, upd data.SkillUpdate) (data.Skill, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.UpdateSkill",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.skills[id]
+	if !ok {
+		return data.Skill{}, data.ErrRecordNotFound
+	}
+	skill := *stored
+	skill.Synonyms = sortedSynonyms(stored)
+
+	oldName := skill.Name
+	if upd.Name != nil {
+		skill.Name = *upd.Name
+	}
+	if upd.Synonyms != nil {
+		skill.Synonyms = upd.Synonyms
+	}
+	if s.skillTermsTaken(skill.ID, skill.Name, skill.Synonyms) {
+		return data.Skill{}, data.ErrDuplicateSkill
+	}
+
+	if skill.Name != oldName {
+		s.renameToySkill(ctx, oldName, skill.Name)
+	}
+	stored.Name = skill.Name
+	stored.Synonyms = slices.Clone(skill.Synonyms)
+	return skill, nil
+}
+
+// renameToySkill renames a skill on every toy that has it. Each toy changes
+// like an edit would: it gets a new version, an audit entry and a change
+// event.
+func (s *Storage) renameToySkill(ctx context.Context, oldName, newName string) {
+	for _, id := range slices.Sorted(maps.Keys(s.toys)) {
+		toy := s.toys[id]
+		if !slices.Contains(toy.Skills, oldName) {
+			continue
+		}
+
+		before := *s.withLiveColumns(cloneToy(toy))
+		for i, name := range toy.Skills {
+			if name == oldName {
+				toy.Skills[i] = newName
+			}
+		}
+		toy.Version++
+
+		after := before
+		after.Skills = slices.Clone(toy.Skills)
+		changes := data.DiffToys(before, after)
+		s.insertAudit(ctx, id, data.AuditActionChange, changes)
+		after.Version++
+		s.insertEvent(data.EventToyChanged, data.ToyEventPayload{ToyID: id, Toy: cloneToy(&after), Changes: changes})
+	}
+}
+
+func (s *Storage) DeleteSkill(ctx context.Context, id int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.DeleteSkill",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	skill, ok := s.skills[id]
+	if !ok {
+		return data.ErrRecordNotFound
+	}
+	for _, t := range s.toys {
+		if slices.Contains(t.Skills, skill.Name) {
+			return data.ErrSkillInUse
+		}
+	}
+
+	delete(s.skills, id)
+	return nil
+}
+
+// SkillDictionary snapshots every skill for normalizing toy skills.
+func (s *Storage) SkillDictionary(ctx context.Context) (*data.SkillDictionary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return data.NewSkillDictionary(s.listSkills()), nil
+}
+
+// skillTermsTaken reports whether another skill than id already uses the
+// name or one of the synonyms as its name or synonym, ignoring case.
+func (s *Storage) skillTermsTaken(id int64, name string, synonyms []string) bool {
+	terms := map[string]bool{strings.ToLower(name): true}
+	for _, synonym := range synonyms {
+		terms[strings.ToLower(synonym)] = true
+	}
+	for _, skill := range s.skills {
+		if skill.ID == id {
+			continue
+		}
+		if terms[strings.ToLower(skill.Name)] {
+			return true
+		}
+		for _, synonym := range skill.Synonyms {
+			if terms[strings.ToLower(synonym)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// skillVariants returns the canonical name and every synonym of the skill
+// that term names. Unknown terms only match themselves.
+func (s *Storage) skillVariants(term string) []string {
+	lower := strings.ToLower(term)
+	for _, skill := range s.skills {
+		if strings.ToLower(skill.Name) == lower || slices.ContainsFunc(skill.Synonyms, func(synonym string) bool {
+			return strings.ToLower(synonym) == lower
+		}) {
+			return append([]string{skill.Name}, skill.Synonyms...)
+		}
+	}
+	return []string{term}
+}
+
+// manufacturerToys counts the live toys of the manufacturer.
+func (s *Storage) manufacturerToys(id int64) int32 {
+	var count int32
+	for _, t := range s.toys {
+		if t.ManufacturerID == id && t.DeletedAt == "" {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Storage) manufacturerByKey(name string, except int64) (*data.Manufacturer, bool) {
+	key := data.ManufacturerKey(name)
+	for _, m := range s.manufacturers {
+		if m.ID != except && data.ManufacturerKey(m.Name) == key {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// registerManufacturer gives a toy whose manufacturer NormalizeToy did not
+// know the ID of the brand with that name, creating the brand if it still
+// does not exist.
+func (s *Storage) registerManufacturer(toy *data.Toy) {
+	if toy.ManufacturerID != 0 || toy.Manufacturer == "" {
+		return
+	}
+	m, ok := s.manufacturerByKey(toy.Manufacturer, 0)
+	if !ok {
+		m = &data.Manufacturer{ID: s.nextID("manufacturers"), Name: toy.Manufacturer, CreatedAt: now()}
+		s.manufacturers[m.ID] = m
+	}
+	toy.ManufacturerID, toy.Manufacturer = m.ID, m.Name
+}
+
+func (s *Storage) ListManufacturers(ctx context.Context) ([]*data.Manufacturer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	manufacturers := []*data.Manufacturer{}
+	for _, stored := range s.manufacturers {
+		m := *stored
+		m.Toys = s.manufacturerToys(m.ID)
+		manufacturers = append(manufacturers, &m)
+	}
+	slices.SortFunc(manufacturers, func(a, b *data.Manufacturer) int {
+		return cmp.Or(strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)), compareInt(a.ID, b.ID))
+	})
+	return manufacturers, nil
+}
+
+// GetBrandPage returns a manufacturer with its toy count and its live toys,
+// the most rented first. Rentals are the reservations that were not
+// cancelled, as in ListRecommended.
+func (s *Storage) GetBrandPage(ctx context.Context, id int64) (data.BrandPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.manufacturers[id]
+	if !ok {
+		return data.BrandPage{}, data.ErrRecordNotFound
+	}
+	m := *stored
+	m.Toys = s.manufacturerToys(id)
+
+	page := data.BrandPage{Manufacturer: m, TopToys: []*data.Toy{}}
+	for _, t := range s.toys {
+		if t.ManufacturerID != id || t.DeletedAt != "" {
+			continue
+		}
+		available := s.availableCount(t.ID)
+		page.TopToys = append(page.TopToys, &data.Toy{
+			ID:             t.ID,
+			Title:          t.Title,
+			Categories:     slices.Clone(t.Categories),
+			Skills:         slices.Clone(t.Skills),
+			RecAge:         t.RecAge,
+			MinAgeMonths:   t.MinAgeMonths,
+			MaxAgeMonths:   t.MaxAgeMonths,
+			Value:          t.Value,
+			Manufacturer:   m.Name,
+			ManufacturerID: m.ID,
+			AvailableCount: available,
+			IsAvailable:    available > 0,
+		})
+	}
+	rentals := map[int64]int64{}
+	for _, r := range s.reservations {
+		if r.Status == data.ReservationStatusActive {
+			rentals[r.ToyID]++
+		}
+	}
+	slices.SortFunc(page.TopToys, func(a, b *data.Toy) int {
+		if c := compareInt(rentals[b.ID], rentals[a.ID]); c != 0 {
+			return c
+		}
+		return compareInt(a.ID, b.ID)
+	})
+	if len(page.TopToys) > data.TopToysLimit {
+		page.TopToys = page.TopToys[:data.TopToysLimit]
+	}
+	return page, nil
+}
+
+func (s *Storage) CreateManufacturer(ctx context.Context, m data.Manufacturer) (data.Manufacturer, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.CreateManufacturer",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.manufacturerByKey(m.Name, 0); ok {
+		return data.Manufacturer{}, data.ErrDuplicateManufacturer
+	}
+
+	m.ID = s.nextID("manufacturers")
+	m.CreatedAt = now()
+	stored := m
+	s.manufacturers[m.ID] = &stored
+	return m, nil
+}
+
+// UpdateManufacturer changes the given fields. Toys only keep the
+// manufacturer's ID, so a rename shows up on all of them.
+func (s *Storage) UpdateManufacturer(ctx context.Context, id int64, upd data.ManufacturerUpdate) (data.Manufacturer, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.UpdateManufacturer",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.manufacturers[id]
+	if !ok {
+		return data.Manufacturer{}, data.ErrRecordNotFound
+	}
+	if upd.Name != nil {
+		if _, ok := s.manufacturerByKey(*upd.Name, id); ok {
+			return data.Manufacturer{}, data.ErrDuplicateManufacturer
+		}
+		stored.Name = *upd.Name
+	}
+	if upd.Country != nil {
+		stored.Country = *upd.Country
+	}
+	if upd.LogoURL != nil {
+		stored.LogoURL = *upd.LogoURL
+	}
+	if upd.Description != nil {
+		stored.Description = *upd.Description
+	}
+
+	m := *stored
+	m.Toys = s.manufacturerToys(id)
+	return m, nil
+}
+
+// DeleteManufacturer drops a manufacturer unless a toy, deleted or not,
+// still refers to it.
+func (s *Storage) DeleteManufacturer(ctx context.Context, id int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "memory.DeleteManufacturer",
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.manufacturers[id]; !ok {
+		return data.ErrRecordNotFound
+	}
+	for _, t := range s.toys {
+		if t.ManufacturerID == id {
+			return data.ErrManufacturerInUse
+		}
+	}
+
+	delete(s.manufacturers, id)
+	return nil
+}
+
+// ManufacturerDirectory snapshots every manufacturer for resolving the
+// manufacturer names of toys being written.
+func (s *Storage) ManufacturerDirectory(ctx context.Context) (*data.ManufacturerDirectory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.manufacturerDirectory(), nil
+}
+
+func (s *Storage) manufacturerDirectory() *data.ManufacturerDirectory {
+	var manufacturers []*data.Manufacturer
+	for _, stored := range s.manufacturers {
+		m := *stored
+		manufacturers = append(manufacturers, &m)
+	}
+	return data.NewManufacturerDirectory(manufacturers)
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"strconv"
+	"time"
+	"toysService/internal/data"
+)
+
+// batchTimeout bounds batch writes and import batches, which run many
+// statements in a single transaction.
+const batchTimeout = 30 * time.Second
+
+// CreateToys inserts validated toys in one transaction. See writeBatch for
+// how atomic and best-effort batches differ.
+func (s *Storage) CreateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.CreateToys",
+		"items":  strconv.Itoa(len(items)),
+	})
+
+	return s.writeBatch(ctx, items, atomic, func(ctx context.Context, tx *sql.Tx, toy data.Toy) (data.Toy, error) {
+		return insertToy(ctx, tx, toy)
+	})
+}
+
+// LoadToys reads the live toys a batch update applies its changes to, keyed
+// by id, in one query. Missing and deleted toys are left out.
+func (s *Storage) LoadToys(ctx context.Context, ids []int64) (map[int64]data.Toy, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.LoadToys",
+		"items":  strconv.Itoa(len(ids)),
+	})
+
+	query := `
+SELECT ` + lockedToyColumns + `
+FROM toys
+WHERE id = ANY($1) AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	found := make(map[int64]data.Toy, len(ids))
+	err := s.read(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			toy, err := scanLockedToy(rows)
+			if err != nil {
+				return err
+			}
+			found[toy.ID] = toy
+		}
+		return rows.Err()
+	})
+	return found, err
+}
+
+// UpdateToys changes validated toys in one transaction. Like ChangeToy, every
+// item carries the version it was read at and fails with
+// data.ErrEditConflict if the toy has changed since.
+func (s *Storage) UpdateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.UpdateToys",
+		"items":  strconv.Itoa(len(items)),
+	})
+
+	return s.writeBatch(ctx, items, atomic, func(ctx context.Context, tx *sql.Tx, toy data.Toy) (data.Toy, error) {
+		before, err := getToyForUpdate(ctx, tx, toy.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return data.Toy{}, data.ErrRecordNotFound
+			default:
+				return data.Toy{}, err
+			}
+		}
+		if before.Version != toy.Version {
+			return data.Toy{}, data.ErrEditConflict
+		}
+		if _, err = updateToy(ctx, tx, before, &toy); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return data.Toy{}, data.ErrEditConflict
+			default:
+				return data.Toy{}, err
+			}
+		}
+		return toy, nil
+	})
+}
+
+// writeBatch runs write for every item inside one transaction. An atomic
+// batch stops at the first failing item, rolls everything back and marks
+// the other items data.ErrBatchAborted. A best-effort batch rolls a failing
+// item back to its savepoint and carries on, committing the rest.
+func (s *Storage) writeBatch(ctx context.Context, items []data.BatchItem, atomic bool, write func(ctx context.Context, tx *sql.Tx, toy data.Toy) (data.Toy, error)) ([]data.BatchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, batchTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]data.BatchResult, len(items))
+	for i, item := range items {
+		results[i].Index = item.Index
+	}
+	for i, item := range items {
+		if !atomic {
+			if _, err = tx.ExecContext(ctx, `SAVEPOINT batch_item`); err != nil {
+				return nil, err
+			}
+		}
+
+		toy, err := write(ctx, tx, item.Toy)
+		if err != nil {
+			results[i].Err = err
+			if atomic {
+				return abortBatch(results, i), nil
+			}
+			if _, rerr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_item`); rerr != nil {
+				return nil, rerr
+			}
+			continue
+		}
+		results[i].ID, results[i].Version = toy.ID, toy.Version
+	}
+
+	return results, tx.Commit()
+}
+
+// abortBatch marks every item but the failed one as not written.
+func abortBatch(results []data.BatchResult, failed int) []data.BatchResult {
+	for i := range results {
+		if i != failed {
+			results[i].Err = data.ErrBatchAborted
+			results[i].ID, results[i].Version = 0, 0
+		}
+	}
+	return results
+}
@@ -0,0 +1,361 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strconv"
+	"time"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+	"toysService/internal/validator"
+	"unicode"
+)
+
+func ValidateCategory(v *validator.Validator, c *data.Category) {
+	v.Check(c.Slug != "", "slug", "slug must be provided")
+	v.Check(len(c.Slug) <= 64, "slug", "slug must not be more than 64 bytes long")
+	v.Check(data.CategorySlugRX.MatchString(c.Slug), "slug", "slug may only contain lowercase latin letters, digits and dashes")
+	v.Check(c.Parent != c.Slug, "parent", "category cannot be its own parent")
+	ValidateCategoryNames(v, c.Names, true)
+}
+
+// ValidateCategoryNames checks localized names. Partial updates may pass
+// empty names to drop a locale, so required is false for them.
+func ValidateCategoryNames(v *validator.Validator, names map[string]string, required bool) {
+	v.Check(!required || len(names) > 0, "names", "at least one localized name must be provided")
+	for locale, name := range names {
+		v.Check(len(locale) >= 2 && len(locale) <= 5, "names", "locale must be a language code like \"ru\" or \"en\"")
+		v.Check(!required || name != "", "names", "names must not be empty")
+		v.Check(len(name) <= 100, "names", "names must not be more than 100 bytes long")
+	}
+}
+
+const categoryColumns = `c.id, c.slug, coalesce(p.slug, ''), c.names, c.created_at`
+
+func scanCategory(row interface{ Scan(...any) error }) (*data.Category, error) {
+	var c data.Category
+	var names []byte
+	if err := row.Scan(&c.ID, &c.Slug, &c.Parent, &names, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(names, &c.Names); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *Storage) ListCategories(ctx context.Context) ([]*data.Category, error) {
+	query := `
+SELECT ` + categoryColumns + `
+FROM categories c
+LEFT JOIN categories p ON p.id = c.parent_id
+ORDER BY c.parent_id NULLS FIRST, c.slug`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*data.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (s *Storage) GetCategory(ctx context.Context, slug string) (data.Category, error) {
+	query := `
+SELECT ` + categoryColumns + `
+FROM categories c
+LEFT JOIN categories p ON p.id = c.parent_id
+WHERE c.slug = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	c, err := scanCategory(s.db.QueryRowContext(ctx, query, slug))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Category{}, data.ErrRecordNotFound
+		default:
+			return data.Category{}, err
+		}
+	}
+	return *c, nil
+}
+
+func (s *Storage) CreateCategory(ctx context.Context, c data.Category) (data.Category, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.CreateCategory",
+	})
+
+	names, err := json.Marshal(c.Names)
+	if err != nil {
+		return data.Category{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Category{}, err
+	}
+	defer tx.Rollback()
+
+	parentID, err := categoryID(ctx, tx, c.Parent)
+	if err != nil {
+		return data.Category{}, err
+	}
+
+	query := `
+INSERT INTO categories (slug, parent_id, names)
+VALUES ($1, $2, $3)
+RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query, c.Slug, parentID, names).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23505":
+			return data.Category{}, data.ErrDuplicateSlug
+		default:
+			return data.Category{}, err
+		}
+	}
+
+	return c, tx.Commit()
+}
+
+// UpdateCategory moves a category and/or changes its names. Names are merged
+// per locale; an empty name removes that locale.
+func (s *Storage) UpdateCategory(ctx context.Context, slug string, upd data.CategoryUpdate) (data.Category, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.UpdateCategory",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Category{}, err
+	}
+	defer tx.Rollback()
+
+	query := `
+SELECT ` + categoryColumns + `
+FROM categories c
+LEFT JOIN categories p ON p.id = c.parent_id
+WHERE c.slug = $1
+FOR UPDATE OF c`
+
+	c, err := scanCategory(tx.QueryRowContext(ctx, query, slug))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Category{}, data.ErrRecordNotFound
+		default:
+			return data.Category{}, err
+		}
+	}
+
+	if upd.Parent != nil {
+		c.Parent = *upd.Parent
+	}
+	parentID, err := categoryID(ctx, tx, c.Parent)
+	if err != nil {
+		return data.Category{}, err
+	}
+	if parentID.Valid {
+		// Walking up from the new parent must not reach the category itself.
+		var cycle bool
+		err = tx.QueryRowContext(ctx, `
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id FROM categories WHERE id = $1
+    UNION
+    SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`, parentID.Int64, c.ID).Scan(&cycle)
+		if err != nil {
+			return data.Category{}, err
+		}
+		if cycle {
+			return data.Category{}, data.ErrCategoryCycle
+		}
+	}
+
+	for locale, name := range upd.Names {
+		if name == "" {
+			delete(c.Names, locale)
+			continue
+		}
+		c.Names[locale] = name
+	}
+	if len(c.Names) == 0 {
+		return data.Category{}, data.ErrNoCategoryNames
+	}
+	names, err := json.Marshal(c.Names)
+	if err != nil {
+		return data.Category{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE categories SET parent_id = $1, names = $2 WHERE id = $3`, parentID, names, c.ID)
+	if err != nil {
+		return data.Category{}, err
+	}
+
+	return *c, tx.Commit()
+}
+
+// DeleteCategory removes a category that no toy (deleted ones included) and
+// no subcategory refers to any more.
+func (s *Storage) DeleteCategory(ctx context.Context, slug string) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.DeleteCategory",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM toys WHERE categories @> ARRAY[$1::text])`, slug).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return data.ErrCategoryInUse
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM categories WHERE slug = $1`, slug)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23503":
+			return data.ErrCategoryInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return data.ErrRecordNotFound
+	}
+	return tx.Commit()
+}
+
+// Taxonomy loads every category for validating and expanding toy categories.
+func (s *Storage) Taxonomy(ctx context.Context) (*data.Taxonomy, error) {
+	categories, err := s.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return data.NewTaxonomy(categories), nil
+}
+
+// categoryID resolves a parent slug; an empty slug means no parent.
+func categoryID(ctx context.Context, tx *sql.Tx, slug string) (sql.NullInt64, error) {
+	if slug == "" {
+		return sql.NullInt64{}, nil
+	}
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM categories WHERE slug = $1`, slug).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return sql.NullInt64{}, data.ErrRecordNotFound
+		default:
+			return sql.NullInt64{}, err
+		}
+	}
+	return sql.NullInt64{Int64: id, Valid: true}, nil
+}
+
+// backfillCategories builds the initial taxonomy from the free-text category
+// values of existing toys and rewrites those values to slugs. Spellings of the
+// same words become one category named after its most used spelling.
+// Translations such as "Building sets" stay separate categories and can be
+// merged by hand.
+func backfillCategories(ctx context.Context, tx *sql.Tx, log *jsonlog.Logger) error {
+	groups, err := spellingGroups(ctx, tx, "categories")
+	if err != nil {
+		return err
+	}
+
+	slugs := map[string]bool{}
+	var values, valueSlugs []string
+	for _, group := range groups {
+		slug := uniqueSlug(data.Slugify(group[0]), slugs)
+		names := map[string]string{}
+		for _, value := range group {
+			if locale := nameLocale(value); names[locale] == "" {
+				names[locale] = value
+			}
+			values = append(values, value)
+			valueSlugs = append(valueSlugs, slug)
+		}
+
+		namesJSON, err := json.Marshal(names)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, `INSERT INTO categories (slug, names) VALUES ($1, $2)`, slug, namesJSON); err != nil {
+			return err
+		}
+	}
+
+	if err = rewriteToyValues(ctx, tx, "categories", values, valueSlugs); err != nil {
+		return err
+	}
+
+	log.PrintInfo("categories backfilled", map[string]string{
+		"values":     strconv.Itoa(len(values)),
+		"categories": strconv.Itoa(len(groups)),
+	})
+	return nil
+}
+
+func uniqueSlug(slug string, taken map[string]bool) string {
+	if slug == "" {
+		slug = "category"
+	}
+	candidate := slug
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", slug, i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// nameLocale guesses the locale of a category name from its script.
+func nameLocale(name string) string {
+	for _, r := range name {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return "ru"
+		}
+	}
+	return "en"
+}
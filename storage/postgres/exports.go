@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"toysService/internal/data"
+)
+
+// exportFetchSize is how many rows an export pulls from its cursor at a time,
+// which is all it ever holds in memory.
+const exportFetchSize = 500
+
+// ExportToys calls fn for every toy matching the ListToy filters in q, in id
+// order. The rows are read through a server-side cursor in a read-only
+// transaction, so the export sees one snapshot of the catalogue however long
+// it takes. There is no timeout; the caller's context bounds the export, and
+// an error from fn stops it.
+func (s *Storage) ExportToys(ctx context.Context, q data.ToyQuery, fn func(toy *data.Toy) error) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.ExportToys",
+	})
+
+	args := []any{q.Title}
+	query := `
+DECLARE toy_export NO SCROLL CURSOR FOR
+SELECT id, COALESCE(sku, ''), title, description, value, recommended_age, min_age_months, COALESCE(max_age_months, 0), ` + manufacturerColumns + `,
+    categories, skills, images, ` + availableUnits + `, created_at
+FROM toys
+WHERE deleted_at IS NULL
+AND ($1 = '' OR ` + fullTextSearch.match + `)`
+	for _, f := range toyFilters(q, &args) {
+		query += " AND " + f.Cond
+	}
+	query += " ORDER BY id"
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf(`FETCH FORWARD %d FROM toy_export`, exportFetchSize)
+	for {
+		n, err := exportBatch(ctx, tx, fetch, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+func exportBatch(ctx context.Context, tx *sql.Tx, fetch string, fn func(toy *data.Toy) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var toy data.Toy
+		err := rows.Scan(
+			&toy.ID,
+			&toy.SKU,
+			&toy.Title,
+			&toy.Desc,
+			&toy.Value,
+			&toy.RecAge,
+			&toy.MinAgeMonths,
+			&toy.MaxAgeMonths,
+			&toy.ManufacturerID,
+			&toy.Manufacturer,
+			pq.Array(&toy.Categories),
+			pq.Array(&toy.Skills),
+			pq.Array(&toy.Images),
+			&toy.AvailableCount,
+			&toy.CreatedAt,
+		)
+		if err != nil {
+			return n, err
+		}
+		toy.IsAvailable = toy.AvailableCount > 0
+		n++
+		if err = fn(&toy); err != nil {
+			return n, err
+		}
+	}
+	return n, rows.Err()
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"time"
+	"toysService/internal/data"
+	"toysService/storage/facetsql"
+)
+
+// facetDialect unnests the category and skill arrays and casts the constant
+// columns to bigint, so every branch of the UNION has the same types.
+var facetDialect = facetsql.Dialect{
+	Sources: map[string]string{
+		data.FacetCategory: `SELECT v AS name, 0::bigint AS id, 0::bigint AS lo, 0::bigint AS hi FROM base, unnest(base.categories) AS v WHERE %s`,
+		data.FacetSkill:    `SELECT v AS name, 0::bigint AS id, 0::bigint AS lo, 0::bigint AS hi FROM base, unnest(base.skills) AS v WHERE %s`,
+		data.FacetManufacturer: `SELECT m.name AS name, m.id AS id, 0::bigint AS lo, 0::bigint AS hi FROM base JOIN manufacturers m ON m.id = base.manufacturer_id
+    WHERE %s`,
+		data.FacetAge: `SELECT b.name AS name, 0::bigint AS id, b.lo AS lo, b.hi AS hi FROM base JOIN age_bands b
+    ON base.min_age_months <= b.hi AND (base.max_age_months IS NULL OR base.max_age_months >= b.lo) WHERE %s`,
+		data.FacetValue: `SELECT b.name AS name, 0::bigint AS id, b.lo AS lo, b.hi AS hi FROM base JOIN value_buckets b
+    ON base.value BETWEEN b.lo AND b.hi WHERE %s`,
+	},
+	Int: "%d::bigint",
+}
+
+// ToyFacets counts the toys matching q per category, skill, manufacturer, age
+// band and value bucket in one query, each facet leaving out its own filters.
+// A fuzzy count runs on a connection with the trigram threshold that ListToy
+// used for its fallback.
+func (s *Storage) ToyFacets(ctx context.Context, q data.ToyQuery, fuzzy bool) (data.Facets, error) {
+	search := fullTextSearch
+	if fuzzy {
+		search = fuzzySearch
+	}
+
+	args := []any{q.Title}
+	query := facetDialect.Query(search.match, toyFilters(q, &args))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	db, done, err := searchQueryer(ctx, s.db, search)
+	if err != nil {
+		return data.Facets{}, err
+	}
+	defer done()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return data.Facets{}, err
+	}
+	defer rows.Close()
+
+	return facetsql.Scan(rows)
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"toysService/internal/jsonlog"
+	"toysService/migrations"
+	"toysService/storage/storagetest"
+)
+
+// newTestStorage migrates the database in TOYS_TEST_DSN and rolls it back
+// once the test is over. The database must be a throwaway one: the test
+// writes to it and rolling back drops every table. Without TOYS_TEST_DSN
+// the test is skipped.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	dsn := os.Getenv("TOYS_TEST_DSN")
+	if dsn == "" {
+		t.Skip("TOYS_TEST_DSN is not set")
+	}
+	s, err := OpenDB(StorageDetails{
+		DSN:          dsn,
+		MaxOpenConns: 5,
+		MaxIdleConns: 5,
+		MaxIdleTime:  "1m",
+	}, jsonlog.New(io.Discard, jsonlog.LevelFatal))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := s.Migrator(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := m.Goto(context.Background(), 0); err != nil {
+			t.Error(err)
+		}
+		s.db.Close()
+	})
+	return s
+}
+
+func TestToyFacets(t *testing.T) {
+	storagetest.TestToyFacets(t, newTestStorage(t))
+}
@@ -0,0 +1,247 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strconv"
+	"time"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+	"toysService/internal/validator"
+)
+
+// ValidateToyImages checks the images being attached to a toy, in order.
+func ValidateToyImages(v *validator.Validator, items []data.ToyImage) {
+	v.Check(len(items) <= data.MaxToyImages, "images", fmt.Sprintf("no more than %d images", data.MaxToyImages))
+	ids := make([]int64, 0, len(items))
+	primaries := 0
+	for _, item := range items {
+		v.Check(item.ID > 0, "images", "image ids must be positive")
+		v.Check(len(item.Alt) <= 500, "alt", "alt text must not be more than 500 bytes long")
+		ids = append(ids, item.ID)
+		if item.Primary {
+			primaries++
+		}
+	}
+	v.Check(validator.Unique(ids), "images", "images should not contain duplicate ids")
+	v.Check(primaries <= 1, "primary", "only one image can be primary")
+}
+
+// reportInsecureImages logs every plain http:// image URL a toy still links.
+// Such toys fail validation on their next edit, and whether the host serves
+// the image over https as well is not something a migration can tell, so
+// the URLs are left for someone to fix by hand.
+func reportInsecureImages(ctx context.Context, tx *sql.Tx, log *jsonlog.Logger) error {
+	rows, err := tx.QueryContext(ctx, `
+SELECT t.id, url
+FROM toys t, unnest(t.images) AS url
+WHERE url ILIKE 'http://%'
+ORDER BY t.id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var url string
+		if err = rows.Scan(&id, &url); err != nil {
+			return err
+		}
+		log.PrintInfo("toy links an image over plain http", map[string]string{
+			"toy_id": strconv.FormatInt(id, 10),
+			"url":    url,
+		})
+	}
+	return rows.Err()
+}
+
+const imageColumns = `id, COALESCE(toy_id, 0), blob_key, thumbnail_key, url, thumbnail_url, content_type, width, height, size_bytes, alt, position, is_primary, created_at`
+
+func scanImage(row interface{ Scan(...any) error }) (*data.ToyImage, error) {
+	var img data.ToyImage
+	err := row.Scan(&img.ID, &img.ToyID, &img.Key, &img.ThumbnailKey, &img.URL, &img.ThumbnailURL, &img.ContentType,
+		&img.Width, &img.Height, &img.Size, &img.Alt, &img.Position, &img.Primary, &img.CreatedAt)
+	return &img, err
+}
+
+// InsertImage records an uploaded image whose blobs are already stored.
+func (s *Storage) InsertImage(ctx context.Context, img data.ToyImage) (data.ToyImage, error) {
+	query := `
+INSERT INTO toy_images (blob_key, thumbnail_key, url, thumbnail_url, content_type, width, height, size_bytes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, img.Key, img.ThumbnailKey, img.URL, img.ThumbnailURL, img.ContentType,
+		img.Width, img.Height, img.Size).Scan(&img.ID, &img.CreatedAt)
+	if err != nil {
+		return data.ToyImage{}, err
+	}
+	return img, nil
+}
+
+func (s *Storage) ListToyImages(ctx context.Context, toyID int64) ([]*data.ToyImage, error) {
+	query := `SELECT ` + imageColumns + ` FROM toy_images WHERE toy_id = $1 ORDER BY position, id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, toyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []*data.ToyImage{}
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// SetToyImages makes items, in order, the images of the toy. Images left out
+// are detached, and the first image is primary unless another one is marked.
+// The toy's images column is rewritten to the image URLs so the gRPC API keeps
+// seeing them, which counts as a change of the toy.
+func (s *Storage) SetToyImages(ctx context.Context, toyID int64, items []data.ToyImage) ([]*data.ToyImage, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.SetToyImages",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := getToyForUpdate(ctx, tx, toyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	primary := -1
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+		if item.Primary {
+			primary = i
+		}
+	}
+	if primary < 0 && len(items) > 0 {
+		primary = 0
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, COALESCE(toy_id, 0) FROM toy_images WHERE id = ANY($1) FOR UPDATE`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	found := map[int64]bool{}
+	for rows.Next() {
+		var id, owner int64
+		if err = rows.Scan(&id, &owner); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if owner != 0 && owner != toyID {
+			rows.Close()
+			return nil, data.ErrImageAttached
+		}
+		found[id] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(found) != len(ids) {
+		return nil, data.ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+UPDATE toy_images SET toy_id = NULL, position = 0, is_primary = false
+WHERE toy_id = $1 AND NOT (id = ANY($2))`, toyID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	// Clear the primary flag first so the partial unique index never sees two.
+	if _, err = tx.ExecContext(ctx, `UPDATE toy_images SET is_primary = false WHERE toy_id = $1`, toyID); err != nil {
+		return nil, err
+	}
+
+	images := make([]*data.ToyImage, 0, len(items))
+	urls := make([]string, 0, len(items))
+	for i, item := range items {
+		img, err := scanImage(tx.QueryRowContext(ctx, `
+UPDATE toy_images SET toy_id = $1, position = $2, alt = $3, is_primary = $4
+WHERE id = $5
+RETURNING `+imageColumns, toyID, i, item.Alt, i == primary, item.ID))
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+		urls = append(urls, img.URL)
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE toys SET images = $1, version = version + 1 WHERE id = $2`, pq.Array(urls), toyID); err != nil {
+		return nil, err
+	}
+
+	after := before
+	after.Images = urls
+	changes := data.DiffToys(before, after)
+	if err = insertAudit(ctx, tx, toyID, data.AuditActionChange, changes); err != nil {
+		return nil, err
+	}
+	after.Version++
+	if err = insertEvent(ctx, tx, data.EventToyChanged, data.ToyEventPayload{ToyID: toyID, Toy: &after, Changes: changes}); err != nil {
+		return nil, err
+	}
+
+	return images, tx.Commit()
+}
+
+// DeleteImage removes an image that is not attached to any toy and returns it
+// so its blobs can be deleted too.
+func (s *Storage) DeleteImage(ctx context.Context, id int64) (data.ToyImage, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.DeleteImage",
+	})
+	query := `DELETE FROM toy_images WHERE id = $1 AND toy_id IS NULL RETURNING ` + imageColumns
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	img, err := scanImage(s.db.QueryRowContext(ctx, query, id))
+	if err == nil {
+		return *img, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return data.ToyImage{}, err
+	}
+
+	var exists bool
+	if err = s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM toy_images WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return data.ToyImage{}, err
+	}
+	if exists {
+		return data.ToyImage{}, data.ErrImageAttached
+	}
+	return data.ToyImage{}, data.ErrRecordNotFound
+}
@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+)
+
+func ValidateManufacturer(v *validator.Validator, m *data.Manufacturer) {
+	v.Check(strings.TrimSpace(m.Name) != "", "name", "name must be provided")
+	v.Check(len(m.Name) <= 200, "name", "name must not be more than 200 bytes long")
+	v.Check(len(m.Country) <= 100, "country", "country must not be more than 100 bytes long")
+	v.Check(m.LogoURL == "" || strings.HasPrefix(m.LogoURL, "https://"), "logoUrl", "logo url must start with https://")
+	v.Check(len(m.LogoURL) <= 2000, "logoUrl", "logo url must not be more than 2000 bytes long")
+	v.Check(len(m.Description) <= 5000, "description", "description must not be more than 5000 bytes long")
+}
+
+// registerManufacturer gives a toy whose manufacturer NormalizeToy did not
+// know the ID of the brand with that name, creating the brand if it still
+// does not exist.
+func registerManufacturer(ctx context.Context, tx *sql.Tx, toy *data.Toy) error {
+	if toy.ManufacturerID != 0 || toy.Manufacturer == "" {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO manufacturers (name) VALUES ($1) ON CONFLICT DO NOTHING`, toy.Manufacturer); err != nil {
+		return err
+	}
+	query := `SELECT id, name FROM manufacturers WHERE manufacturer_key(name) = manufacturer_key($1)`
+	return tx.QueryRowContext(ctx, query, toy.Manufacturer).Scan(&toy.ManufacturerID, &toy.Manufacturer)
+}
+
+// nullID stores a missing manufacturer as NULL.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id > 0}
+}
+
+// manufacturerToys counts the live toys of the manufacturer in the current row.
+const manufacturerToys = `(SELECT count(*) FROM toys WHERE toys.manufacturer_id = m.id AND toys.deleted_at IS NULL)`
+
+const manufacturerSelect = `
+SELECT m.id, m.name, m.country, m.logo_url, m.description, ` + manufacturerToys + `, m.created_at
+FROM manufacturers m`
+
+func scanManufacturer(row interface{ Scan(...any) error }) (*data.Manufacturer, error) {
+	var m data.Manufacturer
+	err := row.Scan(&m.ID, &m.Name, &m.Country, &m.LogoURL, &m.Description, &m.Toys, &m.CreatedAt)
+	return &m, err
+}
+
+func (s *Storage) ListManufacturers(ctx context.Context) ([]*data.Manufacturer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, manufacturerSelect+` ORDER BY lower(m.name)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	manufacturers := []*data.Manufacturer{}
+	for rows.Next() {
+		m, err := scanManufacturer(rows)
+		if err != nil {
+			return nil, err
+		}
+		manufacturers = append(manufacturers, m)
+	}
+	return manufacturers, rows.Err()
+}
+
+// GetBrandPage returns a manufacturer with its toy count and its most rented
+// live toys.
+func (s *Storage) GetBrandPage(ctx context.Context, id int64) (data.BrandPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	m, err := scanManufacturer(s.db.QueryRowContext(ctx, manufacturerSelect+` WHERE m.id = $1`, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.BrandPage{}, data.ErrRecordNotFound
+		default:
+			return data.BrandPage{}, err
+		}
+	}
+
+	query := `
+SELECT id, title, categories, skills, recommended_age, min_age_months, COALESCE(max_age_months, 0), value, ` + availableUnits + `
+FROM toys
+WHERE manufacturer_id = $1 AND deleted_at IS NULL
+ORDER BY (SELECT count(*) FROM toy_rentals r WHERE r.toy_id = toys.id) DESC, id
+LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, id, data.TopToysLimit)
+	if err != nil {
+		return data.BrandPage{}, err
+	}
+	defer rows.Close()
+
+	page := data.BrandPage{Manufacturer: *m, TopToys: []*data.Toy{}}
+	for rows.Next() {
+		toy := data.Toy{Manufacturer: m.Name, ManufacturerID: m.ID}
+		err := rows.Scan(
+			&toy.ID,
+			&toy.Title,
+			pq.Array(&toy.Categories),
+			pq.Array(&toy.Skills),
+			&toy.RecAge,
+			&toy.MinAgeMonths,
+			&toy.MaxAgeMonths,
+			&toy.Value,
+			&toy.AvailableCount,
+		)
+		if err != nil {
+			return data.BrandPage{}, err
+		}
+		toy.IsAvailable = toy.AvailableCount > 0
+		page.TopToys = append(page.TopToys, &toy)
+	}
+	return page, rows.Err()
+}
+
+func (s *Storage) CreateManufacturer(ctx context.Context, m data.Manufacturer) (data.Manufacturer, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.CreateManufacturer",
+	})
+	query := `
+INSERT INTO manufacturers (name, country, logo_url, description)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, m.Name, m.Country, m.LogoURL, m.Description).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return data.Manufacturer{}, manufacturerError(err)
+	}
+	return m, nil
+}
+
+// UpdateManufacturer changes the given fields. Renaming a manufacturer renames
+// it on all of its toys, since they only keep its ID.
+func (s *Storage) UpdateManufacturer(ctx context.Context, id int64, upd data.ManufacturerUpdate) (data.Manufacturer, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.UpdateManufacturer",
+	})
+	query := `
+UPDATE manufacturers m
+SET name = COALESCE($1, name), country = COALESCE($2, country), logo_url = COALESCE($3, logo_url), description = COALESCE($4, description)
+WHERE id = $5
+RETURNING m.id, m.name, m.country, m.logo_url, m.description, ` + manufacturerToys + `, m.created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	m, err := scanManufacturer(s.db.QueryRowContext(ctx, query, upd.Name, upd.Country, upd.LogoURL, upd.Description, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Manufacturer{}, data.ErrRecordNotFound
+		default:
+			return data.Manufacturer{}, manufacturerError(err)
+		}
+	}
+	return *m, nil
+}
+
+// DeleteManufacturer removes a manufacturer no toy refers to any more,
+// deleted toys included.
+func (s *Storage) DeleteManufacturer(ctx context.Context, id int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.DeleteManufacturer",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM manufacturers WHERE id = $1`, id)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23503":
+			return data.ErrManufacturerInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return data.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ManufacturerDirectory loads every manufacturer for resolving the
+// manufacturer names of toys being written.
+func (s *Storage) ManufacturerDirectory(ctx context.Context) (*data.ManufacturerDirectory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name FROM manufacturers`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var manufacturers []*data.Manufacturer
+	for rows.Next() {
+		var m data.Manufacturer
+		if err = rows.Scan(&m.ID, &m.Name); err != nil {
+			return nil, err
+		}
+		manufacturers = append(manufacturers, &m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return data.NewManufacturerDirectory(manufacturers), nil
+}
+
+func manufacturerError(err error) error {
+	var pqErr *pq.Error
+	switch {
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		return data.ErrDuplicateManufacturer
+	default:
+		return err
+	}
+}
@@ -0,0 +1,303 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"toysService/internal/jsonlog"
+)
+
+// migrateLockKey is the advisory lock every migrator takes before touching the
+// schema, so replicas starting together apply each migration exactly once.
+const migrateLockKey = 7_301_544_209
+
+var migrationFileRX = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// migrationHooks run after the up SQL of the migration with the same version,
+// inside its transaction. They cover data backfills that are easier to express
+// in Go than in SQL.
+var migrationHooks = map[int64]func(ctx context.Context, tx *sql.Tx, log *jsonlog.Logger) error{
+	12: backfillAgeRanges,
+	15: backfillCategories,
+	16: backfillSkills,
+	21: reportInsecureImages,
+}
+
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	Applied bool
+}
+
+// Migrator applies the SQL files of a migrations directory. Progress is kept
+// in a golang-migrate compatible schema_migrations table, so databases that
+// were migrated with the migrate CLI are picked up where they left off.
+type Migrator struct {
+	db         *sql.DB
+	log        *jsonlog.Logger
+	migrations []migration
+}
+
+func (s *Storage) Migrator(fsys fs.FS) (*Migrator, error) {
+	return NewMigrator(s.db, s.log, fsys)
+}
+
+func NewMigrator(db *sql.DB, logger *jsonlog.Logger, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*migration{}
+	for _, entry := range entries {
+		match := migrationFileRX.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{db: db, log: logger, migrations: migrations}, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.Goto(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down rolls back the given number of applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		target := int64(0)
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if m.migrations[i].Version > current {
+				continue
+			}
+			if steps == 0 {
+				target = m.migrations[i].Version
+				break
+			}
+			steps--
+		}
+		return m.migrateTo(ctx, conn, current, target)
+	})
+}
+
+// Goto migrates up or down until the schema is at the given version. Version
+// 0 means every migration is rolled back.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("migration %d does not exist", version)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrateTo(ctx, conn, current, version)
+	})
+}
+
+// Force records version as the schema's version without running any
+// migration, like force of the migrate CLI. It adopts a database whose schema
+// was brought up to version by hand, or clears a dirty version once the failed
+// migration has been repaired. Version 0 forgets every migration.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("migration %d does not exist", version)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err = recordVersion(ctx, tx, version); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			statuses = append(statuses, MigrationStatus{
+				Version: mg.Version,
+				Name:    mg.Name,
+				Applied: mg.Version <= current,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) migrateTo(ctx context.Context, conn *sql.Conn, current, target int64) error {
+	if target > current {
+		for _, mg := range m.migrations {
+			if mg.Version <= current || mg.Version > target {
+				continue
+			}
+			if err := m.apply(ctx, conn, mg.Up, mg.Version, migrationHooks[mg.Version]); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", mg.Version, mg.Name, err)
+			}
+			m.log.PrintInfo("migration applied", map[string]string{
+				"version": strconv.FormatInt(mg.Version, 10),
+				"name":    mg.Name,
+			})
+		}
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mg := m.migrations[i]
+		if mg.Version > current || mg.Version <= target {
+			continue
+		}
+		previous := int64(0)
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		if err := m.apply(ctx, conn, mg.Down, previous, nil); err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", mg.Version, mg.Name, err)
+		}
+		m.log.PrintInfo("migration rolled back", map[string]string{
+			"version": strconv.FormatInt(mg.Version, 10),
+			"name":    mg.Name,
+		})
+	}
+	return nil
+}
+
+// apply runs one migration body and records the resulting version in the same
+// transaction, so a failed migration leaves neither schema nor version changed.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, body string, version int64, hook func(context.Context, *sql.Tx, *jsonlog.Logger) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if hook != nil {
+		if err = hook(ctx, tx, m.log); err != nil {
+			return err
+		}
+	}
+	if err = recordVersion(ctx, tx, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordVersion makes version the schema's clean version; 0 records none.
+func recordVersion(ctx context.Context, tx *sql.Tx, version int64) error {
+	if _, err := tx.ExecContext(ctx, `TRUNCATE schema_migrations`); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, version)
+	return err
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrateLockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrateLockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) find(version int64) int {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func currentVersion(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, nil
+	case err != nil:
+		return 0, err
+	case dirty:
+		return 0, fmt.Errorf("database is dirty at version %d, repair it by hand and run migrate force", version)
+	}
+	return version, nil
+}
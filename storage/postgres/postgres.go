@@ -8,7 +8,10 @@ import (
 	"github.com/lib/pq"
 	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
 	"log"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"toysService/internal/data"
 	"toysService/internal/jsonlog"
@@ -18,17 +21,37 @@ import (
 type Storage struct {
 	db  *sql.DB
 	log *jsonlog.Logger
+
+	replicas      []*replica
+	maxReplicaLag time.Duration
+	nextReplica   atomic.Uint64
+	stopChecks    context.CancelFunc
+	checks        sync.WaitGroup
 }
 
 const (
 	emptyValue = 0
 )
 
+// availableUnits counts the physical copies of the toy in the current row
+// that can be handed out right now.
+const availableUnits = `(SELECT count(*) FROM toy_units u WHERE u.toy_id = toys.id AND u.status = 'available')`
+
+// manufacturerColumns reads the manufacturer reference of the current row and
+// the name it points to.
+const manufacturerColumns = `COALESCE(manufacturer_id, 0), COALESCE((SELECT m.name FROM manufacturers m WHERE m.id = toys.manufacturer_id), '')`
+
 type StorageDetails struct {
 	DSN          string
 	MaxOpenConns int
 	MaxIdleConns int
 	MaxIdleTime  string
+	// ReplicaDSNs are read replicas of DSN. ListToy, GetToy and GetToysByIds
+	// read from them while they are healthy and trail the primary by no more
+	// than MaxReplicaLag (zero disables the lag check); everything else
+	// stays on the primary.
+	ReplicaDSNs   []string
+	MaxReplicaLag time.Duration
 }
 
 func OpenDB(details StorageDetails, logger *jsonlog.Logger) (*Storage, error) {
@@ -68,27 +91,78 @@ func OpenDB(details StorageDetails, logger *jsonlog.Logger) (*Storage, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Storage{
-		db:  db,
-		log: logger,
-	}, err
+	s := &Storage{
+		db:            db,
+		log:           logger,
+		maxReplicaLag: details.MaxReplicaLag,
+	}
+	if err = s.openReplicas(details, duration); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close stops the replica health checks and closes the connection pools.
+func (s *Storage) Close() error {
+	return errors.Join(s.closeReplicas(), s.db.Close())
 }
 
-func ValidateToy(v *validator.Validator, toy *data.Toy) {
+// NormalizeToy checks a toy before it is written and rewrites it into the
+// form it is stored in; it is the only step a writer needs. Categories may be
+// given by slug or by any localized name and are rewritten to slugs; unknown
+// ones are rejected. Skills given by name or synonym are rewritten to
+// canonical names, while unknown ones are kept as given: the dictionary
+// groups spellings, it does not limit which skills a toy can have. The
+// manufacturer is given by name and resolved to its ID; an unknown one is
+// left without an ID and is registered as a new brand when the toy is
+// written. The age range in months is kept if the caller set one and parsed
+// from the recommended age otherwise.
+func NormalizeToy(v *validator.Validator, toy *data.Toy, vocabulary data.Vocabulary) {
 	v.Check(toy.Title != "", "title", "title must be provided")
 	v.Check(len(toy.Title) <= 500, "title", "title must not be more than 500 bytes long")
 	v.Check(len(toy.Desc) <= 5000, "desc", "Description must not be more than 5000 bytes long")
-	//v.Check(v.ImageUrlsCheck(toy.Images), "image", "some of image urls is wrong")
+	v.Check(len(toy.Images) <= data.MaxToyImages, "images", fmt.Sprintf("no more than %d images", data.MaxToyImages))
+	v.Check(v.ImageUrlsCheck(toy.Images), "images", "image urls must start with https:// or be paths of uploaded images")
 	v.Check(toy.Categories != nil, "categories", "categories must be provided")
 	v.Check(toy.Skills != nil, "skills", "skills must be provided")
 	v.Check(len(toy.Categories) >= 1, "categories", "at least 1 category")
 	v.Check(len(toy.Skills) >= 1, "skills", "at least 1 skill")
 	v.Check(len(toy.Categories) <= 7, "categories", "no more than 7 categories")
 	v.Check(len(toy.Skills) <= 7, "Skills", "no more than 7 skills")
+	for i, ref := range toy.Categories {
+		slug, ok := vocabulary.Categories.Resolve(ref)
+		v.Check(ok, "categories", "unknown category "+ref)
+		if ok {
+			toy.Categories[i] = slug
+		}
+	}
+	for i, term := range toy.Skills {
+		if name, ok := vocabulary.Skills.Resolve(term); ok {
+			toy.Skills[i] = name
+		}
+	}
 	v.Check(validator.Unique(toy.Categories), "categories", "categories should not contain duplicate values")
 	v.Check(validator.Unique(toy.Skills), "skills", "skills should not contain duplicate values")
 	v.Check(toy.RecAge != "", "recAge", "age must be provided")
+	if toy.MinAgeMonths != 0 || toy.MaxAgeMonths != 0 {
+		minAge, maxAge, ok := toy.AgeRange()
+		v.Check(ok, "minAgeMonths", "must not be negative or more than maxAgeMonths")
+		v.Check(minAge <= data.MaxAgeMonths && maxAge <= data.MaxAgeMonths, "minAgeMonths", "age must not be more than 18 years")
+	} else if toy.RecAge != "" {
+		minAge, maxAge, ok := toy.AgeRange()
+		v.Check(ok, "recAge", "age must look like \"3+\", \"3-5 лет\" or \"18 мес\"")
+		v.Check(minAge <= data.MaxAgeMonths && maxAge <= data.MaxAgeMonths, "recAge", "age must not be more than 18 years")
+		toy.MinAgeMonths, toy.MaxAgeMonths = minAge, maxAge
+	}
 	v.Check(toy.Manufacturer != "", "manufacturer", "manufacturer must be provided")
+	v.Check(len(toy.Manufacturer) <= 200, "manufacturer", "manufacturer must not be more than 200 bytes long")
+	if toy.Manufacturer != "" {
+		if m, ok := vocabulary.Manufacturers.Resolve(toy.Manufacturer); ok {
+			toy.ManufacturerID, toy.Manufacturer = m.ID, m.Name
+		} else {
+			toy.ManufacturerID, toy.Manufacturer = 0, strings.Join(strings.Fields(toy.Manufacturer), " ")
+		}
+	}
 	v.Check(toy.Value >= 2000, "value", "toy value must be more than 1000 tenge")
 	v.Check(toy.Value <= 150000, "value", "limit of toy's value is 150.000 tenge")
 }
@@ -97,34 +171,69 @@ func (s *Storage) CreateToy(ctx context.Context, inputToy data.Toy) (toys.Status
 	s.log.PrintInfo("DB part", map[string]string{
 		"method": "postgres.CreateToy",
 	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Toy{}
+	}
+	defer tx.Rollback()
+
+	toy, err := insertToy(ctx, tx, inputToy)
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Toy{}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Toy{}
+	}
+	return toys.Status_STATUS_OK, "toy added successfuly!", toy
+}
+
+// insertToy writes a new toy together with its audit entry and creation
+// event.
+func insertToy(ctx context.Context, tx *sql.Tx, inputToy data.Toy) (data.Toy, error) {
+	if err := registerManufacturer(ctx, tx, &inputToy); err != nil {
+		return data.Toy{}, err
+	}
+
 	query := `
-INSERT INTO toys (title, description, skills, categories, images, recommended_age, manufacturer, value, is_available)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+INSERT INTO toys (title, description, skills, categories, images, recommended_age, manufacturer_id, value, min_age_months, max_age_months, sku)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING id`
 
-	args := []any{inputToy.Title, inputToy.Desc, pq.Array(inputToy.Skills), pq.Array(inputToy.Categories), pq.Array(inputToy.Images), inputToy.RecAge, inputToy.Manufacturer, inputToy.Value, inputToy.IsAvailable}
+	args := []any{inputToy.Title, inputToy.Desc, pq.Array(inputToy.Skills), pq.Array(inputToy.Categories), pq.Array(inputToy.Images), inputToy.RecAge, nullID(inputToy.ManufacturerID), inputToy.Value, inputToy.MinAgeMonths, nullAge(inputToy.MaxAgeMonths), nullSKU(inputToy.SKU)}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
 	var toyID int64
-	err := s.db.QueryRowContext(ctx, query, args...).Scan(&toyID)
-	println("skibidi")
-	if err != nil {
-		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Toy{}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&toyID); err != nil {
+		return data.Toy{}, err
 	}
 	toy := data.Toy{
-		ID:           toyID,
-		Title:        inputToy.Title,
-		Desc:         inputToy.Desc,
-		Value:        inputToy.Value,
-		Images:       inputToy.Images,
-		Skills:       inputToy.Skills,
-		Categories:   inputToy.Categories,
-		RecAge:       inputToy.RecAge,
-		Manufacturer: inputToy.Manufacturer,
-		IsAvailable:  inputToy.IsAvailable,
+		ID:             toyID,
+		SKU:            inputToy.SKU,
+		Title:          inputToy.Title,
+		Desc:           inputToy.Desc,
+		Value:          inputToy.Value,
+		Images:         inputToy.Images,
+		Skills:         inputToy.Skills,
+		Categories:     inputToy.Categories,
+		RecAge:         inputToy.RecAge,
+		MinAgeMonths:   inputToy.MinAgeMonths,
+		MaxAgeMonths:   inputToy.MaxAgeMonths,
+		Manufacturer:   inputToy.Manufacturer,
+		ManufacturerID: inputToy.ManufacturerID,
+		Version:        1,
 	}
-	return toys.Status_STATUS_OK, "toy added successfuly!", toy
+
+	if err := insertAudit(ctx, tx, toyID, data.AuditActionCreate, data.DiffToys(data.Toy{}, toy)); err != nil {
+		return data.Toy{}, err
+	}
+	if err := insertEvent(ctx, tx, data.EventToyCreated, data.ToyEventPayload{ToyID: toyID, Toy: &toy}); err != nil {
+		return data.Toy{}, err
+	}
+	return toy, nil
 }
 
 func (s *Storage) DeleteToy(ctx context.Context, toyID int64) (toys.Status, string) {
@@ -132,14 +241,21 @@ func (s *Storage) DeleteToy(ctx context.Context, toyID int64) (toys.Status, stri
 		"method": "postgres.DeleteToy",
 	})
 	query := `
-DELETE FROM toys
-WHERE id = $1
+UPDATE toys
+SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL
 `
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	result, err := s.db.ExecContext(ctx, query, toyID)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, toyID)
 
 	if err != nil {
 		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
@@ -153,16 +269,185 @@ WHERE id = $1
 	if rowsAffected == 0 {
 		return toys.Status_STATUS_INTERNAL_ERROR, "it affected 0 rows!"
 	}
+
+	if err = insertAudit(ctx, tx, toyID, data.AuditActionDelete, []data.FieldChange{}); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
+
+	if err = insertEvent(ctx, tx, data.EventToyDeleted, data.ToyEventPayload{ToyID: toyID}); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
+
+	if err = tx.Commit(); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
 	return toys.Status_STATUS_OK, "toy deletion was successful"
 }
 
-func (s *Storage) ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string) {
+// RestoreToy undeletes a toy. It fails with data.ErrRecordNotFound if there
+// is no such toy and data.ErrToyNotDeleted if it was never deleted.
+func (s *Storage) RestoreToy(ctx context.Context, toyID int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.RestoreToy",
+	})
+	query := `
+UPDATE toys
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, toyID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		var exists bool
+		if err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM toys WHERE id = $1)`, toyID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return data.ErrToyNotDeleted
+		}
+		return data.ErrRecordNotFound
+	}
+
+	if err = insertAudit(ctx, tx, toyID, data.AuditActionRestore, []data.FieldChange{}); err != nil {
+		return err
+	}
+
+	if err = insertEvent(ctx, tx, data.EventToyRestored, data.ToyEventPayload{ToyID: toyID}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) ListDeletedToys(ctx context.Context, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.ListDeletedToys",
+	})
+	query := fmt.Sprintf(`
+SELECT count(*) OVER(), id, title, categories, skills, recommended_age, min_age_months, COALESCE(max_age_months, 0), `+manufacturerColumns+`, value, deleted_at
+FROM toys
+WHERE deleted_at IS NOT NULL
+ORDER BY %s %s, id ASC
+LIMIT $1 OFFSET $2`, toySortColumn(filters, fullTextSearch), filters.SortDirection())
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	toysList := []*data.Toy{}
+
+	for rows.Next() {
+		var toy data.Toy
+		err := rows.Scan(
+			&totalRecords,
+			&toy.ID,
+			&toy.Title,
+			pq.Array(&toy.Categories),
+			pq.Array(&toy.Skills),
+			&toy.RecAge,
+			&toy.MinAgeMonths,
+			&toy.MaxAgeMonths,
+			&toy.ManufacturerID,
+			&toy.Manufacturer,
+			&toy.Value,
+			&toy.DeletedAt,
+		)
+		if err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+		}
+		toysList = append(toysList, &toy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+	}
+
+	metadata := filters.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return toysList, toys.Status_STATUS_OK, "deleted toys listing was successful", metadata
+}
+
+// ChangeToy writes the toy only if its version still matches the one the
+// caller read; otherwise it returns data.ErrEditConflict. Every successful
+// write bumps the version by one.
+func (s *Storage) ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string, error) {
 	s.log.PrintInfo("DB part", map[string]string{
 		"method": "postgres.ChangeToy",
 	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", err
+	}
+	defer tx.Rollback()
+
+	before, err := getToyForUpdate(ctx, tx, toy.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return toys.Status_STATUS_INTERNAL_ERROR, "operation affect zero rows!", data.ErrRecordNotFound
+		default:
+			return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", err
+		}
+	}
+
+	if before.Version != toy.Version {
+		return toys.Status_STATUS_INTERNAL_ERROR, "toy was changed by someone else", data.ErrEditConflict
+	}
+
+	if _, err = updateToy(ctx, tx, before, &toy); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return toys.Status_STATUS_INTERNAL_ERROR, "toy was changed by someone else", data.ErrEditConflict
+		default:
+			return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", err
+	}
+	return toys.Status_STATUS_OK, "toys updated successfully!", nil
+}
+
+// updateToy overwrites the editable fields of a locked toy read as before,
+// bumps its version and records the audit entry and change event. It returns
+// sql.ErrNoRows if toy.Version no longer matches.
+func updateToy(ctx context.Context, tx *sql.Tx, before data.Toy, toy *data.Toy) ([]data.FieldChange, error) {
+	if err := registerManufacturer(ctx, tx, toy); err != nil {
+		return nil, err
+	}
+
 	query := `UPDATE toys
-SET title = $1, description = $2, skills = $3, images = $4, categories = $5, recommended_age = $6, manufacturer = $7, value = $8
-WHERE id = $9
+SET title = $1, description = $2, skills = $3, images = $4, categories = $5, recommended_age = $6, manufacturer_id = $7, value = $8,
+    min_age_months = $9, max_age_months = $10, version = version + 1
+WHERE id = $11 AND version = $12 AND deleted_at IS NULL
 RETURNING id
 `
 	args := []any{
@@ -172,25 +457,30 @@ RETURNING id
 		pq.Array(toy.Images),
 		pq.Array(toy.Categories),
 		toy.RecAge,
-		toy.Manufacturer,
+		nullID(toy.ManufacturerID),
 		toy.Value,
+		toy.MinAgeMonths,
+		nullAge(toy.MaxAgeMonths),
 		toy.ID,
+		toy.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&toy.ID); err != nil {
+		return nil, err
+	}
 
-	err := s.db.QueryRowContext(ctx, query, args...).Scan(&toy.ID)
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return toys.Status_STATUS_INTERNAL_ERROR, "operation affect zero rows!"
-		default:
-			return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
-		}
+	changes := data.DiffToys(before, *toy)
+	if err := insertAudit(ctx, tx, toy.ID, data.AuditActionChange, changes); err != nil {
+		return nil, err
+	}
 
+	toy.Version++
+	toy.AvailableCount = before.AvailableCount
+	toy.IsAvailable = before.IsAvailable
+	if err := insertEvent(ctx, tx, data.EventToyChanged, data.ToyEventPayload{ToyID: toy.ID, Toy: toy, Changes: changes}); err != nil {
+		return nil, err
 	}
-	return toys.Status_STATUS_OK, "toys updated successfully!"
+	return changes, nil
 }
 
 func (s *Storage) GetToy(ctx context.Context, toyID int64) (data.Toy, toys.Status, string) {
@@ -202,29 +492,36 @@ func (s *Storage) GetToy(ctx context.Context, toyID int64) (data.Toy, toys.Statu
 	}
 
 	query := `
-SELECT id, created_at, title, description ,skills, categories, images, recommended_age, manufacturer, value, is_available
+SELECT id, created_at, COALESCE(sku, ''), title, description ,skills, categories, images, recommended_age, min_age_months, COALESCE(max_age_months, 0), ` + manufacturerColumns + `, value, version, ` + availableUnits + `
 FROM toys
-WHERE id = $1
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 	var toy data.Toy
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx, query, toyID).Scan(
-		&toy.ID,
-		&toy.CreatedAt,
-		&toy.Title,
-		&toy.Desc,
-		pq.Array(&toy.Skills),
-		pq.Array(&toy.Categories),
-		pq.Array(&toy.Images),
-		&toy.RecAge,
-		&toy.Manufacturer,
-		&toy.Value,
-		&toy.IsAvailable,
-	)
+	err := s.read(ctx, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, query, toyID).Scan(
+			&toy.ID,
+			&toy.CreatedAt,
+			&toy.SKU,
+			&toy.Title,
+			&toy.Desc,
+			pq.Array(&toy.Skills),
+			pq.Array(&toy.Categories),
+			pq.Array(&toy.Images),
+			&toy.RecAge,
+			&toy.MinAgeMonths,
+			&toy.MaxAgeMonths,
+			&toy.ManufacturerID,
+			&toy.Manufacturer,
+			&toy.Value,
+			&toy.Version,
+			&toy.AvailableCount,
+		)
+	})
 
 	if err != nil {
 		switch {
@@ -235,83 +532,77 @@ WHERE id = $1
 		}
 	}
 
+	toy.IsAvailable = toy.AvailableCount > 0
+
 	return toy, toys.Status_STATUS_OK, "toy get successfully"
 }
 
-func (s *Storage) ListToy(
-	ctx context.Context,
-	to int64,
-	from int64,
-	filters data.Filters,
-	categories []string,
-	skills []string,
-	title string,
-) ([]*data.Toy, toys.Status, string, data.Metadata) {
+// ListToy pages with LIMIT/OFFSET unless filters.Cursor is set, in which case
+// it continues from the cursor's row and skips counting the total. Either way
+// the returned metadata carries cursors for the neighbouring pages.
+//
+// When a search query matches nothing at all, ListToy falls back to trigram
+// matching on titles and manufacturers so that typos still find something;
+// metadata.Fuzzy tells the caller the results are approximate.
+func (s *Storage) ListToy(ctx context.Context, q data.ToyQuery, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
 
 	s.log.PrintInfo("DB part", map[string]string{
 		"method": "postgres.ListToy",
 	})
 
-	query := `
-SELECT count(*) OVER(), id, title, categories, skills, recommended_age, value
-FROM toys
-WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')`
-	args := []any{title}
-	argIndex := 2
-
-	if len(categories) > 0 {
-		query += fmt.Sprintf(" AND categories @> $%d", argIndex)
-		args = append(args, pq.Array(categories))
-		argIndex++
+	var cursor data.Cursor
+	if filters.Cursor != "" {
+		var err error
+		if cursor, err = data.DecodeCursor(filters.Cursor); err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "invalid cursor", data.Metadata{}
+		}
 	}
 
-	if len(skills) > 0 {
-		query += fmt.Sprintf(" AND skills @> $%d", argIndex)
-		args = append(args, pq.Array(skills))
-		argIndex++
+	search := fullTextSearch
+	if cursor.Fuzzy {
+		search = fuzzySearch
 	}
 
-	query += fmt.Sprintf(" AND value BETWEEN $%d AND $%d", argIndex, argIndex+1)
-	args = append(args, from, to)
-	argIndex += 2
-
-	query += fmt.Sprintf(" ORDER BY %s %s, id ASC LIMIT $%d OFFSET $%d", filters.SortColumn(), filters.SortDirection(), argIndex, argIndex+1)
-	args = append(args, filters.Limit(), filters.Offset())
-
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	var toysList []*data.Toy
+	var totalRecords int
+	var found toySearch
+	err := s.read(ctx, func(db *sql.DB) error {
+		var err error
+		toysList, totalRecords, found, err = findToys(ctx, db, q, filters, cursor, search)
+		return err
+	})
 	if err != nil {
 		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
 	}
-	defer rows.Close()
-
-	totalRecords := 0
-	toysList := []*data.Toy{}
-
-	for rows.Next() {
-		var toy data.Toy
-		err := rows.Scan(
-			&totalRecords,
-			&toy.ID,
-			&toy.Title,
-			pq.Array(&toy.Categories),
-			pq.Array(&toy.Skills),
-			&toy.RecAge,
-			&toy.Value,
-		)
-		if err != nil {
-			return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+	search = found
+
+	var metadata data.Metadata
+	var hasNext, hasPrev bool
+	if filters.Cursor != "" {
+		hasMore := len(toysList) > int(filters.Limit())
+		if hasMore {
+			toysList = toysList[:filters.Limit()]
 		}
-		toysList = append(toysList, &toy)
+		if cursor.Backward {
+			slices.Reverse(toysList)
+			hasNext, hasPrev = true, hasMore
+		} else {
+			hasNext, hasPrev = hasMore, true
+		}
+		metadata = data.Metadata{PageSize: filters.PageSize}
+	} else {
+		metadata = filters.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		hasNext = int(filters.Offset())+len(toysList) < totalRecords
+		hasPrev = filters.Page > 1
 	}
+	metadata.Fuzzy = search.fuzzy
 
-	if err = rows.Err(); err != nil {
+	if err = pageCursors(&metadata, filters, toySortColumn(filters, search), search.fuzzy, toysList, hasNext, hasPrev); err != nil {
 		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
 	}
-
-	metadata := filters.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
 	return toysList, toys.Status_STATUS_OK, "toy listing was successful", metadata
 }
 
@@ -325,15 +616,36 @@ func (s *Storage) GetToysByIds(ctx context.Context, ids []int64) ([]*data.ToySum
 		placeholders[i] = fmt.Sprintf("$%d", i+1)
 		args[i] = id
 	}
-	query := fmt.Sprintf(`SELECT id, title, value, images[1] AS image_url FROM toys WHERE id IN (%s)`, strings.Join(placeholders, ","))
+	// The summary shows the thumbnail of the primary uploaded image, or the
+	// first image URL for toys without uploads.
+	query := fmt.Sprintf(`
+SELECT id, title, value,
+    COALESCE((SELECT i.thumbnail_url FROM toy_images i WHERE i.toy_id = toys.id AND i.is_primary), images[1], '') AS image_url
+FROM toys
+WHERE id IN (%s)`, strings.Join(placeholders, ","))
 
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	var results []*data.ToySummary
+	err := s.read(ctx, func(db *sql.DB) error {
+		var err error
+		results, err = queryToySummaries(ctx, db, query, args)
+		return err
+	})
 	if err != nil {
 		return []*data.ToySummary{}, "could not query"
 	}
+
+	return results, "toy fetch was successful"
+
+}
+
+func queryToySummaries(ctx context.Context, db *sql.DB, query string, args []any) ([]*data.ToySummary, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 	results := []*data.ToySummary{}
 
@@ -345,37 +657,100 @@ func (s *Storage) GetToysByIds(ctx context.Context, ids []int64) ([]*data.ToySum
 			&toy.Value,
 			&toy.URL,
 		)
-		println(toy.URL)
-		println(toy.Value)
 		if err != nil {
-			return nil, "internal error"
+			return nil, err
 		}
 
 		results = append(results, &toy)
 	}
-	if err = rows.Err(); err != nil {
-		return nil, "internal"
-	}
-
-	return results, "toy fetch was successful"
-
+	return results, rows.Err()
 }
 
-func (s *Storage) ListRecommended(ctx context.Context, userID int64) ([]*data.Toy, toys.Status, string, data.Metadata) {
+func (s *Storage) ListRecommended(ctx context.Context, userID int64, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
 	s.log.PrintInfo("DB part", map[string]string{
 		"method": "postgres.ListRec",
 	})
-	query := `SELECT id, title, categories, recommended_age, value FROM toys
-WHERE categories = $1
-AND (categories @> $2 OR $2 = '{}')
-AND (skills @> $3 OR $3 = '{}')
-`
 
-	// TODO: брать с пользователя категории и скилы игрушек которые он уже купил
+	// Every toy is scored by how often its categories, skills and recommended age
+	// appear in the user's rental history, which ReserveToy records. A user
+	// without history scores zero everywhere, so the ordering falls back to
+	// overall rental popularity. Toys the user has out or booked are left out.
+	query := `
+WITH history AS (
+    SELECT t.categories, t.skills, t.recommended_age
+    FROM toy_rentals r
+    JOIN toys t ON t.id = r.toy_id
+    WHERE r.user_id = $1
+),
+category_weights AS (
+    SELECT c AS category, count(*) AS weight
+    FROM history, unnest(history.categories) AS c
+    GROUP BY c
+),
+skill_weights AS (
+    SELECT sk AS skill, count(*) AS weight
+    FROM history, unnest(history.skills) AS sk
+    GROUP BY sk
+),
+age_weights AS (
+    SELECT recommended_age, count(*) AS weight
+    FROM history
+    GROUP BY recommended_age
+),
+popularity AS (
+    SELECT toy_id, count(*) AS rentals
+    FROM toy_rentals
+    GROUP BY toy_id
+)
+SELECT count(*) OVER(), t.id, t.title, t.categories, t.skills, t.recommended_age, t.value,
+    3 * COALESCE((SELECT sum(cw.weight) FROM category_weights cw WHERE cw.category = ANY(t.categories)), 0)
+  + 2 * COALESCE((SELECT sum(sw.weight) FROM skill_weights sw WHERE sw.skill = ANY(t.skills)), 0)
+  + COALESCE((SELECT aw.weight FROM age_weights aw WHERE aw.recommended_age = t.recommended_age), 0) AS score
+FROM toys t
+LEFT JOIN popularity p ON p.toy_id = t.id
+WHERE t.deleted_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM toy_rentals r
+    WHERE r.user_id = $1 AND r.toy_id = t.id AND (r.returned_at IS NULL OR r.returned_at > now())
+)
+ORDER BY score DESC, COALESCE(p.rentals, 0) DESC, t.id ASC
+LIMIT $2 OFFSET $3`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	panic("potom")
-	panic(query)
+	rows, err := s.db.QueryContext(ctx, query, userID, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch recommendations from db", data.Metadata{}
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	toysList := []*data.Toy{}
+
+	for rows.Next() {
+		var toy data.Toy
+		var score int64
+		err := rows.Scan(
+			&totalRecords,
+			&toy.ID,
+			&toy.Title,
+			pq.Array(&toy.Categories),
+			pq.Array(&toy.Skills),
+			&toy.RecAge,
+			&toy.Value,
+			&score,
+		)
+		if err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch recommendations from db", data.Metadata{}
+		}
+		toysList = append(toysList, &toy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch recommendations from db", data.Metadata{}
+	}
+
+	metadata := filters.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return toysList, toys.Status_STATUS_OK, "recommendations listing was successful", metadata
 }
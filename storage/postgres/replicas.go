@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"toysService/internal/contextkeys"
+)
+
+// replicaCheckInterval is how often the replicas' health and lag are polled.
+const replicaCheckInterval = 2 * time.Second
+
+// primaryLSNQuery reads how far the primary's WAL has got.
+const primaryLSNQuery = `SELECT pg_current_wal_lsn()::text`
+
+// replicaLagQuery measures how far a replica trails the primary, in seconds,
+// given the primary's WAL position in $1. A replica that has replayed up to
+// that position is caught up. Comparing with what the replica itself
+// received is not enough: a replica whose WAL receiver lost the connection
+// replays all it got and then looks caught up forever. Behind the primary,
+// the lag is the time since the last replayed transaction, or NULL when it
+// has replayed none yet.
+const replicaLagQuery = `
+SELECT CASE
+    WHEN NOT pg_is_in_recovery() THEN 0
+    WHEN pg_last_wal_replay_lsn() >= $1::pg_lsn THEN 0
+    ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+END`
+
+// replica is a read replica and what its last health check found.
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// openReplicas opens a pool per replica DSN with the primary's pool settings,
+// checks them once and keeps checking them in the background. A replica that
+// is down at startup is not an error; it is used once it comes up.
+func (s *Storage) openReplicas(details StorageDetails, maxIdleTime time.Duration) error {
+	for i, dsn := range details.ReplicaDSNs {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return err
+		}
+		db.SetMaxOpenConns(details.MaxOpenConns)
+		db.SetMaxIdleConns(details.MaxIdleConns)
+		db.SetConnMaxIdleTime(maxIdleTime)
+		s.replicas = append(s.replicas, &replica{name: "replica-" + strconv.Itoa(i+1), db: db})
+	}
+	if len(s.replicas) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopChecks = cancel
+	s.checkReplicas(ctx)
+	s.checks.Add(1)
+	go func() {
+		defer s.checks.Done()
+		ticker := time.NewTicker(replicaCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.checkReplicas(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// closeReplicas stops the health checks and closes the replica pools.
+func (s *Storage) closeReplicas() error {
+	if s.stopChecks != nil {
+		s.stopChecks()
+		s.checks.Wait()
+	}
+	var errs []error
+	for _, r := range s.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// checkReplicas marks every replica healthy or not: it must answer within a
+// second and, if maxReplicaLag is set, trail the primary by no more than that.
+// A replica whose lag cannot be told counts as lagging. Without the primary's
+// WAL position nothing can be compared, and the replicas keep their state.
+func (s *Storage) checkReplicas(ctx context.Context) {
+	var primaryLSN string
+	lsnCtx, cancel := context.WithTimeout(ctx, time.Second)
+	err := s.db.QueryRowContext(lsnCtx, primaryLSNQuery).Scan(&primaryLSN)
+	cancel()
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.PrintError(err, map[string]string{
+				"message": "could not read the primary's WAL position to check the replicas",
+			})
+		}
+		return
+	}
+
+	for _, r := range s.replicas {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		var seconds sql.NullFloat64
+		err := r.db.QueryRowContext(ctx, replicaLagQuery, primaryLSN).Scan(&seconds)
+		cancel()
+
+		lag := time.Duration(seconds.Float64 * float64(time.Second))
+		lagging := s.maxReplicaLag > 0 && (!seconds.Valid || lag > s.maxReplicaLag)
+		healthy := err == nil && !lagging
+		if r.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		switch {
+		case healthy:
+			s.log.PrintInfo("replica is back in rotation", map[string]string{
+				"replica": r.name,
+			})
+		case err != nil:
+			s.log.PrintError(err, map[string]string{
+				"message": "replica is unreachable, reading from the primary",
+				"replica": r.name,
+			})
+		default:
+			s.log.PrintInfo("replica is lagging, reading from the primary", map[string]string{
+				"replica": r.name,
+				"lag":     lagText(seconds, lag),
+			})
+		}
+	}
+}
+
+func lagText(seconds sql.NullFloat64, lag time.Duration) string {
+	if !seconds.Valid {
+		return "unknown"
+	}
+	return lag.String()
+}
+
+// replica picks the next healthy replica round robin. It returns nil when
+// there is none or ctx asks to read from the primary.
+func (s *Storage) replica(ctx context.Context) *replica {
+	if primary, _ := ctx.Value(contextkeys.ReadPrimaryKey).(bool); primary {
+		return nil
+	}
+	var healthy []*replica
+	for _, r := range s.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	return healthy[s.nextReplica.Add(1)%uint64(len(healthy))]
+}
+
+// read runs fn on a replica, or on the primary when no replica is usable. A
+// replica that fails the read is taken out of rotation until its next health
+// check and fn is retried on the primary; a missing row is an answer, not a
+// failure.
+func (s *Storage) read(ctx context.Context, fn func(db *sql.DB) error) error {
+	r := s.replica(ctx)
+	if r == nil {
+		return fn(s.db)
+	}
+	err := fn(r.db)
+	if err == nil || errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+		return err
+	}
+
+	r.healthy.Store(false)
+	s.log.PrintError(err, map[string]string{
+		"message": "replica read failed, retrying on the primary",
+		"replica": r.name,
+	})
+	return fn(s.db)
+}
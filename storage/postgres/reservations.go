@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"time"
+	"toysService/internal/data"
+	"toysService/internal/validator"
+)
+
+const maxReservationLength = 60 * 24 * time.Hour
+
+func ValidateReservation(v *validator.Validator, r *data.Reservation) {
+	v.Check(r.ToyID > 0, "toyId", "toy id must be provided")
+	v.Check(!r.StartsAt.IsZero(), "startsAt", "start date must be provided")
+	v.Check(!r.EndsAt.IsZero(), "endsAt", "end date must be provided")
+	v.Check(r.EndsAt.After(r.StartsAt), "endsAt", "end date must be after start date")
+	v.Check(r.EndsAt.Sub(r.StartsAt) <= maxReservationLength, "endsAt", "reservation must not be longer than 60 days")
+	v.Check(r.StartsAt.After(time.Now().Add(-time.Hour)), "startsAt", "start date must not be in the past")
+}
+
+// ReserveToy books a free copy of the toy for the requested period. The toy
+// row is locked for the duration of the transaction so concurrent requests for
+// the same toy are serialized, and the exclusion constraint on
+// toy_reservations rejects any overlap that slips through.
+func (s *Storage) ReserveToy(ctx context.Context, r data.Reservation) (data.Reservation, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.ReserveToy",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Reservation{}, err
+	}
+	defer tx.Rollback()
+
+	var toyID int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM toys WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, r.ToyID).Scan(&toyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Reservation{}, data.ErrRecordNotFound
+		default:
+			return data.Reservation{}, err
+		}
+	}
+
+	query := `
+SELECT u.id
+FROM toy_units u
+WHERE u.toy_id = $1
+AND u.status NOT IN ('retired', 'maintenance', 'rented')
+AND NOT EXISTS (
+    SELECT 1 FROM toy_reservations tr
+    WHERE tr.unit_id = u.id AND tr.status = 'active' AND tr.period && tstzrange($2, $3, '[)')
+)
+ORDER BY u.id ASC
+LIMIT 1`
+
+	err = tx.QueryRowContext(ctx, query, r.ToyID, r.StartsAt, r.EndsAt).Scan(&r.UnitID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Reservation{}, data.ErrReservationConflict
+		default:
+			return data.Reservation{}, err
+		}
+	}
+
+	query = `
+INSERT INTO toy_reservations (toy_id, unit_id, user_id, period)
+VALUES ($1, $2, $3, tstzrange($4, $5, '[)'))
+RETURNING id, status, created_at`
+
+	err = tx.QueryRowContext(ctx, query, r.ToyID, r.UnitID, r.UserID, r.StartsAt, r.EndsAt).Scan(&r.ID, &r.Status, &r.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23P01":
+			return data.Reservation{}, data.ErrReservationConflict
+		default:
+			return data.Reservation{}, err
+		}
+	}
+
+	// The reservation is recorded as a rental of the toy for the reserved
+	// period, which is what ListRecommended and brand pages learn from.
+	query = `
+INSERT INTO toy_rentals (user_id, toy_id, reservation_id, rented_at, returned_at)
+VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err = tx.ExecContext(ctx, query, r.UserID, r.ToyID, r.ID, r.StartsAt, r.EndsAt); err != nil {
+		return data.Reservation{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return data.Reservation{}, err
+	}
+
+	return r, nil
+}
+
+func (s *Storage) CancelReservation(ctx context.Context, reservationID int64, userID int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.CancelReservation",
+	})
+	query := `
+UPDATE toy_reservations
+SET status = 'cancelled'
+WHERE id = $1 AND user_id = $2 AND status = 'active'`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, reservationID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return data.ErrRecordNotFound
+	}
+
+	// A cancelled reservation was never a rental.
+	if _, err = tx.ExecContext(ctx, `DELETE FROM toy_rentals WHERE reservation_id = $1`, reservationID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) ListReservations(ctx context.Context, userID int64) ([]*data.Reservation, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.ListReservations",
+	})
+	query := `
+SELECT id, toy_id, unit_id, user_id, lower(period), upper(period), status, created_at
+FROM toy_reservations
+WHERE user_id = $1
+ORDER BY lower(period) DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []*data.Reservation{}
+	for rows.Next() {
+		var r data.Reservation
+		err := rows.Scan(
+			&r.ID,
+			&r.ToyID,
+			&r.UnitID,
+			&r.UserID,
+			&r.StartsAt,
+			&r.EndsAt,
+			&r.Status,
+			&r.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, &r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
@@ -0,0 +1,342 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+	"toysService/internal/validator"
+)
+
+func ValidateSkill(v *validator.Validator, s *data.Skill) {
+	v.Check(strings.TrimSpace(s.Name) != "", "name", "name must be provided")
+	v.Check(len(s.Name) <= 100, "name", "name must not be more than 100 bytes long")
+	ValidateSkillSynonyms(v, s.Name, s.Synonyms)
+}
+
+func ValidateSkillSynonyms(v *validator.Validator, name string, synonyms []string) {
+	v.Check(len(synonyms) <= 20, "synonyms", "no more than 20 synonyms")
+	terms := []string{strings.ToLower(name)}
+	for _, synonym := range synonyms {
+		v.Check(strings.TrimSpace(synonym) != "", "synonyms", "synonyms must not be empty")
+		v.Check(len(synonym) <= 100, "synonyms", "synonyms must not be more than 100 bytes long")
+		terms = append(terms, strings.ToLower(synonym))
+	}
+	v.Check(validator.Unique(terms), "synonyms", "synonyms should not repeat each other or the name")
+}
+
+// ListSkills returns the dictionary with the number of live toys that have
+// each skill, most used first.
+func (s *Storage) ListSkills(ctx context.Context) ([]*data.Skill, error) {
+	query := `
+SELECT s.id, s.name,
+    ARRAY(SELECT synonym FROM skill_synonyms WHERE skill_id = s.id ORDER BY synonym),
+    (SELECT count(*) FROM toys WHERE deleted_at IS NULL AND skills @> ARRAY[s.name]),
+    s.created_at
+FROM skills s
+ORDER BY 4 DESC, s.name`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skills := []*data.Skill{}
+	for rows.Next() {
+		var skill data.Skill
+		if err := rows.Scan(&skill.ID, &skill.Name, pq.Array(&skill.Synonyms), &skill.Toys, &skill.CreatedAt); err != nil {
+			return nil, err
+		}
+		skills = append(skills, &skill)
+	}
+	return skills, rows.Err()
+}
+
+func (s *Storage) CreateSkill(ctx context.Context, skill data.Skill) (data.Skill, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.CreateSkill",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Skill{}, err
+	}
+	defer tx.Rollback()
+
+	if err = checkSkillTerms(ctx, tx, 0, skill.Name, skill.Synonyms); err != nil {
+		return data.Skill{}, err
+	}
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO skills (name) VALUES ($1) RETURNING id, created_at`, skill.Name).Scan(&skill.ID, &skill.CreatedAt)
+	if err != nil {
+		return data.Skill{}, skillError(err)
+	}
+	if err = setSkillSynonyms(ctx, tx, skill.ID, skill.Synonyms); err != nil {
+		return data.Skill{}, err
+	}
+	if skill.Synonyms == nil {
+		skill.Synonyms = []string{}
+	}
+
+	return skill, tx.Commit()
+}
+
+// UpdateSkill renames a skill and/or replaces its synonyms. A rename is
+// applied to every toy that has the skill, deleted ones included.
+func (s *Storage) UpdateSkill(ctx context.Context, id int64, upd data.SkillUpdate) (data.Skill, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.UpdateSkill",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Skill{}, err
+	}
+	defer tx.Rollback()
+
+	var skill data.Skill
+	query := `
+SELECT id, name, ARRAY(SELECT synonym FROM skill_synonyms WHERE skill_id = skills.id ORDER BY synonym), created_at
+FROM skills
+WHERE id = $1
+FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, id).Scan(&skill.ID, &skill.Name, pq.Array(&skill.Synonyms), &skill.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Skill{}, data.ErrRecordNotFound
+		default:
+			return data.Skill{}, err
+		}
+	}
+
+	oldName := skill.Name
+	if upd.Name != nil {
+		skill.Name = *upd.Name
+	}
+	if upd.Synonyms != nil {
+		skill.Synonyms = upd.Synonyms
+	}
+	if err = checkSkillTerms(ctx, tx, skill.ID, skill.Name, skill.Synonyms); err != nil {
+		return data.Skill{}, err
+	}
+
+	if skill.Name != oldName {
+		if _, err = tx.ExecContext(ctx, `UPDATE skills SET name = $1 WHERE id = $2`, skill.Name, skill.ID); err != nil {
+			return data.Skill{}, skillError(err)
+		}
+		if err = renameToySkill(ctx, tx, oldName, skill.Name); err != nil {
+			return data.Skill{}, err
+		}
+	}
+	if upd.Synonyms != nil {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM skill_synonyms WHERE skill_id = $1`, skill.ID); err != nil {
+			return data.Skill{}, err
+		}
+		if err = setSkillSynonyms(ctx, tx, skill.ID, skill.Synonyms); err != nil {
+			return data.Skill{}, err
+		}
+	}
+
+	return skill, tx.Commit()
+}
+
+// renameToySkill renames a skill on every toy that has it. Each toy changes
+// like an edit would: it gets a new version, an audit entry and a change
+// event.
+func renameToySkill(ctx context.Context, tx *sql.Tx, oldName, newName string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM toys WHERE skills @> ARRAY[$1] ORDER BY id FOR UPDATE`, oldName)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		before, err := scanLockedToy(tx.QueryRowContext(ctx, `SELECT `+lockedToyColumns+` FROM toys WHERE id = $1`, id))
+		if err != nil {
+			return err
+		}
+
+		after := before
+		after.Skills = slices.Clone(before.Skills)
+		for i, name := range after.Skills {
+			if name == oldName {
+				after.Skills[i] = newName
+			}
+		}
+		if _, err = tx.ExecContext(ctx, `UPDATE toys SET skills = $1, version = version + 1 WHERE id = $2`, pq.Array(after.Skills), id); err != nil {
+			return err
+		}
+
+		changes := data.DiffToys(before, after)
+		if err = insertAudit(ctx, tx, id, data.AuditActionChange, changes); err != nil {
+			return err
+		}
+		after.Version++
+		if err = insertEvent(ctx, tx, data.EventToyChanged, data.ToyEventPayload{ToyID: id, Toy: &after, Changes: changes}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) DeleteSkill(ctx context.Context, id int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.DeleteSkill",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `
+SELECT EXISTS (SELECT 1 FROM toys WHERE skills @> ARRAY[s.name])
+FROM skills s
+WHERE s.id = $1
+FOR UPDATE`, id).Scan(&inUse)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	if inUse {
+		return data.ErrSkillInUse
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM skills WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SkillDictionary loads every skill for normalizing toy skills.
+func (s *Storage) SkillDictionary(ctx context.Context) (*data.SkillDictionary, error) {
+	skills, err := s.ListSkills(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return data.NewSkillDictionary(skills), nil
+}
+
+// checkSkillTerms rejects a name or synonym that another skill already uses as
+// its name or one of its synonyms. The unique indexes only catch clashes
+// within the same column.
+func checkSkillTerms(ctx context.Context, tx *sql.Tx, id int64, name string, synonyms []string) error {
+	terms := append([]string{name}, synonyms...)
+	var taken bool
+	err := tx.QueryRowContext(ctx, `
+WITH terms AS (SELECT lower(t) AS term FROM unnest($2::text[]) AS t)
+SELECT EXISTS (SELECT 1 FROM skills WHERE id <> $1 AND lower(name) IN (SELECT term FROM terms))
+    OR EXISTS (SELECT 1 FROM skill_synonyms WHERE skill_id <> $1 AND lower(synonym) IN (SELECT term FROM terms))`,
+		id, pq.Array(terms)).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return data.ErrDuplicateSkill
+	}
+	return nil
+}
+
+func setSkillSynonyms(ctx context.Context, tx *sql.Tx, id int64, synonyms []string) error {
+	if len(synonyms) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO skill_synonyms (skill_id, synonym) SELECT $1, unnest($2::text[])`, id, pq.Array(synonyms))
+	return skillError(err)
+}
+
+func skillError(err error) error {
+	var pqErr *pq.Error
+	switch {
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		return data.ErrDuplicateSkill
+	default:
+		return err
+	}
+}
+
+// backfillSkills fills the dictionary from the skills of existing toys.
+// Spellings of the same words become one skill named after the most used one,
+// with the others as synonyms, and toys are rewritten to the canonical names.
+// Translations ("fine motor", "мелкая моторика") stay separate skills until
+// they are merged by hand.
+func backfillSkills(ctx context.Context, tx *sql.Tx, log *jsonlog.Logger) error {
+	groups, err := spellingGroups(ctx, tx, "skills")
+	if err != nil {
+		return err
+	}
+
+	taken := map[string]bool{}
+	var values, names []string
+	for _, group := range groups {
+		name := group[0]
+		var synonyms []string
+		for _, value := range group {
+			values = append(values, value)
+			names = append(names, name)
+			// Spellings that differ only in case share a dictionary term.
+			if term := strings.ToLower(value); !taken[term] {
+				taken[term] = true
+				if value != name {
+					synonyms = append(synonyms, value)
+				}
+			}
+		}
+
+		var id int64
+		if err = tx.QueryRowContext(ctx, `INSERT INTO skills (name) VALUES ($1) RETURNING id`, name).Scan(&id); err != nil {
+			return err
+		}
+		if err = setSkillSynonyms(ctx, tx, id, synonyms); err != nil {
+			return err
+		}
+	}
+
+	if err = rewriteToyValues(ctx, tx, "skills", values, names); err != nil {
+		return err
+	}
+
+	log.PrintInfo("skills backfilled", map[string]string{
+		"values": strconv.Itoa(len(values)),
+		"skills": strconv.Itoa(len(groups)),
+	})
+	return nil
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+)
+
+// spellingGroups reads the distinct values of a text[] column of toys and
+// groups the ones that stem to the same words ("Конструкторы",
+// "конструктор"). Groups come most used first and list their spellings most
+// used first.
+func spellingGroups(ctx context.Context, tx *sql.Tx, column string) ([][]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+SELECT c, strip(to_tsvector('toys_search', c))::text
+FROM toys, unnest(`+column+`) AS c
+WHERE c <> ''
+GROUP BY c
+ORDER BY count(*) DESC, c`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := map[string]int{}
+	var groups [][]string
+	for rows.Next() {
+		var value, stem string
+		if err = rows.Scan(&value, &stem); err != nil {
+			return nil, err
+		}
+		if stem == "" {
+			stem = value
+		}
+
+		i, ok := index[stem]
+		if !ok {
+			i = len(groups)
+			index[stem] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], value)
+	}
+	return groups, rows.Err()
+}
+
+// rewriteToyValues replaces values[i] with replacements[i] in a text[] column
+// of every toy, keeping the first position of each result and dropping the
+// duplicates that merging spellings produces.
+func rewriteToyValues(ctx context.Context, tx *sql.Tx, column string, values, replacements []string) error {
+	_, err := tx.ExecContext(ctx, `
+UPDATE toys SET `+column+` = ARRAY(
+    SELECT m.replacement
+    FROM unnest(toys.`+column+`) WITH ORDINALITY AS s(value, n)
+    JOIN unnest($1::text[], $2::text[]) AS m(value, replacement) ON m.value = s.value
+    GROUP BY m.replacement
+    ORDER BY min(s.n)
+)
+WHERE EXISTS (SELECT 1 FROM unnest(toys.`+column+`) AS c WHERE c <> '')`, pq.Array(values), pq.Array(replacements))
+	return err
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+	"toysService/internal/data"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SuggestToys completes prefix with toy titles, category names and
+// manufacturer names, most used first. Titles of live toys come from
+// toy_search_terms, which triggers keep up to date on every toy write.
+// Categories come from the taxonomy, matching on any localized name and
+// counting the live toys of their whole subtree, and manufacturers from the
+// manufacturers table, so both are suggested even before any toy uses them.
+func (s *Storage) SuggestToys(ctx context.Context, prefix string, limit int) ([]*data.Suggestion, error) {
+	query := `
+SELECT kind, term, slug, id, toys_count
+FROM (
+    SELECT kind, term, '' AS slug, 0::bigint AS id, toys_count
+    FROM toy_search_terms
+    WHERE lower(term) LIKE lower($1) || '%'
+    UNION ALL
+    SELECT 'category', min(n.value), c.slug, 0,
+        (SELECT count(*) FROM toys WHERE deleted_at IS NULL AND categories && category_subtree(c.slug))
+    FROM categories c, jsonb_each_text(c.names) AS n
+    WHERE lower(n.value) LIKE lower($1) || '%'
+    GROUP BY c.slug
+    UNION ALL
+    SELECT 'manufacturer', m.name, '', m.id,
+        (SELECT count(*) FROM toys WHERE deleted_at IS NULL AND manufacturer_id = m.id)
+    FROM manufacturers m
+    WHERE lower(m.name) LIKE lower($1) || '%'
+) AS suggestions
+ORDER BY toys_count DESC, length(term), term
+LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, likeEscaper.Replace(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*data.Suggestion{}
+	for rows.Next() {
+		var sg data.Suggestion
+		if err = rows.Scan(&sg.Kind, &sg.Text, &sg.Slug, &sg.ID, &sg.Toys); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &sg)
+	}
+	return suggestions, rows.Err()
+}
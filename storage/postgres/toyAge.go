@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+)
+
+// backfillAgeRanges fills min_age_months/max_age_months from the free-text
+// recommended_age of existing toys. Missing ages and values the parser does
+// not understand keep the "any age" default and are logged so they can be
+// fixed by hand.
+func backfillAgeRanges(ctx context.Context, tx *sql.Tx, log *jsonlog.Logger) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, COALESCE(recommended_age, '') FROM toys`)
+	if err != nil {
+		return err
+	}
+
+	type ageRange struct {
+		id       int64
+		min, max int32
+	}
+	var parsed []ageRange
+	for rows.Next() {
+		var id int64
+		var recAge string
+		if err = rows.Scan(&id, &recAge); err != nil {
+			rows.Close()
+			return err
+		}
+		minAge, maxAge, ok := data.ParseAgeRange(recAge)
+		if !ok {
+			log.PrintInfo("could not parse recommended age", map[string]string{
+				"toy_id": strconv.FormatInt(id, 10),
+				"value":  recAge,
+			})
+			continue
+		}
+		parsed = append(parsed, ageRange{id: id, min: minAge, max: maxAge})
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range parsed {
+		_, err = tx.ExecContext(ctx, `UPDATE toys SET min_age_months = $1, max_age_months = $2 WHERE id = $3`, r.min, nullAge(r.max), r.id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullAge stores an open upper bound as NULL.
+func nullAge(months int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: months, Valid: months > 0}
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
+	"time"
+	"toysService/internal/contextkeys"
+	"toysService/internal/data"
+)
+
+func actorFromContext(ctx context.Context) sql.NullInt64 {
+	userID, ok := ctx.Value(contextkeys.UserIDKey).(int64)
+	return sql.NullInt64{Int64: userID, Valid: ok}
+}
+
+func insertAudit(ctx context.Context, tx *sql.Tx, toyID int64, action string, changes []data.FieldChange) error {
+	query := `
+INSERT INTO toy_audit (toy_id, user_id, action, changes)
+VALUES ($1, $2, $3, $4)`
+
+	js, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, toyID, actorFromContext(ctx), action, js)
+	return err
+}
+
+// lockedToyColumns are what getToyForUpdate, getToyBySKU and LoadToys read;
+// scanLockedToy scans them.
+const lockedToyColumns = `id, COALESCE(sku, ''), title, description, skills, categories, images, recommended_age, min_age_months, COALESCE(max_age_months, 0), ` + manufacturerColumns + `, value, version, ` + availableUnits
+
+func scanLockedToy(row interface{ Scan(dest ...any) error }, extra ...any) (data.Toy, error) {
+	var toy data.Toy
+	dest := []any{
+		&toy.ID,
+		&toy.SKU,
+		&toy.Title,
+		&toy.Desc,
+		pq.Array(&toy.Skills),
+		pq.Array(&toy.Categories),
+		pq.Array(&toy.Images),
+		&toy.RecAge,
+		&toy.MinAgeMonths,
+		&toy.MaxAgeMonths,
+		&toy.ManufacturerID,
+		&toy.Manufacturer,
+		&toy.Value,
+		&toy.Version,
+		&toy.AvailableCount,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	toy.IsAvailable = toy.AvailableCount > 0
+	return toy, err
+}
+
+func getToyForUpdate(ctx context.Context, tx *sql.Tx, toyID int64) (data.Toy, error) {
+	query := `
+SELECT ` + lockedToyColumns + `
+FROM toys
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE`
+
+	return scanLockedToy(tx.QueryRowContext(ctx, query, toyID))
+}
+
+func (s *Storage) ListToyHistory(ctx context.Context, toyID int64, filters data.Filters) ([]*data.ToyAuditEntry, toys.Status, string, data.Metadata) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "postgres.ListToyHistory",
+	})
+	query := fmt.Sprintf(`
+SELECT count(*) OVER(), id, toy_id, COALESCE(user_id, 0), action, changes, changed_at
+FROM toy_audit
+WHERE toy_id = $1
+ORDER BY %s %s, id DESC
+LIMIT $2 OFFSET $3`, filters.SortColumn(), filters.SortDirection())
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, toyID, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toy history from db", data.Metadata{}
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*data.ToyAuditEntry{}
+
+	for rows.Next() {
+		var entry data.ToyAuditEntry
+		var changes []byte
+		err := rows.Scan(
+			&totalRecords,
+			&entry.ID,
+			&entry.ToyID,
+			&entry.UserID,
+			&entry.Action,
+			&changes,
+			&entry.ChangedAt,
+		)
+		if err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toy history from db", data.Metadata{}
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not decode toy history", data.Metadata{}
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toy history from db", data.Metadata{}
+	}
+
+	metadata := filters.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return entries, toys.Status_STATUS_OK, "toy history listing was successful", metadata
+}
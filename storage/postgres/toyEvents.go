@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	"toysService/internal/data"
+)
+
+// outboxLockKey guards delivery so that only one relay in the cluster works
+// through the outbox at a time, which keeps per-toy ordering intact.
+const outboxLockKey = 7_301_544_210
+
+func insertEvent(ctx context.Context, tx *sql.Tx, eventType string, payload data.ToyEventPayload) error {
+	query := `
+INSERT INTO toy_events (toy_id, event_type, payload)
+VALUES ($1, $2, $3)`
+
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, payload.ToyID, eventType, js)
+	return err
+}
+
+// insertAvailabilityEvent records the current number of available copies of
+// a toy. Callers only invoke it when a unit moved in or out of "available".
+// The toy row is locked first, as ReserveToy does, so that concurrent unit
+// changes count one after the other and the last event has the final count.
+func insertAvailabilityEvent(ctx context.Context, tx *sql.Tx, toyID int64) error {
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM toys WHERE id = $1 FOR UPDATE`, toyID); err != nil {
+		return err
+	}
+
+	var count int32
+	err := tx.QueryRowContext(ctx, `SELECT count(*) FROM toy_units WHERE toy_id = $1 AND status = 'available'`, toyID).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	isAvailable := count > 0
+	return insertEvent(ctx, tx, data.EventToyAvailabilityChanged, data.ToyEventPayload{
+		ToyID:          toyID,
+		AvailableCount: &count,
+		IsAvailable:    &isAvailable,
+	})
+}
+
+// DeliverToyEvents hands pending outbox events to publish in commit order. When
+// publishing fails the event is rescheduled with backoff(attempts) and every
+// later event of the same toy is held back, so consumers never see a toy's
+// events out of order. Events are marked published only after publish
+// returns, giving at-least-once delivery.
+func (s *Storage) DeliverToyEvents(
+	ctx context.Context,
+	limit int,
+	publish func(context.Context, *data.ToyEvent) error,
+	backoff func(attempts int32) time.Duration,
+) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err = tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockKey).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	query := `
+SELECT e.id, e.toy_id, e.event_type, e.payload, e.created_at, e.attempts
+FROM toy_events e
+WHERE e.published_at IS NULL
+AND e.next_attempt_at <= now()
+AND NOT EXISTS (
+    SELECT 1 FROM toy_events p
+    WHERE p.toy_id = e.toy_id AND p.published_at IS NULL AND p.id < e.id AND p.next_attempt_at > now()
+)
+ORDER BY e.id ASC
+LIMIT $1`
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	events := []*data.ToyEvent{}
+	for rows.Next() {
+		var event data.ToyEvent
+		err := rows.Scan(&event.ID, &event.ToyID, &event.Type, &event.Payload, &event.CreatedAt, &event.Attempts)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, &event)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	blocked := map[int64]bool{}
+	for _, event := range events {
+		if blocked[event.ToyID] {
+			continue
+		}
+
+		if pubErr := publish(ctx, event); pubErr != nil {
+			blocked[event.ToyID] = true
+			retryAt := time.Now().Add(backoff(event.Attempts + 1))
+			_, err = tx.ExecContext(ctx, `
+UPDATE toy_events
+SET attempts = attempts + 1, next_attempt_at = $1, last_error = $2
+WHERE id = $3`, retryAt, pubErr.Error(), event.ID)
+			if err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE toy_events SET published_at = now(), attempts = attempts + 1 WHERE id = $1`, event.ID)
+		if err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return delivered, nil
+}
@@ -0,0 +1,338 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"toysService/internal/data"
+	"toysService/storage/facetsql"
+)
+
+// toySearch is how ListToy matches, ranks and highlights its title argument
+// ($1). The rank expression is the "relevance" sort and is negated so that
+// the ascending sort puts the best matches first, like every other sort name
+// without a minus.
+type toySearch struct {
+	match   string
+	rank    string
+	snippet string
+	fuzzy   bool
+}
+
+// searchQuery turns the search text into a tsquery. websearch syntax lets
+// users write "lego -duplo" or quoted phrases.
+const searchQuery = `websearch_to_tsquery('toys_search', $1)`
+
+var fullTextSearch = toySearch{
+	match: `search_document @@ ` + searchQuery,
+	rank:  `(-ts_rank_cd(search_document, ` + searchQuery + `))`,
+	snippet: `CASE WHEN $1 = '' THEN '' ELSE ts_headline('toys_search', title || ' — ' || description, ` + searchQuery + `,
+    'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=8, FragmentDelimiter=" … "') END`,
+}
+
+// fuzzySearch matches titles and manufacturer names that contain something
+// close to the search text, e.g. "lgo" finds "LEGO Duplo".
+var fuzzySearch = toySearch{
+	match: `($1 <% title OR manufacturer_id IN (SELECT id FROM manufacturers WHERE $1 <% name))`,
+	rank: `(-GREATEST(word_similarity($1, title),
+    COALESCE((SELECT word_similarity($1, m.name) FROM manufacturers m WHERE m.id = toys.manufacturer_id), 0)))`,
+	snippet: `''`,
+	fuzzy:   true,
+}
+
+// fuzzyThreshold is the pg_trgm word similarity a fuzzy match needs. The
+// default of 0.6 is too strict for short words with a missing letter.
+const fuzzyThreshold = "0.4"
+
+// toySortColumns maps the sort names accepted by ListToy to SQL columns.
+// Names not listed here are column names already.
+var toySortColumns = map[string]string{
+	"recAge": "min_age_months",
+	"age":    "min_age_months",
+	"from":   "value",
+	"to":     "value",
+}
+
+func toySortColumn(filters data.Filters, search toySearch) string {
+	column := filters.SortColumn()
+	if column == "relevance" {
+		return search.rank
+	}
+	if mapped, ok := toySortColumns[column]; ok {
+		return mapped
+	}
+	return column
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// toyFilter is one ListToy filter as an SQL condition. ToyFacets hands them
+// to facetsql, so they share its type.
+type toyFilter = facetsql.Filter
+
+// toyFilters turns the query's filters into SQL conditions, appending their
+// arguments to args. $1 is reserved for the search text.
+func toyFilters(q data.ToyQuery, args *[]any) []toyFilter {
+	var filters []toyFilter
+	arg := func(value any) int {
+		*args = append(*args, value)
+		return len(*args)
+	}
+
+	// Every requested category must match, either itself or through one of
+	// its subcategories.
+	for _, slug := range q.Categories {
+		filters = append(filters, toyFilter{Facet: data.FacetCategory, Cond: fmt.Sprintf("categories && (SELECT category_subtree($%d))", arg(slug))})
+	}
+	// Skills match by canonical name or any synonym.
+	for _, skill := range q.Skills {
+		filters = append(filters, toyFilter{Facet: data.FacetSkill, Cond: fmt.Sprintf("skills && skill_variants($%d)", arg(skill))})
+	}
+	if q.AgeMonths != nil {
+		n := arg(*q.AgeMonths)
+		filters = append(filters, toyFilter{Facet: data.FacetAge, Cond: fmt.Sprintf("min_age_months <= $%d AND (max_age_months IS NULL OR max_age_months >= $%d)", n, n)})
+	}
+	if len(q.Manufacturers) > 0 {
+		filters = append(filters, toyFilter{Facet: data.FacetManufacturer, Cond: fmt.Sprintf("manufacturer_id = ANY($%d)", arg(pq.Array(q.Manufacturers)))})
+	}
+	from, to := arg(q.From), arg(q.To)
+	filters = append(filters, toyFilter{Facet: data.FacetValue, Cond: fmt.Sprintf("value BETWEEN $%d AND $%d", from, to)})
+
+	return filters
+}
+
+// searchQueryer returns what a query using search should run on db. Fuzzy
+// searches need their similarity threshold set, which only lasts for a
+// transaction; done releases it.
+func searchQueryer(ctx context.Context, db *sql.DB, search toySearch) (queryer, func(), error) {
+	if !search.fuzzy {
+		return db, func() {}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, fuzzyThreshold); err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	return tx, func() { tx.Rollback() }, nil
+}
+
+// findToys runs one ListToy page on db, falling back to fuzzySearch when a
+// search query matches nothing at all. It returns the search that produced
+// the page.
+func findToys(ctx context.Context, db *sql.DB, q data.ToyQuery, filters data.Filters, cursor data.Cursor, search toySearch) ([]*data.Toy, int, toySearch, error) {
+	toysList, totalRecords, err := queryToys(ctx, db, q, filters, cursor, search)
+	if err != nil {
+		return nil, 0, search, err
+	}
+	if len(toysList) > 0 || q.Title == "" || search.fuzzy || filters.Cursor != "" {
+		return toysList, totalRecords, search, nil
+	}
+
+	if filters.Page > 1 {
+		// An empty later page may just be past the end of the matches.
+		probe := data.Filters{Page: 1, PageSize: 1, Sort: filters.Sort, SortSafelist: filters.SortSafelist}
+		found, _, err := queryToys(ctx, db, q, probe, cursor, search)
+		if err != nil || len(found) > 0 {
+			return toysList, totalRecords, search, err
+		}
+	}
+	toysList, totalRecords, err = queryToys(ctx, db, q, filters, cursor, fuzzySearch)
+	return toysList, totalRecords, fuzzySearch, err
+}
+
+// queryToys runs one ListToy page. In cursor mode it reads one row more than
+// the page size so the caller can tell whether another page follows, and the
+// returned total is zero.
+func queryToys(ctx context.Context, db *sql.DB, q data.ToyQuery, filters data.Filters, cursor data.Cursor, search toySearch) ([]*data.Toy, int, error) {
+	column := toySortColumn(filters, search)
+	direction := filters.SortDirection()
+
+	countColumn := "count(*) OVER()"
+	if filters.Cursor != "" {
+		countColumn = "0"
+	}
+
+	args := []any{q.Title}
+	query := `
+SELECT ` + countColumn + `, id, title, categories, skills, recommended_age, min_age_months, COALESCE(max_age_months, 0), ` + manufacturerColumns + `, value, ` + availableUnits + `,
+    -` + search.rank + `, ` + search.snippet + `
+FROM toys
+WHERE deleted_at IS NULL
+AND ($1 = '' OR ` + search.match + `)`
+	for _, f := range toyFilters(q, &args) {
+		query += " AND " + f.Cond
+	}
+	argIndex := len(args) + 1
+
+	if filters.Cursor != "" {
+		key, err := toyCursorKey(column, cursor)
+		if err != nil {
+			return nil, 0, err
+		}
+		query += keysetCondition(column, direction, cursor.Backward, argIndex, argIndex+1)
+		args = append(args, key, cursor.ID)
+		argIndex += 2
+
+		// A backward page is read in reverse order and flipped back by ListToy.
+		idDirection := "ASC"
+		if cursor.Backward {
+			direction, idDirection = flipDirection(direction), "DESC"
+		}
+		query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT $%d", column, direction, idDirection, argIndex)
+		args = append(args, filters.Limit()+1)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, id ASC LIMIT $%d OFFSET $%d", column, direction, argIndex, argIndex+1)
+		args = append(args, filters.Limit(), filters.Offset())
+	}
+
+	conn, done, err := searchQueryer(ctx, db, search)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer done()
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	toysList := []*data.Toy{}
+
+	for rows.Next() {
+		var toy data.Toy
+		err := rows.Scan(
+			&totalRecords,
+			&toy.ID,
+			&toy.Title,
+			pq.Array(&toy.Categories),
+			pq.Array(&toy.Skills),
+			&toy.RecAge,
+			&toy.MinAgeMonths,
+			&toy.MaxAgeMonths,
+			&toy.ManufacturerID,
+			&toy.Manufacturer,
+			&toy.Value,
+			&toy.AvailableCount,
+			&toy.Rank,
+			&toy.Snippet,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		toy.IsAvailable = toy.AvailableCount > 0
+		toysList = append(toysList, &toy)
+	}
+
+	return toysList, totalRecords, rows.Err()
+}
+
+// toySortKey returns the value of the sort column for a listed toy, which is
+// what a cursor pointing at that toy has to remember.
+func toySortKey(column string, toy *data.Toy) any {
+	switch column {
+	case "title":
+		return toy.Title
+	case "value":
+		return toy.Value
+	case "min_age_months":
+		return toy.MinAgeMonths
+	case "skills":
+		return toy.Skills
+	case "categories":
+		return toy.Categories
+	case fullTextSearch.rank, fuzzySearch.rank:
+		return -toy.Rank
+	default:
+		return toy.ID
+	}
+}
+
+// toyCursorKey decodes a cursor's sort key into a query argument of the
+// column's type.
+func toyCursorKey(column string, c data.Cursor) (any, error) {
+	switch column {
+	case "title":
+		var key string
+		err := c.KeyInto(&key)
+		return key, err
+	case "value", "id":
+		var key int64
+		err := c.KeyInto(&key)
+		return key, err
+	case "min_age_months":
+		var key int32
+		err := c.KeyInto(&key)
+		return key, err
+	case "skills", "categories":
+		var key []string
+		err := c.KeyInto(&key)
+		return pq.Array(key), err
+	case fullTextSearch.rank, fuzzySearch.rank:
+		var key float32
+		err := c.KeyInto(&key)
+		return key, err
+	default:
+		return nil, fmt.Errorf("cursor pagination is not supported for %q", column)
+	}
+}
+
+// keysetCondition selects the rows after (or before, for a backward cursor)
+// the cursor position in "column dir, id ASC" order. keyArg and idArg are the
+// placeholder numbers of the cursor's key and id.
+func keysetCondition(column string, direction string, backward bool, keyArg, idArg int) string {
+	keyOp, idOp := ">", ">"
+	if direction == "DESC" {
+		keyOp = "<"
+	}
+	if backward {
+		keyOp, idOp = flipOp(keyOp), flipOp(idOp)
+	}
+	return fmt.Sprintf(" AND (%[1]s %[2]s $%[3]d OR (%[1]s = $%[3]d AND id %[4]s $%[5]d))", column, keyOp, keyArg, idOp, idArg)
+}
+
+func flipOp(op string) string {
+	if op == ">" {
+		return "<"
+	}
+	return ">"
+}
+
+func flipDirection(direction string) string {
+	if direction == "DESC" {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+// pageCursors fills the next/prev cursors of a page of toys. hasNext and
+// hasPrev tell whether there is anything beyond either end of the page.
+func pageCursors(metadata *data.Metadata, filters data.Filters, column string, fuzzy bool, page []*data.Toy, hasNext, hasPrev bool) error {
+	if len(page) == 0 {
+		return nil
+	}
+	var err error
+	if hasNext {
+		last := page[len(page)-1]
+		c := data.Cursor{Sort: filters.Sort, ID: last.ID, Fuzzy: fuzzy}
+		if metadata.NextCursor, err = c.Encode(toySortKey(column, last)); err != nil {
+			return err
+		}
+	}
+	if hasPrev {
+		first := page[0]
+		c := data.Cursor{Sort: filters.Sort, ID: first.ID, Backward: true, Fuzzy: fuzzy}
+		if metadata.PrevCursor, err = c.Encode(toySortKey(column, first)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"testing"
+	"toysService/storage/storagetest"
+)
+
+// TestListToy expects the database to sort text by a linguistic collation,
+// such as an ICU or glibc locale, as production databases do; under the "C"
+// collation titles come out in byte order instead.
+func TestListToy(t *testing.T) {
+	storagetest.TestListToy(t, newTestStorage(t))
+}
+
+func TestKeysetCondition(t *testing.T) {
+	for _, tc := range []struct {
+		direction string
+		backward  bool
+		want      string
+	}{
+		{"ASC", false, " AND (value > $3 OR (value = $3 AND id > $4))"},
+		{"DESC", false, " AND (value < $3 OR (value = $3 AND id > $4))"},
+		{"ASC", true, " AND (value < $3 OR (value = $3 AND id < $4))"},
+		{"DESC", true, " AND (value > $3 OR (value = $3 AND id < $4))"},
+	} {
+		if got := keysetCondition("value", tc.direction, tc.backward, 3, 4); got != tc.want {
+			t.Errorf("%s, backward %t: got %q, want %q", tc.direction, tc.backward, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+	"toysService/internal/data"
+)
+
+// batchTimeout bounds batch writes and import batches, which run many
+// statements in a single transaction.
+const batchTimeout = 30 * time.Second
+
+// CreateToys inserts validated toys in one immediate transaction, so the
+// whole batch holds the database's write lock; writeBatch has the details.
+func (s *Storage) CreateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.CreateToys",
+		"items":  strconv.Itoa(len(items)),
+	})
+
+	return s.writeBatch(ctx, items, atomic, func(ctx context.Context, tx *sql.Tx, toy data.Toy) (data.Toy, error) {
+		return insertToy(ctx, tx, toy)
+	})
+}
+
+// LoadToys reads the live toys of a batch update in one query, passing the
+// ids as a JSON array for json_each. Missing and deleted toys are left out.
+func (s *Storage) LoadToys(ctx context.Context, ids []int64) (map[int64]data.Toy, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.LoadToys",
+		"items":  strconv.Itoa(len(ids)),
+	})
+
+	// The ids travel as one JSON array rather than a placeholder each.
+	list, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+SELECT ` + lockedToyColumns + `
+FROM toys
+WHERE id IN (SELECT value FROM json_each($1)) AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, string(list))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[int64]data.Toy, len(ids))
+	for rows.Next() {
+		toy, err := scanLockedToy(rows)
+		if err != nil {
+			return nil, err
+		}
+		found[toy.ID] = toy
+	}
+	return found, rows.Err()
+}
+
+// UpdateToys changes validated toys under the same version check as
+// ChangeToy, reporting data.ErrEditConflict for items read at an older
+// version.
+func (s *Storage) UpdateToys(ctx context.Context, items []data.BatchItem, atomic bool) ([]data.BatchResult, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.UpdateToys",
+		"items":  strconv.Itoa(len(items)),
+	})
+
+	return s.writeBatch(ctx, items, atomic, func(ctx context.Context, tx *sql.Tx, toy data.Toy) (data.Toy, error) {
+		before, err := getToyForUpdate(ctx, tx, toy.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return data.Toy{}, data.ErrRecordNotFound
+			default:
+				return data.Toy{}, err
+			}
+		}
+		if before.Version != toy.Version {
+			return data.Toy{}, data.ErrEditConflict
+		}
+		if _, err = updateToy(ctx, tx, before, &toy); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return data.Toy{}, data.ErrEditConflict
+			default:
+				return data.Toy{}, err
+			}
+		}
+		return toy, nil
+	})
+}
+
+// writeBatch runs write for every item of the batch. Atomic batches abort
+// at the first failure and mark the rest data.ErrBatchAborted; best-effort
+// batches wrap each item in a SAVEPOINT and roll back just the failed ones.
+func (s *Storage) writeBatch(ctx context.Context, items []data.BatchItem, atomic bool, write func(ctx context.Context, tx *sql.Tx, toy data.Toy) (data.Toy, error)) ([]data.BatchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, batchTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]data.BatchResult, len(items))
+	for i, item := range items {
+		results[i].Index = item.Index
+	}
+	for i, item := range items {
+		if !atomic {
+			if _, err = tx.ExecContext(ctx, `SAVEPOINT batch_item`); err != nil {
+				return nil, err
+			}
+		}
+
+		toy, err := write(ctx, tx, item.Toy)
+		if err != nil {
+			results[i].Err = err
+			if atomic {
+				return abortBatch(results, i), nil
+			}
+			if _, rerr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_item`); rerr != nil {
+				return nil, rerr
+			}
+			continue
+		}
+		results[i].ID, results[i].Version = toy.ID, toy.Version
+	}
+
+	return results, tx.Commit()
+}
+
+// abortBatch marks every item but the failed one as not written.
+func abortBatch(results []data.BatchResult, failed int) []data.BatchResult {
+	for i := range results {
+		if i != failed {
+			results[i].Err = data.ErrBatchAborted
+			results[i].ID, results[i].Version = 0, 0
+		}
+	}
+	return results
+}
@@ -0,0 +1,270 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	sqlite3 "modernc.org/sqlite/lib"
+	"time"
+	"toysService/internal/data"
+)
+
+const categoryColumns = `c.id, c.slug, coalesce(p.slug, ''), c.names, c.created_at`
+
+func scanCategory(row interface{ Scan(...any) error }) (*data.Category, error) {
+	var c data.Category
+	var names []byte
+	if err := row.Scan(&c.ID, &c.Slug, &c.Parent, &names, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(names, &c.Names); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *Storage) ListCategories(ctx context.Context) ([]*data.Category, error) {
+	query := `
+SELECT ` + categoryColumns + `
+FROM categories c
+LEFT JOIN categories p ON p.id = c.parent_id
+ORDER BY c.parent_id NULLS FIRST, c.slug`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*data.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (s *Storage) GetCategory(ctx context.Context, slug string) (data.Category, error) {
+	query := `
+SELECT ` + categoryColumns + `
+FROM categories c
+LEFT JOIN categories p ON p.id = c.parent_id
+WHERE c.slug = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	c, err := scanCategory(s.db.QueryRowContext(ctx, query, slug))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Category{}, data.ErrRecordNotFound
+		default:
+			return data.Category{}, err
+		}
+	}
+	return *c, nil
+}
+
+func (s *Storage) CreateCategory(ctx context.Context, c data.Category) (data.Category, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.CreateCategory",
+	})
+
+	names, err := json.Marshal(c.Names)
+	if err != nil {
+		return data.Category{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Category{}, err
+	}
+	defer tx.Rollback()
+
+	parentID, err := categoryID(ctx, tx, c.Parent)
+	if err != nil {
+		return data.Category{}, err
+	}
+
+	query := `
+INSERT INTO categories (slug, parent_id, names)
+VALUES ($1, $2, $3)
+RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query, c.Slug, parentID, string(names)).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		switch errorCode(err) {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+			return data.Category{}, data.ErrDuplicateSlug
+		default:
+			return data.Category{}, err
+		}
+	}
+
+	return c, tx.Commit()
+}
+
+// UpdateCategory reparents and/or renames a category. The names JSON object
+// is merged in Go, where an empty name drops its locale, and written back
+// whole; a recursive CTE rejects moves under the category's own subtree.
+func (s *Storage) UpdateCategory(ctx context.Context, slug string, upd data.CategoryUpdate) (data.Category, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.UpdateCategory",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Category{}, err
+	}
+	defer tx.Rollback()
+
+	query := `
+SELECT ` + categoryColumns + `
+FROM categories c
+LEFT JOIN categories p ON p.id = c.parent_id
+WHERE c.slug = $1`
+
+	c, err := scanCategory(tx.QueryRowContext(ctx, query, slug))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Category{}, data.ErrRecordNotFound
+		default:
+			return data.Category{}, err
+		}
+	}
+
+	if upd.Parent != nil {
+		c.Parent = *upd.Parent
+	}
+	parentID, err := categoryID(ctx, tx, c.Parent)
+	if err != nil {
+		return data.Category{}, err
+	}
+	if parentID.Valid {
+		// Walking up from the new parent must not reach the category itself.
+		var cycle bool
+		err = tx.QueryRowContext(ctx, `
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id FROM categories WHERE id = $1
+    UNION
+    SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`, parentID.Int64, c.ID).Scan(&cycle)
+		if err != nil {
+			return data.Category{}, err
+		}
+		if cycle {
+			return data.Category{}, data.ErrCategoryCycle
+		}
+	}
+
+	for locale, name := range upd.Names {
+		if name == "" {
+			delete(c.Names, locale)
+			continue
+		}
+		c.Names[locale] = name
+	}
+	if len(c.Names) == 0 {
+		return data.Category{}, data.ErrNoCategoryNames
+	}
+	names, err := json.Marshal(c.Names)
+	if err != nil {
+		return data.Category{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE categories SET parent_id = $1, names = $2 WHERE id = $3`, parentID, string(names), c.ID)
+	if err != nil {
+		return data.Category{}, err
+	}
+
+	return *c, tx.Commit()
+}
+
+// DeleteCategory removes an unused category. Toys are checked through
+// json_each on their categories, deleted toys included; subcategories are
+// caught by the parent_id foreign key.
+func (s *Storage) DeleteCategory(ctx context.Context, slug string) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.DeleteCategory",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM toys, json_each(toys.categories) AS c WHERE c.value = $1)`, slug).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return data.ErrCategoryInUse
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM categories WHERE slug = $1`, slug)
+	if err != nil {
+		switch errorCode(err) {
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return data.ErrCategoryInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return data.ErrRecordNotFound
+	}
+	return tx.Commit()
+}
+
+// Taxonomy builds the category tree from ListCategories.
+func (s *Storage) Taxonomy(ctx context.Context) (*data.Taxonomy, error) {
+	categories, err := s.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return data.NewTaxonomy(categories), nil
+}
+
+// categoryID resolves a parent slug; an empty slug means no parent.
+func categoryID(ctx context.Context, tx *sql.Tx, slug string) (sql.NullInt64, error) {
+	if slug == "" {
+		return sql.NullInt64{}, nil
+	}
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM categories WHERE slug = $1`, slug).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return sql.NullInt64{}, data.ErrRecordNotFound
+		default:
+			return sql.NullInt64{}, err
+		}
+	}
+	return sql.NullInt64{Int64: id, Valid: true}, nil
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"toysService/internal/data"
+)
+
+// ExportToys calls fn for every toy matching the ListToy filters in q, in id
+// order. The rows are streamed from a single query, which in WAL mode reads
+// one snapshot of the catalogue however long it takes. There is no timeout;
+// the caller's context bounds the export, and an error from fn stops it.
+func (s *Storage) ExportToys(ctx context.Context, q data.ToyQuery, fn func(toy *data.Toy) error) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ExportToys",
+	})
+
+	args := []any{q.Title}
+	query := `
+SELECT id, COALESCE(sku, ''), title, description, value, recommended_age, min_age_months, COALESCE(max_age_months, 0), ` + manufacturerColumns + `,
+    categories, skills, images, ` + availableUnits + `, created_at
+FROM toys
+WHERE deleted_at IS NULL
+AND ($1 = '' OR ` + fullTextSearch.match + `)`
+	filters, err := toyFilters(q, &args)
+	if err != nil {
+		return err
+	}
+	for _, f := range filters {
+		query += " AND " + f.Cond
+	}
+	query += " ORDER BY id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var toy data.Toy
+		err := rows.Scan(
+			&toy.ID,
+			&toy.SKU,
+			&toy.Title,
+			&toy.Desc,
+			&toy.Value,
+			&toy.RecAge,
+			&toy.MinAgeMonths,
+			&toy.MaxAgeMonths,
+			&toy.ManufacturerID,
+			&toy.Manufacturer,
+			jsonArray(&toy.Categories),
+			jsonArray(&toy.Skills),
+			jsonArray(&toy.Images),
+			&toy.AvailableCount,
+			&toy.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		toy.IsAvailable = toy.AvailableCount > 0
+		if err = fn(&toy); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
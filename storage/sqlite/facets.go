@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+	"toysService/internal/data"
+	"toysService/storage/facetsql"
+)
+
+// facetDialect reads the category and skill JSON arrays with json_each.
+// Column types follow the values, so the constants need no casts.
+var facetDialect = facetsql.Dialect{
+	Sources: map[string]string{
+		data.FacetCategory: `SELECT v.value AS name, 0 AS id, 0 AS lo, 0 AS hi FROM base, json_each(base.categories) AS v WHERE %s`,
+		data.FacetSkill:    `SELECT v.value AS name, 0 AS id, 0 AS lo, 0 AS hi FROM base, json_each(base.skills) AS v WHERE %s`,
+		data.FacetManufacturer: `SELECT m.name AS name, m.id AS id, 0 AS lo, 0 AS hi FROM base JOIN manufacturers m ON m.id = base.manufacturer_id
+    WHERE %s`,
+		data.FacetAge: `SELECT b.name AS name, 0 AS id, b.lo AS lo, b.hi AS hi FROM base JOIN age_bands b
+    ON base.min_age_months <= b.hi AND (base.max_age_months IS NULL OR base.max_age_months >= b.lo) WHERE %s`,
+		data.FacetValue: `SELECT b.name AS name, 0 AS id, b.lo AS lo, b.hi AS hi FROM base JOIN value_buckets b
+    ON base.value BETWEEN b.lo AND b.hi WHERE %s`,
+	},
+	Int: "%d",
+}
+
+// ToyFacets counts the toys matching q per facet like the postgres backend.
+// A fuzzy count matches with the word_similarity function registered in
+// functions.go instead of the FTS5 index.
+func (s *Storage) ToyFacets(ctx context.Context, q data.ToyQuery, fuzzy bool) (data.Facets, error) {
+	search := fullTextSearch
+	if fuzzy {
+		search = fuzzySearch
+	}
+
+	args := []any{q.Title}
+	filters, err := toyFilters(q, &args)
+	if err != nil {
+		return data.Facets{}, err
+	}
+	query := facetDialect.Query(search.match, filters)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return data.Facets{}, err
+	}
+	defer rows.Close()
+
+	return facetsql.Scan(rows)
+}
@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"toysService/internal/jsonlog"
+	migrations "toysService/migrations/sqlite"
+	"toysService/storage/storagetest"
+)
+
+// newTestStorage opens a migrated database in a temporary directory.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	s, err := OpenDB(StorageDetails{
+		DSN:          filepath.Join(t.TempDir(), "toys.db"),
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+		MaxIdleTime:  "1m",
+	}, jsonlog.New(io.Discard, jsonlog.LevelFatal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.db.Close() })
+
+	m, err := s.Migrator(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestToyFacets(t *testing.T) {
+	storagetest.TestToyFacets(t, newTestStorage(t))
+}
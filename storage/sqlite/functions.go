@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"modernc.org/sqlite"
+	"strings"
+	"toysService/internal/textsearch"
+)
+
+// The schema and queries lean on a few functions SQLite lacks. They are
+// registered with the driver for every connection, so a database file can
+// only be written through this package; the sqlite3 shell can still read
+// it.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("casefold", 1, casefold)
+	sqlite.MustRegisterDeterministicScalarFunction("manufacturer_key", 1, manufacturerKey)
+	sqlite.MustRegisterDeterministicScalarFunction("toys_lexemes", 1, toysLexemes)
+	sqlite.MustRegisterDeterministicScalarFunction("toys_match_query", 1, toysMatchQuery)
+	sqlite.MustRegisterDeterministicScalarFunction("toys_headline", 2, toysHeadline)
+	sqlite.MustRegisterDeterministicScalarFunction("word_similarity", 2, wordSimilarity)
+	sqlite.MustRegisterDeterministicScalarFunction("float4", 1, float4)
+	sqlite.MustRegisterCollationUtf8("toys_text", collateText)
+}
+
+// text reads a text argument; NULL reads as "".
+func text(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// collateText is the toys_text collation: text sorts like in postgres, and
+// the char(1)-joined sort keys of array columns compare element by element.
+func collateText(a, b string) int {
+	return textsearch.CompareLists(strings.Split(a, "\x01"), strings.Split(b, "\x01"))
+}
+
+// casefold lowercases any script, unlike lower() which only knows ASCII.
+func casefold(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	return strings.ToLower(text(args[0])), nil
+}
+
+// manufacturerKey is what makes two manufacturer names the same brand:
+// " LEGO ", "Lego" and "lego" all map to "lego".
+func manufacturerKey(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	return strings.ToLower(strings.Join(strings.Fields(text(args[0])), " ")), nil
+}
+
+// toysLexemes is what toys_search indexes a text as: its lexemes separated
+// by spaces.
+func toysLexemes(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	return strings.Join(textsearch.Lexemes(text(args[0])), " "), nil
+}
+
+// toysMatchQuery turns search text with the websearch syntax into an FTS5
+// query over toys_search, as websearch_to_tsquery does for postgres.
+func toysMatchQuery(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	return matchExpression(textsearch.Parse(text(args[0]))), nil
+}
+
+// matchExpression renders a parsed search as an FTS5 query. FTS5's NOT can
+// only subtract from something, so clauses of nothing but negated words
+// match nothing; a query without any clause left is the empty phrase, which
+// never matches either.
+func matchExpression(query textsearch.Query) string {
+	var clauses []string
+	for _, clause := range query {
+		var include, exclude []string
+		for _, atom := range clause {
+			// Lexemes are letters and digits only and need no escaping.
+			phrase := `"` + strings.Join(atom.Lexemes, " ") + `"`
+			if atom.Negated {
+				exclude = append(exclude, phrase)
+			} else {
+				include = append(include, phrase)
+			}
+		}
+		if len(include) == 0 {
+			continue
+		}
+		expr := strings.Join(include, " AND ")
+		for _, phrase := range exclude {
+			expr += " NOT " + phrase
+		}
+		clauses = append(clauses, "("+expr+")")
+	}
+	if len(clauses) == 0 {
+		return `""`
+	}
+	return strings.Join(clauses, " OR ")
+}
+
+// toysHeadline highlights the words of a text that the search (the second
+// argument) looks for, like ts_headline.
+func toysHeadline(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	return textsearch.Snippet(text(args[0]), textsearch.Parse(text(args[1]))), nil
+}
+
+func wordSimilarity(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	return float64(textsearch.WordSimilarity(text(args[0]), text(args[1]))), nil
+}
+
+// float4 rounds a number to single precision. Ranks are float32 in
+// data.Toy and in cursors, so they are compared at that precision in SQL
+// too, as the real ranks of postgres are.
+func float4(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	switch v := args[0].(type) {
+	case float64:
+		return float64(float32(v)), nil
+	case int64:
+		return float64(float32(v)), nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,202 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	"toysService/internal/data"
+)
+
+const imageColumns = `id, COALESCE(toy_id, 0), blob_key, thumbnail_key, url, thumbnail_url, content_type, width, height, size_bytes, alt, position, is_primary, created_at`
+
+func scanImage(row interface{ Scan(...any) error }) (*data.ToyImage, error) {
+	var img data.ToyImage
+	err := row.Scan(&img.ID, &img.ToyID, &img.Key, &img.ThumbnailKey, &img.URL, &img.ThumbnailURL, &img.ContentType,
+		&img.Width, &img.Height, &img.Size, &img.Alt, &img.Position, &img.Primary, &img.CreatedAt)
+	return &img, err
+}
+
+// InsertImage records the row of an image whose blobs the upload has
+// already stored, filling in its id and creation time.
+func (s *Storage) InsertImage(ctx context.Context, img data.ToyImage) (data.ToyImage, error) {
+	query := `
+INSERT INTO toy_images (blob_key, thumbnail_key, url, thumbnail_url, content_type, width, height, size_bytes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, img.Key, img.ThumbnailKey, img.URL, img.ThumbnailURL, img.ContentType,
+		img.Width, img.Height, img.Size).Scan(&img.ID, &img.CreatedAt)
+	if err != nil {
+		return data.ToyImage{}, err
+	}
+	return img, nil
+}
+
+func (s *Storage) ListToyImages(ctx context.Context, toyID int64) ([]*data.ToyImage, error) {
+	query := `SELECT ` + imageColumns + ` FROM toy_images WHERE toy_id = $1 ORDER BY position, id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, toyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []*data.ToyImage{}
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// SetToyImages replaces the toy's gallery with items, in order, detaching
+// the images left out. The first image is primary unless another one is
+// marked. The toy's JSON images column is rewritten to the new URLs as a
+// versioned change of the toy.
+func (s *Storage) SetToyImages(ctx context.Context, toyID int64, items []data.ToyImage) ([]*data.ToyImage, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.SetToyImages",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := getToyForUpdate(ctx, tx, toyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	primary := -1
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+		if item.Primary {
+			primary = i
+		}
+	}
+	if primary < 0 && len(items) > 0 {
+		primary = 0
+	}
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, COALESCE(toy_id, 0) FROM toy_images WHERE id IN (SELECT value FROM json_each($1))`, string(idsJSON))
+	if err != nil {
+		return nil, err
+	}
+	found := map[int64]bool{}
+	for rows.Next() {
+		var id, owner int64
+		if err = rows.Scan(&id, &owner); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if owner != 0 && owner != toyID {
+			rows.Close()
+			return nil, data.ErrImageAttached
+		}
+		found[id] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(found) != len(ids) {
+		return nil, data.ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+UPDATE toy_images SET toy_id = NULL, position = 0, is_primary = false
+WHERE toy_id = $1 AND id NOT IN (SELECT value FROM json_each($2))`, toyID, string(idsJSON))
+	if err != nil {
+		return nil, err
+	}
+	// Clear the primary flag first so the partial unique index never sees two.
+	if _, err = tx.ExecContext(ctx, `UPDATE toy_images SET is_primary = false WHERE toy_id = $1`, toyID); err != nil {
+		return nil, err
+	}
+
+	images := make([]*data.ToyImage, 0, len(items))
+	urls := make([]string, 0, len(items))
+	for i, item := range items {
+		img, err := scanImage(tx.QueryRowContext(ctx, `
+UPDATE toy_images SET toy_id = $1, position = $2, alt = $3, is_primary = $4
+WHERE id = $5
+RETURNING `+imageColumns, toyID, i, item.Alt, i == primary, item.ID))
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+		urls = append(urls, img.URL)
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE toys SET images = $1, version = version + 1 WHERE id = $2`, jsonArray(&urls), toyID); err != nil {
+		return nil, err
+	}
+
+	after := before
+	after.Images = urls
+	changes := data.DiffToys(before, after)
+	if err = insertAudit(ctx, tx, toyID, data.AuditActionChange, changes); err != nil {
+		return nil, err
+	}
+	after.Version++
+	if err = insertEvent(ctx, tx, data.EventToyChanged, data.ToyEventPayload{ToyID: toyID, Toy: &after, Changes: changes}); err != nil {
+		return nil, err
+	}
+
+	return images, tx.Commit()
+}
+
+// DeleteImage deletes a detached image and returns its row, whose blob keys
+// the caller removes from the blob store.
+func (s *Storage) DeleteImage(ctx context.Context, id int64) (data.ToyImage, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.DeleteImage",
+	})
+	query := `DELETE FROM toy_images WHERE id = $1 AND toy_id IS NULL RETURNING ` + imageColumns
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	img, err := scanImage(s.db.QueryRowContext(ctx, query, id))
+	if err == nil {
+		return *img, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return data.ToyImage{}, err
+	}
+
+	var exists bool
+	if err = s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM toy_images WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return data.ToyImage{}, err
+	}
+	if exists {
+		return data.ToyImage{}, data.ErrImageAttached
+	}
+	return data.ToyImage{}, data.ErrRecordNotFound
+}
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"toysService/internal/data"
+)
+
+func nullSKU(sku string) sql.NullString {
+	return sql.NullString{String: sku, Valid: sku != ""}
+}
+
+// Vocabulary bundles the taxonomy, skills and manufacturers that
+// NormalizeToy needs, for the import command.
+func (s *Storage) Vocabulary(ctx context.Context) (data.Vocabulary, error) {
+	taxonomy, err := s.Taxonomy(ctx)
+	if err != nil {
+		return data.Vocabulary{}, err
+	}
+	skills, err := s.SkillDictionary(ctx)
+	if err != nil {
+		return data.Vocabulary{}, err
+	}
+	manufacturers, err := s.ManufacturerDirectory(ctx)
+	if err != nil {
+		return data.Vocabulary{}, err
+	}
+	return data.Vocabulary{Categories: taxonomy, Skills: skills, Manufacturers: manufacturers}, nil
+}
+
+// ImportToys upserts one batch of validated toys by SKU inside a single
+// transaction. A row that fails is rolled back to its savepoint and reported
+// without affecting the rest of the batch. Rows whose toy has a nil Images
+// keep the images the toy already has.
+//
+// With dryRun nothing is written: the transaction is always rolled back and
+// the results describe what the import would do.
+func (s *Storage) ImportToys(ctx context.Context, rows []data.ImportRow, dryRun bool) ([]data.ImportResult, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ImportToys",
+		"rows":   strconv.Itoa(len(rows)),
+		"dryRun": strconv.FormatBool(dryRun),
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, batchTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]data.ImportResult, 0, len(rows))
+	for _, row := range rows {
+		if _, err = tx.ExecContext(ctx, `SAVEPOINT import_row`); err != nil {
+			return nil, err
+		}
+
+		result, err := importToy(ctx, tx, row.Toy, dryRun)
+		if err != nil {
+			if _, rerr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT import_row`); rerr != nil {
+				return nil, err
+			}
+			result = data.ImportResult{Action: data.ImportActionError, Error: err.Error()}
+		}
+		result.Line, result.SKU = row.Line, row.Toy.SKU
+		results = append(results, result)
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	return results, tx.Commit()
+}
+
+func importToy(ctx context.Context, tx *sql.Tx, toy data.Toy, dryRun bool) (data.ImportResult, error) {
+	before, deleted, err := getToyBySKU(ctx, tx, toy.SKU)
+	if errors.Is(err, sql.ErrNoRows) {
+		result := data.ImportResult{Action: data.ImportActionCreate, Changes: data.DiffToys(data.Toy{}, toy)}
+		if dryRun {
+			return result, nil
+		}
+		created, err := insertToy(ctx, tx, toy)
+		if err != nil {
+			return data.ImportResult{}, err
+		}
+		result.ToyID = created.ID
+		return result, nil
+	}
+	if err != nil {
+		return data.ImportResult{}, err
+	}
+	if deleted {
+		return data.ImportResult{}, data.ErrDeletedSKU
+	}
+
+	toy.ID, toy.Version = before.ID, before.Version
+	if toy.Images == nil {
+		toy.Images = before.Images
+	}
+	changes := data.DiffToys(before, toy)
+	if len(changes) == 0 {
+		return data.ImportResult{Action: data.ImportActionUnchanged, ToyID: before.ID}, nil
+	}
+	if !dryRun {
+		if _, err = updateToy(ctx, tx, before, &toy); err != nil {
+			return data.ImportResult{}, err
+		}
+	}
+	return data.ImportResult{Action: data.ImportActionChange, ToyID: before.ID, Changes: changes}, nil
+}
+
+// getToyBySKU reads the toy with an SKU, deleted or not, and reports whether
+// it is deleted.
+func getToyBySKU(ctx context.Context, tx *sql.Tx, sku string) (data.Toy, bool, error) {
+	query := `
+SELECT ` + lockedToyColumns + `, deleted_at IS NOT NULL
+FROM toys
+WHERE sku = $1`
+
+	var deleted bool
+	toy, err := scanLockedToy(tx.QueryRowContext(ctx, query, sku), &deleted)
+	return toy, deleted, err
+}
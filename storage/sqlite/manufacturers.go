@@ -0,0 +1,236 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	sqlite3 "modernc.org/sqlite/lib"
+	"time"
+	"toysService/internal/data"
+)
+
+// registerManufacturer gives a toy whose manufacturer NormalizeToy did not
+// know the ID of the brand with that name, creating the brand if it still
+// does not exist.
+func registerManufacturer(ctx context.Context, tx *sql.Tx, toy *data.Toy) error {
+	if toy.ManufacturerID != 0 || toy.Manufacturer == "" {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO manufacturers (name) VALUES ($1) ON CONFLICT DO NOTHING`, toy.Manufacturer); err != nil {
+		return err
+	}
+	query := `SELECT id, name FROM manufacturers WHERE manufacturer_key(name) = manufacturer_key($1)`
+	return tx.QueryRowContext(ctx, query, toy.Manufacturer).Scan(&toy.ManufacturerID, &toy.Manufacturer)
+}
+
+// nullID stores a missing manufacturer as NULL.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id > 0}
+}
+
+// manufacturerToys counts the live toys of the manufacturer in the current row.
+const manufacturerToys = `(SELECT count(*) FROM toys WHERE toys.manufacturer_id = m.id AND toys.deleted_at IS NULL)`
+
+const manufacturerSelect = `
+SELECT m.id, m.name, m.country, m.logo_url, m.description, ` + manufacturerToys + `, m.created_at
+FROM manufacturers m`
+
+func scanManufacturer(row interface{ Scan(...any) error }) (*data.Manufacturer, error) {
+	var m data.Manufacturer
+	err := row.Scan(&m.ID, &m.Name, &m.Country, &m.LogoURL, &m.Description, &m.Toys, &m.CreatedAt)
+	return &m, err
+}
+
+func (s *Storage) ListManufacturers(ctx context.Context) ([]*data.Manufacturer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, manufacturerSelect+` ORDER BY casefold(m.name)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	manufacturers := []*data.Manufacturer{}
+	for rows.Next() {
+		m, err := scanManufacturer(rows)
+		if err != nil {
+			return nil, err
+		}
+		manufacturers = append(manufacturers, m)
+	}
+	return manufacturers, rows.Err()
+}
+
+// GetBrandPage loads a manufacturer's page: its live toy count and the live
+// toys with the most rentals in toy_rentals.
+func (s *Storage) GetBrandPage(ctx context.Context, id int64) (data.BrandPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	m, err := scanManufacturer(s.db.QueryRowContext(ctx, manufacturerSelect+` WHERE m.id = $1`, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.BrandPage{}, data.ErrRecordNotFound
+		default:
+			return data.BrandPage{}, err
+		}
+	}
+
+	query := `
+SELECT id, title, categories, skills, recommended_age, min_age_months, COALESCE(max_age_months, 0), value, ` + availableUnits + `
+FROM toys
+WHERE manufacturer_id = $1 AND deleted_at IS NULL
+ORDER BY (SELECT count(*) FROM toy_rentals r WHERE r.toy_id = toys.id) DESC, id
+LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, id, data.TopToysLimit)
+	if err != nil {
+		return data.BrandPage{}, err
+	}
+	defer rows.Close()
+
+	page := data.BrandPage{Manufacturer: *m, TopToys: []*data.Toy{}}
+	for rows.Next() {
+		toy := data.Toy{Manufacturer: m.Name, ManufacturerID: m.ID}
+		err := rows.Scan(
+			&toy.ID,
+			&toy.Title,
+			jsonArray(&toy.Categories),
+			jsonArray(&toy.Skills),
+			&toy.RecAge,
+			&toy.MinAgeMonths,
+			&toy.MaxAgeMonths,
+			&toy.Value,
+			&toy.AvailableCount,
+		)
+		if err != nil {
+			return data.BrandPage{}, err
+		}
+		toy.IsAvailable = toy.AvailableCount > 0
+		page.TopToys = append(page.TopToys, &toy)
+	}
+	return page, rows.Err()
+}
+
+func (s *Storage) CreateManufacturer(ctx context.Context, m data.Manufacturer) (data.Manufacturer, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.CreateManufacturer",
+	})
+	query := `
+INSERT INTO manufacturers (name, country, logo_url, description)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, m.Name, m.Country, m.LogoURL, m.Description).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return data.Manufacturer{}, manufacturerError(err)
+	}
+	return m, nil
+}
+
+// UpdateManufacturer applies upd to a manufacturer. Toys reference it by
+// manufacturer_id, so a rename needs no change to them.
+func (s *Storage) UpdateManufacturer(ctx context.Context, id int64, upd data.ManufacturerUpdate) (data.Manufacturer, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.UpdateManufacturer",
+	})
+	query := `
+UPDATE manufacturers
+SET name = COALESCE($1, name), country = COALESCE($2, country), logo_url = COALESCE($3, logo_url), description = COALESCE($4, description)
+WHERE id = $5`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Manufacturer{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, query, upd.Name, upd.Country, upd.LogoURL, upd.Description, id); err != nil {
+		return data.Manufacturer{}, manufacturerError(err)
+	}
+
+	// RETURNING cannot run subqueries, so the result is read back instead.
+	m, err := scanManufacturer(tx.QueryRowContext(ctx, manufacturerSelect+` WHERE m.id = $1`, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Manufacturer{}, data.ErrRecordNotFound
+		default:
+			return data.Manufacturer{}, err
+		}
+	}
+	return *m, tx.Commit()
+}
+
+// DeleteManufacturer deletes a manufacturer, relying on the foreign key of
+// toys.manufacturer_id to refuse while any toy, deleted or not, uses it.
+func (s *Storage) DeleteManufacturer(ctx context.Context, id int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.DeleteManufacturer",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM manufacturers WHERE id = $1`, id)
+	if err != nil {
+		switch errorCode(err) {
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return data.ErrManufacturerInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return data.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ManufacturerDirectory reads every manufacturer's id and name so toy writes
+// can resolve manufacturer names.
+func (s *Storage) ManufacturerDirectory(ctx context.Context) (*data.ManufacturerDirectory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name FROM manufacturers`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var manufacturers []*data.Manufacturer
+	for rows.Next() {
+		var m data.Manufacturer
+		if err = rows.Scan(&m.ID, &m.Name); err != nil {
+			return nil, err
+		}
+		manufacturers = append(manufacturers, &m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return data.NewManufacturerDirectory(manufacturers), nil
+}
+
+func manufacturerError(err error) error {
+	switch errorCode(err) {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return data.ErrDuplicateManufacturer
+	default:
+		return err
+	}
+}
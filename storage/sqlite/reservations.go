@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	sqlite3 "modernc.org/sqlite/lib"
+	"time"
+	"toysService/internal/data"
+)
+
+// ReserveToy books a free copy of the toy for the requested period. The
+// transaction holds the write lock from its start, so concurrent requests are
+// serialized, and the toy_reservations_no_overlap trigger rejects any overlap
+// that slips through.
+func (s *Storage) ReserveToy(ctx context.Context, r data.Reservation) (data.Reservation, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ReserveToy",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Reservation{}, err
+	}
+	defer tx.Rollback()
+
+	var toyID int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM toys WHERE id = $1 AND deleted_at IS NULL`, r.ToyID).Scan(&toyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Reservation{}, data.ErrRecordNotFound
+		default:
+			return data.Reservation{}, err
+		}
+	}
+
+	query := `
+SELECT u.id
+FROM toy_units u
+WHERE u.toy_id = $1
+AND u.status NOT IN ('retired', 'maintenance', 'rented')
+AND NOT EXISTS (
+    SELECT 1 FROM toy_reservations tr
+    WHERE tr.unit_id = u.id AND tr.status = 'active' AND tr.starts_at < $3 AND $2 < tr.ends_at
+)
+ORDER BY u.id ASC
+LIMIT 1`
+
+	startsAt, endsAt := timestamp(r.StartsAt), timestamp(r.EndsAt)
+	err = tx.QueryRowContext(ctx, query, r.ToyID, startsAt, endsAt).Scan(&r.UnitID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Reservation{}, data.ErrReservationConflict
+		default:
+			return data.Reservation{}, err
+		}
+	}
+
+	query = `
+INSERT INTO toy_reservations (toy_id, unit_id, user_id, starts_at, ends_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, status, created_at`
+
+	err = tx.QueryRowContext(ctx, query, r.ToyID, r.UnitID, r.UserID, startsAt, endsAt).Scan(&r.ID, &r.Status, &r.CreatedAt)
+	if err != nil {
+		switch errorCode(err) {
+		case sqlite3.SQLITE_CONSTRAINT_TRIGGER:
+			return data.Reservation{}, data.ErrReservationConflict
+		default:
+			return data.Reservation{}, err
+		}
+	}
+
+	// The reservation is recorded as a rental of the toy for the reserved
+	// period, which is what ListRecommended and brand pages learn from.
+	query = `
+INSERT INTO toy_rentals (user_id, toy_id, reservation_id, rented_at, returned_at)
+VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err = tx.ExecContext(ctx, query, r.UserID, r.ToyID, r.ID, startsAt, endsAt); err != nil {
+		return data.Reservation{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return data.Reservation{}, err
+	}
+
+	return r, nil
+}
+
+func (s *Storage) CancelReservation(ctx context.Context, reservationID int64, userID int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.CancelReservation",
+	})
+	query := `
+UPDATE toy_reservations
+SET status = 'cancelled'
+WHERE id = $1 AND user_id = $2 AND status = 'active'`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, reservationID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return data.ErrRecordNotFound
+	}
+
+	// A cancelled reservation was never a rental.
+	if _, err = tx.ExecContext(ctx, `DELETE FROM toy_rentals WHERE reservation_id = $1`, reservationID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) ListReservations(ctx context.Context, userID int64) ([]*data.Reservation, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ListReservations",
+	})
+	query := `
+SELECT id, toy_id, unit_id, user_id, starts_at, ends_at, status, created_at
+FROM toy_reservations
+WHERE user_id = $1
+ORDER BY starts_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []*data.Reservation{}
+	for rows.Next() {
+		var r data.Reservation
+		err := rows.Scan(
+			&r.ID,
+			&r.ToyID,
+			&r.UnitID,
+			&r.UserID,
+			&r.StartsAt,
+			&r.EndsAt,
+			&r.Status,
+			&r.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, &r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
@@ -0,0 +1,278 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	sqlite3 "modernc.org/sqlite/lib"
+	"slices"
+	"time"
+	"toysService/internal/data"
+)
+
+// skillSynonyms is the JSON array of the synonyms of the skill in the
+// current row.
+const skillSynonyms = `(SELECT json_group_array(synonym ORDER BY synonym) FROM skill_synonyms WHERE skill_id = s.id)`
+
+// ListSkills lists the skill dictionary, most used first. Usage counts come
+// from json_each over the skills of live toys.
+func (s *Storage) ListSkills(ctx context.Context) ([]*data.Skill, error) {
+	query := `
+SELECT s.id, s.name, ` + skillSynonyms + `,
+    (SELECT count(*) FROM toys WHERE deleted_at IS NULL AND EXISTS (SELECT 1 FROM json_each(toys.skills) WHERE value = s.name)),
+    s.created_at
+FROM skills s
+ORDER BY 4 DESC, s.name`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skills := []*data.Skill{}
+	for rows.Next() {
+		var skill data.Skill
+		if err := rows.Scan(&skill.ID, &skill.Name, jsonArray(&skill.Synonyms), &skill.Toys, &skill.CreatedAt); err != nil {
+			return nil, err
+		}
+		skills = append(skills, &skill)
+	}
+	return skills, rows.Err()
+}
+
+func (s *Storage) CreateSkill(ctx context.Context, skill data.Skill) (data.Skill, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.CreateSkill",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Skill{}, err
+	}
+	defer tx.Rollback()
+
+	if err = checkSkillTerms(ctx, tx, 0, skill.Name, skill.Synonyms); err != nil {
+		return data.Skill{}, err
+	}
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO skills (name) VALUES ($1) RETURNING id, created_at`, skill.Name).Scan(&skill.ID, &skill.CreatedAt)
+	if err != nil {
+		return data.Skill{}, skillError(err)
+	}
+	if err = setSkillSynonyms(ctx, tx, skill.ID, skill.Synonyms); err != nil {
+		return data.Skill{}, err
+	}
+	if skill.Synonyms == nil {
+		skill.Synonyms = []string{}
+	}
+
+	return skill, tx.Commit()
+}
+
+// UpdateSkill renames a skill and/or replaces its synonyms. A rename
+// rewrites the skills JSON array of every toy that lists the old name,
+// deleted toys included, and bumps their versions.
+func (s *Storage) UpdateSkill(ctx context.Context, id int64, upd data.SkillUpdate) (data.Skill, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.UpdateSkill",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.Skill{}, err
+	}
+	defer tx.Rollback()
+
+	var skill data.Skill
+	query := `
+SELECT s.id, s.name, ` + skillSynonyms + `, s.created_at
+FROM skills s
+WHERE s.id = $1`
+	err = tx.QueryRowContext(ctx, query, id).Scan(&skill.ID, &skill.Name, jsonArray(&skill.Synonyms), &skill.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Skill{}, data.ErrRecordNotFound
+		default:
+			return data.Skill{}, err
+		}
+	}
+
+	oldName := skill.Name
+	if upd.Name != nil {
+		skill.Name = *upd.Name
+	}
+	if upd.Synonyms != nil {
+		skill.Synonyms = upd.Synonyms
+	}
+	if err = checkSkillTerms(ctx, tx, skill.ID, skill.Name, skill.Synonyms); err != nil {
+		return data.Skill{}, err
+	}
+
+	if skill.Name != oldName {
+		if _, err = tx.ExecContext(ctx, `UPDATE skills SET name = $1 WHERE id = $2`, skill.Name, skill.ID); err != nil {
+			return data.Skill{}, skillError(err)
+		}
+		if err = renameToySkill(ctx, tx, oldName, skill.Name); err != nil {
+			return data.Skill{}, err
+		}
+	}
+	if upd.Synonyms != nil {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM skill_synonyms WHERE skill_id = $1`, skill.ID); err != nil {
+			return data.Skill{}, err
+		}
+		if err = setSkillSynonyms(ctx, tx, skill.ID, skill.Synonyms); err != nil {
+			return data.Skill{}, err
+		}
+	}
+
+	return skill, tx.Commit()
+}
+
+// renameToySkill renames a skill on every toy that has it. Each toy changes
+// like an edit would: it gets a new version, an audit entry and a change
+// event.
+func renameToySkill(ctx context.Context, tx *sql.Tx, oldName, newName string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM toys WHERE EXISTS (SELECT 1 FROM json_each(toys.skills) WHERE value = $1) ORDER BY id`, oldName)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		before, err := scanLockedToy(tx.QueryRowContext(ctx, `SELECT `+lockedToyColumns+` FROM toys WHERE id = $1`, id))
+		if err != nil {
+			return err
+		}
+
+		after := before
+		after.Skills = slices.Clone(before.Skills)
+		for i, name := range after.Skills {
+			if name == oldName {
+				after.Skills[i] = newName
+			}
+		}
+		if _, err = tx.ExecContext(ctx, `UPDATE toys SET skills = $1, version = version + 1 WHERE id = $2`, jsonArray(&after.Skills), id); err != nil {
+			return err
+		}
+
+		changes := data.DiffToys(before, after)
+		if err = insertAudit(ctx, tx, id, data.AuditActionChange, changes); err != nil {
+			return err
+		}
+		after.Version++
+		if err = insertEvent(ctx, tx, data.EventToyChanged, data.ToyEventPayload{ToyID: id, Toy: &after, Changes: changes}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) DeleteSkill(ctx context.Context, id int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.DeleteSkill",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `
+SELECT EXISTS (SELECT 1 FROM toys, json_each(toys.skills) AS sk WHERE sk.value = s.name)
+FROM skills s
+WHERE s.id = $1`, id).Scan(&inUse)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	if inUse {
+		return data.ErrSkillInUse
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM skills WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SkillDictionary builds the skill lookup from ListSkills.
+func (s *Storage) SkillDictionary(ctx context.Context) (*data.SkillDictionary, error) {
+	skills, err := s.ListSkills(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return data.NewSkillDictionary(skills), nil
+}
+
+// checkSkillTerms rejects a name or synonym that another skill already uses as
+// its name or one of its synonyms. The unique indexes only catch clashes
+// within the same column.
+func checkSkillTerms(ctx context.Context, tx *sql.Tx, id int64, name string, synonyms []string) error {
+	terms, err := json.Marshal(append([]string{name}, synonyms...))
+	if err != nil {
+		return err
+	}
+	var taken bool
+	err = tx.QueryRowContext(ctx, `
+WITH terms AS (SELECT casefold(value) AS term FROM json_each($2))
+SELECT EXISTS (SELECT 1 FROM skills WHERE id <> $1 AND casefold(name) IN (SELECT term FROM terms))
+    OR EXISTS (SELECT 1 FROM skill_synonyms WHERE skill_id <> $1 AND casefold(synonym) IN (SELECT term FROM terms))`,
+		id, string(terms)).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return data.ErrDuplicateSkill
+	}
+	return nil
+}
+
+func setSkillSynonyms(ctx context.Context, tx *sql.Tx, id int64, synonyms []string) error {
+	if len(synonyms) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO skill_synonyms (skill_id, synonym) SELECT $1, value FROM json_each($2)`, id, jsonArray(&synonyms))
+	return skillError(err)
+}
+
+func skillError(err error) error {
+	switch errorCode(err) {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return data.ErrDuplicateSkill
+	default:
+		return err
+	}
+}
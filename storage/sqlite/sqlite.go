@@ -0,0 +1,719 @@
+// Package sqlite is a toys storage on a single SQLite file, for deployments
+// that run the catalogue on one machine without a postgres server. It keeps
+// the postgres backend's behaviour, down to its status messages; the
+// schema lives in migrations/sqlite.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
+	"modernc.org/sqlite"
+	"slices"
+	"strings"
+	"time"
+	"toysService/internal/data"
+	"toysService/internal/jsonlog"
+)
+
+type Storage struct {
+	db  *sql.DB
+	log *jsonlog.Logger
+}
+
+// availableUnits counts the physical copies of the toy in the current row
+// that can be handed out right now.
+const availableUnits = `(SELECT count(*) FROM toy_units u WHERE u.toy_id = toys.id AND u.status = 'available')`
+
+// manufacturerColumns reads the manufacturer reference of the current row and
+// the name it points to.
+const manufacturerColumns = `COALESCE(manufacturer_id, 0), COALESCE((SELECT m.name FROM manufacturers m WHERE m.id = toys.manufacturer_id), '')`
+
+// StorageDetails mirrors the postgres settings. DSN is the path of the
+// database file, which is created if it does not exist.
+type StorageDetails struct {
+	DSN          string
+	MaxOpenConns int
+	MaxIdleConns int
+	MaxIdleTime  string
+}
+
+// connectionParams turn on what the storage relies on: foreign keys,
+// write-ahead logging so that readers never wait for the writer, a busy
+// timeout instead of immediate SQLITE_BUSY errors, and transactions that take
+// the write lock when they begin. The last one stands in for the row locks
+// of postgres: a write transaction has the whole file to itself.
+const connectionParams = "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+func OpenDB(details StorageDetails, logger *jsonlog.Logger) (*Storage, error) {
+	dsn := details.DSN
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + connectionParams
+	} else {
+		dsn += "?" + connectionParams
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(details.MaxOpenConns)
+	db.SetMaxIdleConns(details.MaxIdleConns)
+
+	duration, err := time.ParseDuration(details.MaxIdleTime)
+	if err != nil {
+		return nil, err
+	}
+	db.SetConnMaxIdleTime(duration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err = db.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	return &Storage{
+		db:  db,
+		log: logger,
+	}, nil
+}
+
+// Close closes the database file.
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// timeFormat is how timestamps are stored: UTC to the second, like the
+// timestamp(0) columns of postgres, in a layout that sorts as text.
+const timeFormat = "2006-01-02T15:04:05Z"
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// stringArray reads and writes a []string as a JSON array, the way pq.Array
+// does for text[] columns.
+type stringArray struct {
+	values *[]string
+}
+
+func jsonArray(values *[]string) stringArray {
+	return stringArray{values: values}
+}
+
+func (a stringArray) Value() (driver.Value, error) {
+	if *a.values == nil {
+		return "[]", nil
+	}
+	js, err := json.Marshal(*a.values)
+	return string(js), err
+}
+
+func (a stringArray) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(src), a.values)
+	case []byte:
+		return json.Unmarshal(src, a.values)
+	default:
+		return fmt.Errorf("cannot scan %T into a string array", src)
+	}
+}
+
+// errorCode returns the extended result code of a SQLite error, or 0 for
+// other errors.
+func errorCode(err error) int {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code()
+	}
+	return 0
+}
+
+func (s *Storage) CreateToy(ctx context.Context, inputToy data.Toy) (toys.Status, string, data.Toy) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.CreateToy",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Toy{}
+	}
+	defer tx.Rollback()
+
+	toy, err := insertToy(ctx, tx, inputToy)
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Toy{}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", data.Toy{}
+	}
+	return toys.Status_STATUS_OK, "toy added successfuly!", toy
+}
+
+// insertToy writes a new toy together with its audit entry and creation
+// event.
+func insertToy(ctx context.Context, tx *sql.Tx, inputToy data.Toy) (data.Toy, error) {
+	if err := registerManufacturer(ctx, tx, &inputToy); err != nil {
+		return data.Toy{}, err
+	}
+
+	query := `
+INSERT INTO toys (title, description, skills, categories, images, recommended_age, manufacturer_id, value, min_age_months, max_age_months, sku)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id`
+
+	args := []any{inputToy.Title, inputToy.Desc, jsonArray(&inputToy.Skills), jsonArray(&inputToy.Categories), jsonArray(&inputToy.Images), inputToy.RecAge, nullID(inputToy.ManufacturerID), inputToy.Value, inputToy.MinAgeMonths, nullAge(inputToy.MaxAgeMonths), nullSKU(inputToy.SKU)}
+
+	var toyID int64
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&toyID); err != nil {
+		return data.Toy{}, err
+	}
+	toy := data.Toy{
+		ID:             toyID,
+		SKU:            inputToy.SKU,
+		Title:          inputToy.Title,
+		Desc:           inputToy.Desc,
+		Value:          inputToy.Value,
+		Images:         inputToy.Images,
+		Skills:         inputToy.Skills,
+		Categories:     inputToy.Categories,
+		RecAge:         inputToy.RecAge,
+		MinAgeMonths:   inputToy.MinAgeMonths,
+		MaxAgeMonths:   inputToy.MaxAgeMonths,
+		Manufacturer:   inputToy.Manufacturer,
+		ManufacturerID: inputToy.ManufacturerID,
+		Version:        1,
+	}
+
+	if err := insertAudit(ctx, tx, toyID, data.AuditActionCreate, data.DiffToys(data.Toy{}, toy)); err != nil {
+		return data.Toy{}, err
+	}
+	if err := insertEvent(ctx, tx, data.EventToyCreated, data.ToyEventPayload{ToyID: toyID, Toy: &toy}); err != nil {
+		return data.Toy{}, err
+	}
+	return toy, nil
+}
+
+func (s *Storage) DeleteToy(ctx context.Context, toyID int64) (toys.Status, string) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.DeleteToy",
+	})
+	query := `
+UPDATE toys
+SET deleted_at = $2
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, toyID, timestamp(time.Now()))
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
+
+	if rowsAffected == 0 {
+		return toys.Status_STATUS_INTERNAL_ERROR, "it affected 0 rows!"
+	}
+
+	if err = insertAudit(ctx, tx, toyID, data.AuditActionDelete, []data.FieldChange{}); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
+
+	if err = insertEvent(ctx, tx, data.EventToyDeleted, data.ToyEventPayload{ToyID: toyID}); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
+
+	if err = tx.Commit(); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+	}
+	return toys.Status_STATUS_OK, "toy deletion was successful"
+}
+
+// RestoreToy clears deleted_at on a deleted toy. A miss is told apart in
+// the same transaction: data.ErrRecordNotFound if the toy does not exist,
+// data.ErrToyNotDeleted if it is live.
+func (s *Storage) RestoreToy(ctx context.Context, toyID int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.RestoreToy",
+	})
+	query := `
+UPDATE toys
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, toyID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		var exists bool
+		if err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM toys WHERE id = $1)`, toyID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return data.ErrToyNotDeleted
+		}
+		return data.ErrRecordNotFound
+	}
+
+	if err = insertAudit(ctx, tx, toyID, data.AuditActionRestore, []data.FieldChange{}); err != nil {
+		return err
+	}
+
+	if err = insertEvent(ctx, tx, data.EventToyRestored, data.ToyEventPayload{ToyID: toyID}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) ListDeletedToys(ctx context.Context, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ListDeletedToys",
+	})
+	query := fmt.Sprintf(`
+SELECT count(*) OVER(), id, title, categories, skills, recommended_age, min_age_months, COALESCE(max_age_months, 0), `+manufacturerColumns+`, value, deleted_at
+FROM toys
+WHERE deleted_at IS NOT NULL
+ORDER BY %s %s, id ASC
+LIMIT $1 OFFSET $2`, sortExpression(toySortColumn(filters, fullTextSearch)), filters.SortDirection())
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	toysList := []*data.Toy{}
+
+	for rows.Next() {
+		var toy data.Toy
+		err := rows.Scan(
+			&totalRecords,
+			&toy.ID,
+			&toy.Title,
+			jsonArray(&toy.Categories),
+			jsonArray(&toy.Skills),
+			&toy.RecAge,
+			&toy.MinAgeMonths,
+			&toy.MaxAgeMonths,
+			&toy.ManufacturerID,
+			&toy.Manufacturer,
+			&toy.Value,
+			&toy.DeletedAt,
+		)
+		if err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+		}
+		toysList = append(toysList, &toy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+	}
+
+	metadata := filters.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return toysList, toys.Status_STATUS_OK, "deleted toys listing was successful", metadata
+}
+
+// ChangeToy rewrites a toy read at toy.Version and bumps the version. The
+// immediate transaction takes the write lock up front, so the version read
+// cannot go stale before the update; a mismatch is data.ErrEditConflict.
+func (s *Storage) ChangeToy(ctx context.Context, toy data.Toy) (toys.Status, string, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ChangeToy",
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", err
+	}
+	defer tx.Rollback()
+
+	before, err := getToyForUpdate(ctx, tx, toy.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return toys.Status_STATUS_INTERNAL_ERROR, "operation affect zero rows!", data.ErrRecordNotFound
+		default:
+			return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", err
+		}
+	}
+
+	if before.Version != toy.Version {
+		return toys.Status_STATUS_INTERNAL_ERROR, "toy was changed by someone else", data.ErrEditConflict
+	}
+
+	if _, err = updateToy(ctx, tx, before, &toy); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return toys.Status_STATUS_INTERNAL_ERROR, "toy was changed by someone else", data.ErrEditConflict
+		default:
+			return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return toys.Status_STATUS_INTERNAL_ERROR, "internal error!", err
+	}
+	return toys.Status_STATUS_OK, "toys updated successfully!", nil
+}
+
+// updateToy overwrites the editable fields of a toy read as before in the
+// same transaction, bumps its version and records the audit entry and change
+// event. It returns sql.ErrNoRows if toy.Version no longer matches.
+func updateToy(ctx context.Context, tx *sql.Tx, before data.Toy, toy *data.Toy) ([]data.FieldChange, error) {
+	if err := registerManufacturer(ctx, tx, toy); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE toys
+SET title = $1, description = $2, skills = $3, images = $4, categories = $5, recommended_age = $6, manufacturer_id = $7, value = $8,
+    min_age_months = $9, max_age_months = $10, version = version + 1
+WHERE id = $11 AND version = $12 AND deleted_at IS NULL
+RETURNING id
+`
+	args := []any{
+		toy.Title,
+		toy.Desc,
+		jsonArray(&toy.Skills),
+		jsonArray(&toy.Images),
+		jsonArray(&toy.Categories),
+		toy.RecAge,
+		nullID(toy.ManufacturerID),
+		toy.Value,
+		toy.MinAgeMonths,
+		nullAge(toy.MaxAgeMonths),
+		toy.ID,
+		toy.Version,
+	}
+
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&toy.ID); err != nil {
+		return nil, err
+	}
+
+	changes := data.DiffToys(before, *toy)
+	if err := insertAudit(ctx, tx, toy.ID, data.AuditActionChange, changes); err != nil {
+		return nil, err
+	}
+
+	toy.Version++
+	toy.AvailableCount = before.AvailableCount
+	toy.IsAvailable = before.IsAvailable
+	if err := insertEvent(ctx, tx, data.EventToyChanged, data.ToyEventPayload{ToyID: toy.ID, Toy: toy, Changes: changes}); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (s *Storage) GetToy(ctx context.Context, toyID int64) (data.Toy, toys.Status, string) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.GetToy",
+	})
+	if toyID < 1 {
+		return data.Toy{}, toys.Status_STATUS_INTERNAL_ERROR, "invalid toy id"
+	}
+
+	query := `
+SELECT id, created_at, COALESCE(sku, ''), title, description, skills, categories, images, recommended_age, min_age_months, COALESCE(max_age_months, 0), ` + manufacturerColumns + `, value, version, ` + availableUnits + `
+FROM toys
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+	var toy data.Toy
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, toyID).Scan(
+		&toy.ID,
+		&toy.CreatedAt,
+		&toy.SKU,
+		&toy.Title,
+		&toy.Desc,
+		jsonArray(&toy.Skills),
+		jsonArray(&toy.Categories),
+		jsonArray(&toy.Images),
+		&toy.RecAge,
+		&toy.MinAgeMonths,
+		&toy.MaxAgeMonths,
+		&toy.ManufacturerID,
+		&toy.Manufacturer,
+		&toy.Value,
+		&toy.Version,
+		&toy.AvailableCount,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.Toy{}, toys.Status_STATUS_INTERNAL_ERROR, "operation affect zero rows!"
+		default:
+			return data.Toy{}, toys.Status_STATUS_INTERNAL_ERROR, "internal error!"
+		}
+	}
+
+	toy.IsAvailable = toy.AvailableCount > 0
+
+	return toy, toys.Status_STATUS_OK, "toy get successfully"
+}
+
+// ListToy pages with LIMIT/OFFSET, or from filters.Cursor without counting
+// the total, and returns cursors for the neighbouring pages. Searches go
+// through the FTS5 index; one that matches nothing falls back to the
+// word_similarity function on titles and manufacturer names, and
+// metadata.Fuzzy says so.
+func (s *Storage) ListToy(ctx context.Context, q data.ToyQuery, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ListToy",
+	})
+
+	var cursor data.Cursor
+	if filters.Cursor != "" {
+		var err error
+		if cursor, err = data.DecodeCursor(filters.Cursor); err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "invalid cursor", data.Metadata{}
+		}
+	}
+
+	search := fullTextSearch
+	if cursor.Fuzzy {
+		search = fuzzySearch
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	toysList, totalRecords, err := s.queryToys(ctx, q, filters, cursor, search)
+	if err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+	}
+
+	if len(toysList) == 0 && q.Title != "" && !search.fuzzy && filters.Cursor == "" {
+		matched := false
+		if filters.Page > 1 {
+			// An empty later page may just be past the end of the matches.
+			probe := data.Filters{Page: 1, PageSize: 1, Sort: filters.Sort, SortSafelist: filters.SortSafelist}
+			found, _, err := s.queryToys(ctx, q, probe, cursor, search)
+			if err != nil {
+				return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+			}
+			matched = len(found) > 0
+		}
+		if !matched {
+			search = fuzzySearch
+			toysList, totalRecords, err = s.queryToys(ctx, q, filters, cursor, search)
+			if err != nil {
+				return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+			}
+		}
+	}
+
+	var metadata data.Metadata
+	var hasNext, hasPrev bool
+	if filters.Cursor != "" {
+		hasMore := len(toysList) > int(filters.Limit())
+		if hasMore {
+			toysList = toysList[:filters.Limit()]
+		}
+		if cursor.Backward {
+			slices.Reverse(toysList)
+			hasNext, hasPrev = true, hasMore
+		} else {
+			hasNext, hasPrev = hasMore, true
+		}
+		metadata = data.Metadata{PageSize: filters.PageSize}
+	} else {
+		metadata = filters.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		hasNext = int(filters.Offset())+len(toysList) < totalRecords
+		hasPrev = filters.Page > 1
+	}
+	metadata.Fuzzy = search.fuzzy
+
+	if err = pageCursors(&metadata, filters, toySortColumn(filters, search), search.fuzzy, toysList, hasNext, hasPrev); err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch toys from db", data.Metadata{}
+	}
+	return toysList, toys.Status_STATUS_OK, "toy listing was successful", metadata
+}
+
+func (s *Storage) GetToysByIds(ctx context.Context, ids []int64) ([]*data.ToySummary, string) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.gettoysbyid",
+	})
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	// The summary shows the thumbnail of the primary uploaded image, or the
+	// first image URL for toys without uploads.
+	query := fmt.Sprintf(`
+SELECT id, title, value,
+    COALESCE((SELECT i.thumbnail_url FROM toy_images i WHERE i.toy_id = toys.id AND i.is_primary), json_extract(images, '$[0]'), '') AS image_url
+FROM toys
+WHERE id IN (%s)`, strings.Join(placeholders, ","))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return []*data.ToySummary{}, "could not query"
+	}
+	defer rows.Close()
+	results := []*data.ToySummary{}
+
+	for rows.Next() {
+		var toy data.ToySummary
+		err := rows.Scan(
+			&toy.ID,
+			&toy.Title,
+			&toy.Value,
+			&toy.URL,
+		)
+		if err != nil {
+			return nil, "internal error"
+		}
+
+		results = append(results, &toy)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "internal"
+	}
+
+	return results, "toy fetch was successful"
+}
+
+func (s *Storage) ListRecommended(ctx context.Context, userID int64, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ListRec",
+	})
+
+	// Every toy is scored by how often its categories, skills and recommended age
+	// appear in the user's rental history, which ReserveToy records. A user
+	// without history scores zero everywhere, so the ordering falls back to
+	// overall rental popularity. Toys the user has out or booked are left out.
+	query := `
+WITH history AS (
+    SELECT t.categories, t.skills, t.recommended_age
+    FROM toy_rentals r
+    JOIN toys t ON t.id = r.toy_id
+    WHERE r.user_id = $1
+),
+category_weights AS (
+    SELECT c.value AS category, count(*) AS weight
+    FROM history, json_each(history.categories) AS c
+    GROUP BY c.value
+),
+skill_weights AS (
+    SELECT sk.value AS skill, count(*) AS weight
+    FROM history, json_each(history.skills) AS sk
+    GROUP BY sk.value
+),
+age_weights AS (
+    SELECT recommended_age, count(*) AS weight
+    FROM history
+    GROUP BY recommended_age
+),
+popularity AS (
+    SELECT toy_id, count(*) AS rentals
+    FROM toy_rentals
+    GROUP BY toy_id
+)
+SELECT count(*) OVER(), t.id, t.title, t.categories, t.skills, t.recommended_age, t.value,
+    3 * COALESCE((SELECT sum(cw.weight) FROM category_weights cw WHERE cw.category IN (SELECT value FROM json_each(t.categories))), 0)
+  + 2 * COALESCE((SELECT sum(sw.weight) FROM skill_weights sw WHERE sw.skill IN (SELECT value FROM json_each(t.skills))), 0)
+  + COALESCE((SELECT aw.weight FROM age_weights aw WHERE aw.recommended_age = t.recommended_age), 0) AS score
+FROM toys t
+LEFT JOIN popularity p ON p.toy_id = t.id
+WHERE t.deleted_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM toy_rentals r
+    WHERE r.user_id = $1 AND r.toy_id = t.id AND (r.returned_at IS NULL OR r.returned_at > $4)
+)
+ORDER BY score DESC, COALESCE(p.rentals, 0) DESC, t.id ASC
+LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, filters.Limit(), filters.Offset(), timestamp(time.Now()))
+	if err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch recommendations from db", data.Metadata{}
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	toysList := []*data.Toy{}
+
+	for rows.Next() {
+		var toy data.Toy
+		var score int64
+		err := rows.Scan(
+			&totalRecords,
+			&toy.ID,
+			&toy.Title,
+			jsonArray(&toy.Categories),
+			jsonArray(&toy.Skills),
+			&toy.RecAge,
+			&toy.Value,
+			&score,
+		)
+		if err != nil {
+			return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch recommendations from db", data.Metadata{}
+		}
+		toysList = append(toysList, &toy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, toys.Status_STATUS_INTERNAL_ERROR, "could not fetch recommendations from db", data.Metadata{}
+	}
+
+	metadata := filters.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return toysList, toys.Status_STATUS_OK, "recommendations listing was successful", metadata
+}
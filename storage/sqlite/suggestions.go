@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"time"
+	"toysService/internal/data"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SuggestToys matches prefix case-insensitively with casefold against the
+// titles of live toys, counted straight from the toys table since there is
+// no toy_search_terms here, and against category and manufacturer names
+// read from their own tables, the way the postgres backend does.
+func (s *Storage) SuggestToys(ctx context.Context, prefix string, limit int) ([]*data.Suggestion, error) {
+	query := `
+SELECT kind, term, slug, id, toys_count
+FROM (
+    SELECT 'title' AS kind, title AS term, '' AS slug, 0 AS id, count(*) AS toys_count
+    FROM toys
+    WHERE deleted_at IS NULL AND title <> '' AND casefold(title) LIKE casefold($1) || '%' ESCAPE '\'
+    GROUP BY title
+    UNION ALL
+    SELECT 'category', min(n.value), c.slug, 0,
+        (SELECT count(*) FROM toys WHERE deleted_at IS NULL AND EXISTS (
+            SELECT 1 FROM json_each(toys.categories) WHERE value IN (` + strings.ReplaceAll(categorySubtree, "$%[1]d", "c.slug") + `)))
+    FROM categories c, json_each(c.names) AS n
+    WHERE casefold(n.value) LIKE casefold($1) || '%' ESCAPE '\'
+    GROUP BY c.slug
+    UNION ALL
+    SELECT 'manufacturer', m.name, '', m.id,
+        (SELECT count(*) FROM toys WHERE deleted_at IS NULL AND manufacturer_id = m.id)
+    FROM manufacturers m
+    WHERE casefold(m.name) LIKE casefold($1) || '%' ESCAPE '\'
+) AS suggestions
+ORDER BY toys_count DESC, length(term), term
+LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, likeEscaper.Replace(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*data.Suggestion{}
+	for rows.Next() {
+		var sg data.Suggestion
+		if err = rows.Scan(&sg.Kind, &sg.Text, &sg.Slug, &sg.ID, &sg.Toys); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &sg)
+	}
+	return suggestions, rows.Err()
+}
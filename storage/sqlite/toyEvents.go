@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	"toysService/internal/data"
+)
+
+func insertEvent(ctx context.Context, tx *sql.Tx, eventType string, payload data.ToyEventPayload) error {
+	query := `
+INSERT INTO toy_events (toy_id, event_type, payload)
+VALUES ($1, $2, $3)`
+
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, payload.ToyID, eventType, string(js))
+	return err
+}
+
+// insertAvailabilityEvent records the current number of available copies of
+// a toy. Callers only invoke it when a unit moved in or out of "available".
+// The write transaction already holds the database's write lock, so no
+// other unit change can slip in between the change and the count.
+func insertAvailabilityEvent(ctx context.Context, tx *sql.Tx, toyID int64) error {
+	var count int32
+	err := tx.QueryRowContext(ctx, `SELECT count(*) FROM toy_units WHERE toy_id = $1 AND status = 'available'`, toyID).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	isAvailable := count > 0
+	return insertEvent(ctx, tx, data.EventToyAvailabilityChanged, data.ToyEventPayload{
+		ToyID:          toyID,
+		AvailableCount: &count,
+		IsAvailable:    &isAvailable,
+	})
+}
+
+// DeliverToyEvents hands pending outbox events to publish in commit order. When
+// publishing fails the event is rescheduled with backoff(attempts) and every
+// later event of the same toy is held back, so consumers never see a toy's
+// events out of order. Events are marked published only after publish
+// returns, giving at-least-once delivery.
+//
+// A SQLite database belongs to a single node with a single relay, so unlike
+// postgres there is no lock to take; holding the write lock while publishing
+// would stall every write in the meantime.
+func (s *Storage) DeliverToyEvents(
+	ctx context.Context,
+	limit int,
+	publish func(context.Context, *data.ToyEvent) error,
+	backoff func(attempts int32) time.Duration,
+) (int, error) {
+	query := `
+SELECT e.id, e.toy_id, e.event_type, e.payload, e.created_at, e.attempts
+FROM toy_events e
+WHERE e.published_at IS NULL
+AND e.next_attempt_at <= $2
+AND NOT EXISTS (
+    SELECT 1 FROM toy_events p
+    WHERE p.toy_id = e.toy_id AND p.published_at IS NULL AND p.id < e.id AND p.next_attempt_at > $2
+)
+ORDER BY e.id ASC
+LIMIT $1`
+
+	rows, err := s.db.QueryContext(ctx, query, limit, timestamp(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+
+	events := []*data.ToyEvent{}
+	for rows.Next() {
+		var event data.ToyEvent
+		var payload []byte
+		err := rows.Scan(&event.ID, &event.ToyID, &event.Type, &payload, &event.CreatedAt, &event.Attempts)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		event.Payload = payload
+		events = append(events, &event)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	blocked := map[int64]bool{}
+	for _, event := range events {
+		if blocked[event.ToyID] {
+			continue
+		}
+
+		if pubErr := publish(ctx, event); pubErr != nil {
+			blocked[event.ToyID] = true
+			retryAt := time.Now().Add(backoff(event.Attempts + 1))
+			_, err = s.db.ExecContext(ctx, `
+UPDATE toy_events
+SET attempts = attempts + 1, next_attempt_at = $1, last_error = $2
+WHERE id = $3`, timestamp(retryAt), pubErr.Error(), event.ID)
+			if err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		_, err = s.db.ExecContext(ctx, `UPDATE toy_events SET published_at = $1, attempts = attempts + 1 WHERE id = $2`, timestamp(time.Now()), event.ID)
+		if err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
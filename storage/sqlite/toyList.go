@@ -0,0 +1,348 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"toysService/internal/data"
+	"toysService/storage/facetsql"
+)
+
+// toySearch is how ListToy matches, ranks and highlights its title argument
+// ($1). The rank expression is the "relevance" sort and is negated so that
+// the ascending sort puts the best matches first, like every other sort name
+// without a minus.
+type toySearch struct {
+	match   string
+	rank    string
+	snippet string
+	fuzzy   bool
+}
+
+// searchQuery turns the search text into an FTS5 query over toys_search.
+// The websearch syntax lets users write "lego -duplo" or quoted phrases.
+const searchQuery = `toys_match_query($1)`
+
+// bm25 already ranks better matches lower. The column weights are those
+// ts_rank_cd gives the A, B, C and D labels.
+var fullTextSearch = toySearch{
+	match: `id IN (SELECT rowid FROM toys_search WHERE toys_search MATCH ` + searchQuery + `)`,
+	rank: `(CASE WHEN $1 = '' THEN 0 ELSE COALESCE((SELECT float4(bm25(toys_search, 1.0, 0.4, 0.2, 0.1))
+    FROM toys_search WHERE toys_search MATCH ` + searchQuery + ` AND rowid = toys.id), 0) END)`,
+	snippet: `CASE WHEN $1 = '' THEN '' ELSE toys_headline(title || ' — ' || description, $1) END`,
+}
+
+// fuzzySearch matches titles and manufacturer names that contain something
+// close to the search text, e.g. "lgo" finds "LEGO Duplo".
+var fuzzySearch = toySearch{
+	match: `(word_similarity($1, title) >= ` + fuzzyThreshold + ` OR manufacturer_id IN (SELECT id FROM manufacturers WHERE word_similarity($1, name) >= ` + fuzzyThreshold + `))`,
+	rank: `(-max(word_similarity($1, title),
+    COALESCE((SELECT word_similarity($1, m.name) FROM manufacturers m WHERE m.id = toys.manufacturer_id), 0)))`,
+	snippet: `''`,
+	fuzzy:   true,
+}
+
+// fuzzyThreshold is the word similarity a fuzzy match needs, as in the
+// postgres backend.
+const fuzzyThreshold = "0.4"
+
+// toySortColumns maps the sort names accepted by ListToy to SQL columns.
+// Names not listed here are column names already.
+var toySortColumns = map[string]string{
+	"recAge": "min_age_months",
+	"age":    "min_age_months",
+	"from":   "value",
+	"to":     "value",
+}
+
+// Array columns sort like text[] in postgres: element by element. Their sort
+// keys join the elements with a control character no name contains, which
+// the toys_text collation splits on again.
+const (
+	skillsSortKey     = `(SELECT group_concat(value, char(1) ORDER BY key) FROM json_each(skills))`
+	categoriesSortKey = `(SELECT group_concat(value, char(1) ORDER BY key) FROM json_each(categories))`
+)
+
+func toySortColumn(filters data.Filters, search toySearch) string {
+	column := filters.SortColumn()
+	switch column {
+	case "relevance":
+		return search.rank
+	case "skills":
+		return skillsSortKey
+	case "categories":
+		return categoriesSortKey
+	}
+	if mapped, ok := toySortColumns[column]; ok {
+		return mapped
+	}
+	return column
+}
+
+// sortExpression is what ORDER BY and the keyset condition compare for a
+// sort column. Text sorts under the toys_text collation rather than byte by
+// byte, so titles come out in the order postgres gives them.
+func sortExpression(column string) string {
+	switch column {
+	case "title", skillsSortKey, categoriesSortKey:
+		return column + " COLLATE toys_text"
+	}
+	return column
+}
+
+// toyFilter is one ListToy filter as an SQL condition. ToyFacets hands them
+// to facetsql, so they share its type.
+type toyFilter = facetsql.Filter
+
+// categorySubtree lists the slug in $%[1]d and the slugs of all its
+// descendants, so filtering by a parent category also finds toys filed under
+// its children.
+const categorySubtree = `WITH RECURSIVE tree(id, slug) AS (
+    SELECT id, slug FROM categories WHERE slug = $%[1]d
+    UNION
+    SELECT sub.id, sub.slug FROM categories sub JOIN tree ON sub.parent_id = tree.id
+) SELECT slug FROM tree UNION SELECT $%[1]d`
+
+// skillVariants lists the canonical name and every synonym of the skill that
+// the term in $%[1]d names, so filtering by "fine motor" also finds toys
+// tagged "Мелкая моторика". Unknown terms only match themselves.
+const skillVariants = `WITH skill AS (
+    SELECT id, name FROM skills WHERE casefold(name) = casefold($%[1]d)
+    UNION
+    SELECT s.id, s.name FROM skills s JOIN skill_synonyms sy ON sy.skill_id = s.id WHERE casefold(sy.synonym) = casefold($%[1]d)
+) SELECT name FROM skill
+UNION SELECT sy.synonym FROM skill_synonyms sy JOIN skill ON sy.skill_id = skill.id
+UNION SELECT $%[1]d WHERE NOT EXISTS (SELECT 1 FROM skill)`
+
+// toyFilters turns the query's filters into SQL conditions, appending their
+// arguments to args. $1 is reserved for the search text.
+func toyFilters(q data.ToyQuery, args *[]any) ([]toyFilter, error) {
+	var filters []toyFilter
+	arg := func(value any) int {
+		*args = append(*args, value)
+		return len(*args)
+	}
+
+	// Every requested category must match, either itself or through one of
+	// its subcategories.
+	for _, slug := range q.Categories {
+		subtree := fmt.Sprintf(categorySubtree, arg(slug))
+		filters = append(filters, toyFilter{Facet: data.FacetCategory, Cond: "EXISTS (SELECT 1 FROM json_each(toys.categories) WHERE value IN (" + subtree + "))"})
+	}
+	// Skills match by canonical name or any synonym.
+	for _, skill := range q.Skills {
+		variants := fmt.Sprintf(skillVariants, arg(skill))
+		filters = append(filters, toyFilter{Facet: data.FacetSkill, Cond: "EXISTS (SELECT 1 FROM json_each(toys.skills) WHERE value IN (" + variants + "))"})
+	}
+	if q.AgeMonths != nil {
+		n := arg(*q.AgeMonths)
+		filters = append(filters, toyFilter{Facet: data.FacetAge, Cond: fmt.Sprintf("min_age_months <= $%d AND (max_age_months IS NULL OR max_age_months >= $%d)", n, n)})
+	}
+	if len(q.Manufacturers) > 0 {
+		ids, err := json.Marshal(q.Manufacturers)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, toyFilter{Facet: data.FacetManufacturer, Cond: fmt.Sprintf("manufacturer_id IN (SELECT value FROM json_each($%d))", arg(string(ids)))})
+	}
+	from, to := arg(q.From), arg(q.To)
+	filters = append(filters, toyFilter{Facet: data.FacetValue, Cond: fmt.Sprintf("value BETWEEN $%d AND $%d", from, to)})
+
+	return filters, nil
+}
+
+// queryToys runs one ListToy page. In cursor mode it reads one row more than
+// the page size so the caller can tell whether another page follows, and the
+// returned total is zero.
+func (s *Storage) queryToys(ctx context.Context, q data.ToyQuery, filters data.Filters, cursor data.Cursor, search toySearch) ([]*data.Toy, int, error) {
+	column := toySortColumn(filters, search)
+	sortBy := sortExpression(column)
+	direction := filters.SortDirection()
+
+	countColumn := "count(*) OVER()"
+	if filters.Cursor != "" {
+		countColumn = "0"
+	}
+
+	args := []any{q.Title}
+	query := `
+SELECT ` + countColumn + `, id, title, categories, skills, recommended_age, min_age_months, COALESCE(max_age_months, 0), ` + manufacturerColumns + `, value, ` + availableUnits + `,
+    -` + search.rank + `, ` + search.snippet + `
+FROM toys
+WHERE deleted_at IS NULL
+AND ($1 = '' OR ` + search.match + `)`
+	conds, err := toyFilters(q, &args)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, f := range conds {
+		query += " AND " + f.Cond
+	}
+	argIndex := len(args) + 1
+
+	if filters.Cursor != "" {
+		key, err := toyCursorKey(column, cursor)
+		if err != nil {
+			return nil, 0, err
+		}
+		query += keysetCondition(sortBy, direction, cursor.Backward, argIndex, argIndex+1)
+		args = append(args, key, cursor.ID)
+		argIndex += 2
+
+		// A backward page is read in reverse order and flipped back by ListToy.
+		idDirection := "ASC"
+		if cursor.Backward {
+			direction, idDirection = flipDirection(direction), "DESC"
+		}
+		query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT $%d", sortBy, direction, idDirection, argIndex)
+		args = append(args, filters.Limit()+1)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, id ASC LIMIT $%d OFFSET $%d", sortBy, direction, argIndex, argIndex+1)
+		args = append(args, filters.Limit(), filters.Offset())
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	toysList := []*data.Toy{}
+
+	for rows.Next() {
+		var toy data.Toy
+		err := rows.Scan(
+			&totalRecords,
+			&toy.ID,
+			&toy.Title,
+			jsonArray(&toy.Categories),
+			jsonArray(&toy.Skills),
+			&toy.RecAge,
+			&toy.MinAgeMonths,
+			&toy.MaxAgeMonths,
+			&toy.ManufacturerID,
+			&toy.Manufacturer,
+			&toy.Value,
+			&toy.AvailableCount,
+			&toy.Rank,
+			&toy.Snippet,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		toy.IsAvailable = toy.AvailableCount > 0
+		toysList = append(toysList, &toy)
+	}
+
+	return toysList, totalRecords, rows.Err()
+}
+
+// toySortKey returns the value of the sort column for a listed toy, which is
+// what a cursor pointing at that toy has to remember.
+func toySortKey(column string, toy *data.Toy) any {
+	switch column {
+	case "title":
+		return toy.Title
+	case "value":
+		return toy.Value
+	case "min_age_months":
+		return toy.MinAgeMonths
+	case skillsSortKey:
+		return toy.Skills
+	case categoriesSortKey:
+		return toy.Categories
+	case fullTextSearch.rank, fuzzySearch.rank:
+		return -toy.Rank
+	default:
+		return toy.ID
+	}
+}
+
+// toyCursorKey decodes a cursor's sort key into a query argument of the
+// column's type.
+func toyCursorKey(column string, c data.Cursor) (any, error) {
+	switch column {
+	case "title":
+		var key string
+		err := c.KeyInto(&key)
+		return key, err
+	case "value", "id":
+		var key int64
+		err := c.KeyInto(&key)
+		return key, err
+	case "min_age_months":
+		var key int32
+		err := c.KeyInto(&key)
+		return key, err
+	case skillsSortKey, categoriesSortKey:
+		var key []string
+		err := c.KeyInto(&key)
+		return strings.Join(key, "\x01"), err
+	case fullTextSearch.rank, fuzzySearch.rank:
+		var key float32
+		err := c.KeyInto(&key)
+		return float64(key), err
+	default:
+		return nil, fmt.Errorf("cursor pagination is not supported for %q", column)
+	}
+}
+
+// keysetCondition selects the rows after (or before, for a backward cursor)
+// the cursor position in "column dir, id ASC" order. keyArg and idArg are the
+// placeholder numbers of the cursor's key and id.
+func keysetCondition(column string, direction string, backward bool, keyArg, idArg int) string {
+	keyOp, idOp := ">", ">"
+	if direction == "DESC" {
+		keyOp = "<"
+	}
+	if backward {
+		keyOp, idOp = flipOp(keyOp), flipOp(idOp)
+	}
+	return fmt.Sprintf(" AND (%[1]s %[2]s $%[3]d OR (%[1]s = $%[3]d AND id %[4]s $%[5]d))", column, keyOp, keyArg, idOp, idArg)
+}
+
+func flipOp(op string) string {
+	if op == ">" {
+		return "<"
+	}
+	return ">"
+}
+
+func flipDirection(direction string) string {
+	if direction == "DESC" {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+// pageCursors fills the next/prev cursors of a page of toys. hasNext and
+// hasPrev tell whether there is anything beyond either end of the page.
+func pageCursors(metadata *data.Metadata, filters data.Filters, column string, fuzzy bool, page []*data.Toy, hasNext, hasPrev bool) error {
+	if len(page) == 0 {
+		return nil
+	}
+	var err error
+	if hasNext {
+		last := page[len(page)-1]
+		c := data.Cursor{Sort: filters.Sort, ID: last.ID, Fuzzy: fuzzy}
+		if metadata.NextCursor, err = c.Encode(toySortKey(column, last)); err != nil {
+			return err
+		}
+	}
+	if hasPrev {
+		first := page[0]
+		c := data.Cursor{Sort: filters.Sort, ID: first.ID, Backward: true, Fuzzy: fuzzy}
+		if metadata.PrevCursor, err = c.Encode(toySortKey(column, first)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullAge stores an open upper bound as NULL.
+func nullAge(months int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: months, Valid: months > 0}
+}
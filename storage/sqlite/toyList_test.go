@@ -0,0 +1,10 @@
+package sqlite
+
+import (
+	"testing"
+	"toysService/storage/storagetest"
+)
+
+func TestListToy(t *testing.T) {
+	storagetest.TestListToy(t, newTestStorage(t))
+}
@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	sqlite3 "modernc.org/sqlite/lib"
+	"time"
+	"toysService/internal/data"
+)
+
+func (s *Storage) AddToyUnit(ctx context.Context, unit data.ToyUnit) (data.ToyUnit, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.AddToyUnit",
+	})
+	query := `
+INSERT INTO toy_units (toy_id, serial, condition, location, status)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at`
+
+	if unit.Status == "" {
+		unit.Status = data.UnitStatusAvailable
+	}
+	args := []any{unit.ToyID, unit.Serial, unit.Condition, unit.Location, unit.Status}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return data.ToyUnit{}, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&unit.ID, &unit.CreatedAt)
+	if err != nil {
+		switch errorCode(err) {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+			return data.ToyUnit{}, data.ErrDuplicateSerial
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return data.ToyUnit{}, data.ErrRecordNotFound
+		default:
+			return data.ToyUnit{}, err
+		}
+	}
+
+	if unit.Status == data.UnitStatusAvailable {
+		if err = insertAvailabilityEvent(ctx, tx, unit.ToyID); err != nil {
+			return data.ToyUnit{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return data.ToyUnit{}, err
+	}
+	return unit, nil
+}
+
+func (s *Storage) ListToyUnits(ctx context.Context, toyID int64) ([]*data.ToyUnit, error) {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ListToyUnits",
+	})
+	query := `
+SELECT id, toy_id, serial, condition, location, status, created_at, COALESCE(retired_at, '')
+FROM toy_units
+WHERE toy_id = $1
+ORDER BY id ASC`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, toyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	units := []*data.ToyUnit{}
+	for rows.Next() {
+		var unit data.ToyUnit
+		err := rows.Scan(
+			&unit.ID,
+			&unit.ToyID,
+			&unit.Serial,
+			&unit.Condition,
+			&unit.Location,
+			&unit.Status,
+			&unit.CreatedAt,
+			&unit.RetiredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, &unit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return units, nil
+}
+
+func (s *Storage) ChangeToyUnitStatus(ctx context.Context, unitID int64, unitStatus string) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.ChangeToyUnitStatus",
+	})
+	query := `
+UPDATE toy_units
+SET status = $1, retired_at = CASE WHEN $1 = 'retired' THEN $3 END
+WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return s.updateUnitStatus(ctx, unitID, unitStatus, query, unitStatus, unitID, timestamp(time.Now()))
+}
+
+func (s *Storage) RetireToyUnit(ctx context.Context, unitID int64) error {
+	s.log.PrintInfo("DB part", map[string]string{
+		"method": "sqlite.RetireToyUnit",
+	})
+	query := `
+UPDATE toy_units
+SET status = 'retired', retired_at = $2
+WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return s.updateUnitStatus(ctx, unitID, data.UnitStatusRetired, query, unitID, timestamp(time.Now()))
+}
+
+// updateUnitStatus runs the given UPDATE and, when the unit entered or left
+// the "available" status, records an availability event for its toy in the
+// same transaction. Changing a retired unit fails with data.ErrUnitRetired.
+func (s *Storage) updateUnitStatus(ctx context.Context, unitID int64, newStatus string, query string, args ...any) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var toyID int64
+	var oldStatus string
+	err = tx.QueryRowContext(ctx, `SELECT toy_id, status FROM toy_units WHERE id = $1`, unitID).Scan(&toyID, &oldStatus)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	if oldStatus == data.UnitStatusRetired {
+		return data.ErrUnitRetired
+	}
+
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	if (oldStatus == data.UnitStatusAvailable) != (newStatus == data.UnitStatusAvailable) {
+		if err = insertAvailabilityEvent(ctx, tx, toyID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
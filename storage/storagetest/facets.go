@@ -0,0 +1,84 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
+	"testing"
+	"toysService/internal/data"
+)
+
+// FacetStorage is the part of a storage provider the ToyFacets checks use.
+type FacetStorage interface {
+	CreateCategory(ctx context.Context, c data.Category) (data.Category, error)
+	CreateSkill(ctx context.Context, skill data.Skill) (data.Skill, error)
+	CreateManufacturer(ctx context.Context, m data.Manufacturer) (data.Manufacturer, error)
+	CreateToy(ctx context.Context, inputToy data.Toy) (toys.Status, string, data.Toy)
+	ToyFacets(ctx context.Context, q data.ToyQuery, fuzzy bool) (data.Facets, error)
+}
+
+// TestToyFacets checks that a facet with several filters, one per requested
+// value, drops all of them and keeps every other facet's.
+func TestToyFacets(t *testing.T, s FacetStorage) {
+	ctx := context.Background()
+
+	for _, slug := range []string{"puzzles", "vehicles", "outdoor"} {
+		if _, err := s.CreateCategory(ctx, data.Category{Slug: slug, Names: map[string]string{"en": slug}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"logic", "motor", "social"} {
+		if _, err := s.CreateSkill(ctx, data.Skill{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := s.CreateManufacturer(ctx, data.Manufacturer{Name: "Acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, toy := range []data.Toy{
+		{Title: "Both and both", Categories: []string{"puzzles", "vehicles"}, Skills: []string{"logic", "motor"}},
+		{Title: "Puzzle only", Categories: []string{"puzzles"}, Skills: []string{"logic", "motor"}},
+		{Title: "Logic only", Categories: []string{"puzzles", "vehicles"}, Skills: []string{"logic"}},
+		{Title: "Vehicles outdoors", Categories: []string{"vehicles", "outdoor"}, Skills: []string{"logic", "motor", "social"}},
+	} {
+		toy.Images = []string{"https://example.com/toy.jpg"}
+		toy.Value = 3000
+		toy.ManufacturerID = m.ID
+		if status, msg, _ := s.CreateToy(ctx, toy); status != toys.Status_STATUS_OK {
+			t.Fatalf("create %q: %s", toy.Title, msg)
+		}
+	}
+
+	facets, err := s.ToyFacets(ctx, data.ToyQuery{
+		Categories: []string{"puzzles", "vehicles"},
+		Skills:     []string{"logic", "motor"},
+		To:         1_000_000,
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Categories count the toys with both skills, skills the toys in both
+	// categories, and manufacturers and value buckets the toys that pass
+	// every filter.
+	checkFacet(t, "categories", facets.Categories, map[string]int32{"puzzles": 2, "vehicles": 2, "outdoor": 1})
+	checkFacet(t, "skills", facets.Skills, map[string]int32{"logic": 2, "motor": 1})
+	checkFacet(t, "manufacturers", facets.Manufacturers, map[string]int32{"Acme": 1})
+	checkFacet(t, "value buckets", facets.ValueBuckets, map[string]int32{"2000-4999": 1})
+}
+
+func checkFacet(t *testing.T, name string, got []data.FacetCount, want map[string]int32) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("%s: got %v, want %v", name, got, want)
+		return
+	}
+	for _, fc := range got {
+		if fc.Count != want[fc.Value] {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package storagetest checks that the toys storage providers behave the same.
+// Each backend's tests run these checks against a fresh, empty storage.
+package storagetest
+
+import (
+	"context"
+	"github.com/spacecowboytobykty123/toysProto/gen/go/toys"
+	"slices"
+	"testing"
+	"toysService/internal/data"
+)
+
+// Storage is the part of a storage provider the ListToy checks use.
+type Storage interface {
+	CreateCategory(ctx context.Context, c data.Category) (data.Category, error)
+	CreateSkill(ctx context.Context, skill data.Skill) (data.Skill, error)
+	CreateManufacturer(ctx context.Context, m data.Manufacturer) (data.Manufacturer, error)
+	CreateToy(ctx context.Context, inputToy data.Toy) (toys.Status, string, data.Toy)
+	ListToy(ctx context.Context, q data.ToyQuery, filters data.Filters) ([]*data.Toy, toys.Status, string, data.Metadata)
+}
+
+// listToyCases are ListToy queries over the toys created by createToys with
+// the titles they must list, in order. Text sorts by the database collation,
+// not byte by byte: case and accents only break ties and Ё sorts with Е, so
+// "Ёлка" follows "Аист" although its first letter has the lower code point.
+var listToyCases = []struct {
+	name string
+	q    data.ToyQuery
+	sort string
+	want []string
+}{
+	{"by id", data.ToyQuery{}, "id", []string{"apple cart", "Zebra", "Ёлка", "Ball", "ball", "Äpfel", "Аист"}},
+	{"by title", data.ToyQuery{}, "title", []string{"Äpfel", "apple cart", "ball", "Ball", "Zebra", "Аист", "Ёлка"}},
+	{"by title descending", data.ToyQuery{}, "-title", []string{"Ёлка", "Аист", "Zebra", "Ball", "ball", "apple cart", "Äpfel"}},
+	{"by value", data.ToyQuery{}, "value", []string{"Ball", "apple cart", "Ёлка", "Zebra", "ball", "Аист", "Äpfel"}},
+	{"by value descending", data.ToyQuery{}, "-value", []string{"Äpfel", "Аист", "Zebra", "ball", "apple cart", "Ёлка", "Ball"}},
+	{"by age", data.ToyQuery{}, "age", []string{"Ball", "Аист", "ball", "apple cart", "Zebra", "Ёлка", "Äpfel"}},
+	{"by recommended age descending", data.ToyQuery{}, "-recAge", []string{"Äpfel", "Ёлка", "Zebra", "apple cart", "ball", "Ball", "Аист"}},
+	{"by skills", data.ToyQuery{}, "skills", []string{"apple cart", "Zebra", "ball", "Ёлка", "Аист", "Ball", "Äpfel"}},
+	{"by skills descending", data.ToyQuery{}, "-skills", []string{"Äpfel", "Ball", "Аист", "Ёлка", "ball", "Zebra", "apple cart"}},
+	{"by categories", data.ToyQuery{}, "categories", []string{"Ball", "Аист", "Ёлка", "apple cart", "Äpfel", "Zebra", "ball"}},
+	{"by categories descending", data.ToyQuery{}, "-categories", []string{"ball", "Zebra", "Äpfel", "apple cart", "Ёлка", "Аист", "Ball"}},
+	{"category with subcategories", data.ToyQuery{Categories: []string{"games"}}, "id", []string{"apple cart", "Ball", "Äpfel", "Аист"}},
+	{"skill with synonyms", data.ToyQuery{Skills: []string{"logic"}}, "id", []string{"apple cart", "Zebra", "Ball", "ball"}},
+	{"skill by synonym", data.ToyQuery{Skills: []string{"reasoning"}}, "title", []string{"apple cart", "ball", "Ball", "Zebra"}},
+	{"category and skill", data.ToyQuery{Categories: []string{"games"}, Skills: []string{"logic"}}, "-id", []string{"Ball", "apple cart"}},
+	{"age", data.ToyQuery{AgeMonths: ptr[int32](24)}, "title", []string{"apple cart", "Ball", "Zebra", "Аист"}},
+	{"manufacturer", data.ToyQuery{Manufacturers: []int64{1}}, "value", []string{"apple cart", "Ёлка", "ball", "Аист"}},
+	{"value range", data.ToyQuery{From: 3000, To: 4000}, "-title", []string{"Ёлка", "Zebra", "ball", "apple cart"}},
+	{"age and value range", data.ToyQuery{AgeMonths: ptr[int32](24), From: 3000, To: 4000}, "-value", []string{"Zebra", "apple cart"}},
+	{"search", data.ToyQuery{Title: "zebra"}, "id", []string{"Zebra"}},
+}
+
+// TestListToy creates a small catalogue in s and checks every listToyCases
+// query with page numbers and with cursors in both directions.
+func TestListToy(t *testing.T, s Storage) {
+	ctx := context.Background()
+	createToys(t, ctx, s)
+
+	for _, tc := range listToyCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := tc.q
+			if q.To == 0 {
+				q.To = 1_000_000
+			}
+
+			var got []string
+			for page := int32(1); ; page++ {
+				list, metadata := listToys(t, ctx, s, q, data.Filters{Page: page, PageSize: 2, Sort: tc.sort})
+				got = append(got, titles(list)...)
+				if metadata.NextCursor == "" {
+					break
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("pages: got %q, want %q", got, tc.want)
+			}
+
+			// Walk forward with next cursors from the first page, then back
+			// with prev cursors from the last one.
+			list, metadata := listToys(t, ctx, s, q, data.Filters{Page: 1, PageSize: 2, Sort: tc.sort})
+			got = titles(list)
+			for metadata.NextCursor != "" {
+				list, metadata = listToys(t, ctx, s, q, data.Filters{Page: 1, PageSize: 2, Sort: tc.sort, Cursor: metadata.NextCursor})
+				got = append(got, titles(list)...)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("next cursors: got %q, want %q", got, tc.want)
+			}
+
+			got = titles(list)
+			for metadata.PrevCursor != "" {
+				list, metadata = listToys(t, ctx, s, q, data.Filters{Page: 1, PageSize: 2, Sort: tc.sort, Cursor: metadata.PrevCursor})
+				got = append(titles(list), got...)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("prev cursors: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// createToys adds the catalogue listToyCases expects. Manufacturer 1 is Acme.
+func createToys(t *testing.T, ctx context.Context, s Storage) {
+	t.Helper()
+
+	for _, c := range []data.Category{
+		{Slug: "games", Names: map[string]string{"en": "Games"}},
+		{Slug: "puzzles", Parent: "games", Names: map[string]string{"en": "Puzzles"}},
+		{Slug: "vehicles", Names: map[string]string{"en": "Vehicles"}},
+		{Slug: "outdoor", Names: map[string]string{"en": "Outdoor"}},
+	} {
+		if _, err := s.CreateCategory(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, skill := range []data.Skill{
+		{Name: "logic", Synonyms: []string{"reasoning"}},
+		{Name: "motor"},
+		{Name: "social"},
+	} {
+		if _, err := s.CreateSkill(ctx, skill); err != nil {
+			t.Fatal(err)
+		}
+	}
+	acme, err := s.CreateManufacturer(ctx, data.Manufacturer{Name: "Acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zeta, err := s.CreateManufacturer(ctx, data.Manufacturer{Name: "Zeta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acme.ID != 1 {
+		t.Fatalf("the first manufacturer got id %d", acme.ID)
+	}
+
+	for _, toy := range []data.Toy{
+		{Title: "apple cart", Categories: []string{"puzzles"}, Skills: []string{"logic"}, MinAgeMonths: 12, MaxAgeMonths: 36, ManufacturerID: acme.ID, Value: 3000},
+		{Title: "Zebra", Categories: []string{"vehicles"}, Skills: []string{"logic", "motor"}, MinAgeMonths: 24, ManufacturerID: zeta.ID, Value: 4000},
+		{Title: "Ёлка", Categories: []string{"outdoor"}, Skills: []string{"motor"}, MinAgeMonths: 36, MaxAgeMonths: 72, ManufacturerID: acme.ID, Value: 3000},
+		{Title: "Ball", Categories: []string{"games"}, Skills: []string{"reasoning"}, MaxAgeMonths: 24, ManufacturerID: zeta.ID, Value: 2500},
+		{Title: "ball", Categories: []string{"vehicles", "outdoor"}, Skills: []string{"logic", "motor", "social"}, MinAgeMonths: 6, MaxAgeMonths: 12, ManufacturerID: acme.ID, Value: 4000},
+		{Title: "Äpfel", Categories: []string{"puzzles", "vehicles"}, Skills: []string{"social"}, MinAgeMonths: 48, ManufacturerID: zeta.ID, Value: 6000},
+		{Title: "Аист", Categories: []string{"games", "outdoor"}, Skills: []string{"motor", "social"}, ManufacturerID: acme.ID, Value: 5000},
+	} {
+		toy.Images = []string{"https://example.com/toy.jpg"}
+		if status, msg, _ := s.CreateToy(ctx, toy); status != toys.Status_STATUS_OK {
+			t.Fatalf("create %q: %s", toy.Title, msg)
+		}
+	}
+}
+
+func listToys(t *testing.T, ctx context.Context, s Storage, q data.ToyQuery, filters data.Filters) ([]*data.Toy, data.Metadata) {
+	t.Helper()
+
+	filters.SortSafelist = data.ToySortSafelist
+	list, status, msg, metadata := s.ListToy(ctx, q, filters)
+	if status != toys.Status_STATUS_OK {
+		t.Fatalf("list toys: %s", msg)
+	}
+	return list, metadata
+}
+
+func titles(list []*data.Toy) []string {
+	names := make([]string, 0, len(list))
+	for _, toy := range list {
+		names = append(names, toy.Title)
+	}
+	return names
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}